@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"playground/verification"
+	"playground/web"
+)
+
+type AttestationInfo struct {
+	ID     string                         `json:"id"`
+	Name   string                         `json:"name"`
+	Fields []verification.DisclosureField `json:"fields"`
+}
+
+type AttestationsResponse struct {
+	Attestations []AttestationInfo `json:"attestations"`
+}
+
+// Attestations lists the enabled document types and the fields each can disclose,
+// so frontends can render the consent UI without hardcoding it
+func Attestations(w http.ResponseWriter, r *http.Request) {
+	web.Recover(web.CORS(http.HandlerFunc(handleAttestations))).ServeHTTP(w, r)
+}
+
+func handleAttestations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		web.WriteJSON(w, r, http.StatusMethodNotAllowed, map[string]string{"message": "Method not allowed"})
+		return
+	}
+
+	allowed, err := verification.AllowedAttestations()
+	if err != nil {
+		log.Printf("Failed to load allowed attestations: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+
+	response := AttestationsResponse{Attestations: []AttestationInfo{}}
+	for _, a := range verification.Attestations() {
+		if !allowed[a.Code] {
+			continue
+		}
+		response.Attestations = append(response.Attestations, AttestationInfo{ID: a.Code, Name: a.Name, Fields: a.Fields})
+	}
+	web.WriteJSON(w, r, http.StatusOK, response)
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"playground/config"
+	"playground/web"
+)
+
+type AuditResponse struct {
+	UserID  string              `json:"userId"`
+	Entries []config.AuditEntry `json:"entries"`
+}
+
+// Audit returns the verification audit log of a user, newest first
+func Audit(w http.ResponseWriter, r *http.Request) {
+	web.Recover(web.RequireAdminToken(http.HandlerFunc(handleAudit))).ServeHTTP(w, r)
+}
+
+func handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		web.WriteJSON(w, r, http.StatusMethodNotAllowed, map[string]string{"message": "Method not allowed"})
+		return
+	}
+
+	query := r.URL.Query()
+	userID := query.Get("userId")
+	if userID == "" {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "User ID is required"})
+		return
+	}
+	if err := config.ValidateConfigID(userID); err != nil {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "Invalid user ID: " + err.Error()})
+		return
+	}
+
+	kvStore, err := config.NewConfigStoreFromEnv()
+	if err != nil {
+		log.Printf("Failed to initialize config store: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	defer kvStore.Close()
+
+	store, err := config.NewTenantConfigStore(kvStore, query.Get("tenantId"))
+	if err != nil {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	entries, err := store.ReadAudit(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to read audit log: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	web.WriteJSON(w, r, http.StatusOK, AuditResponse{UserID: userID, Entries: entries})
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"playground/config"
+	"playground/settings"
+	"playground/web"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+)
+
+type EffectiveConfigResponse struct {
+	UserID   string                  `json:"userId"`
+	ActionID string                  `json:"actionId"`
+	Config   self.VerificationConfig `json:"config"`
+}
+
+// EffectiveConfig returns the fully-resolved config a verify call would enforce for a user
+// It goes through the same store wrappers as the verify handler so the two can't drift.
+// Responses carry an ETag, and a matching If-None-Match gets 304 Not Modified
+func EffectiveConfig(w http.ResponseWriter, r *http.Request) {
+	web.Recover(http.HandlerFunc(handleEffectiveConfig)).ServeHTTP(w, r)
+}
+
+func handleEffectiveConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		web.WriteJSON(w, r, http.StatusMethodNotAllowed, map[string]string{"message": "Method not allowed"})
+		return
+	}
+
+	query := r.URL.Query()
+	userID := query.Get("userId")
+	if userID == "" {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "User ID is required"})
+		return
+	}
+	if err := config.ValidateConfigID(userID); err != nil {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "Invalid user ID: " + err.Error()})
+		return
+	}
+
+	kvStore, err := config.NewConfigStoreFromEnv()
+	if err != nil {
+		log.Printf("Failed to initialize config store: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	defer kvStore.Close()
+
+	tenantStore, err := config.NewTenantConfigStore(kvStore, query.Get("tenantId"))
+	if err != nil {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+	settingsCfg, err := settings.Load()
+	if err != nil {
+		log.Printf("Failed to load settings: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	store := config.ResolvedConfigStore{VerificationConfigStore: tenantStore, Policy: settingsCfg.Policy()}
+
+	// Mirror the SDK: derive the action ID first, then load the config stored under it
+	ctx := r.Context()
+	if attestationID := query.Get("attestationId"); attestationID != "" {
+		if err := config.ValidateConfigID(attestationID); err != nil {
+			web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "Invalid attestation ID: " + err.Error()})
+			return
+		}
+		ctx = config.WithAttestation(ctx, attestationID)
+	}
+	actionID, err := store.GetActionId(ctx, userID, query.Get("userDefinedData"))
+	if err != nil {
+		log.Printf("Failed to get action ID: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	cfg, err := store.GetConfig(ctx, actionID)
+	if err != nil {
+		log.Printf("Failed to get config: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+
+	// The ETag follows the stored config's version, with the content mixed in because the
+	// version alone can't tell apart two stored configs, or a default whose env changed
+	version, found, err := tenantStore.ConfigVersion(ctx, actionID)
+	if err != nil {
+		log.Printf("Failed to get config version: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	configVersion := config.DefaultConfigVersion
+	if found {
+		configVersion = strconv.FormatInt(version, 10)
+	}
+	configJSON, err := json.Marshal(cfg)
+	if err != nil {
+		log.Printf("Failed to marshal config: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	if web.NotModified(w, r, web.ETag(actionID, configVersion, string(configJSON))) {
+		return
+	}
+
+	web.WriteJSON(w, r, http.StatusOK, EffectiveConfigResponse{
+		UserID:   userID,
+		ActionID: actionID,
+		Config:   cfg,
+	})
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"playground/config"
+	"playground/web"
+)
+
+type UpdateConfigResponse struct {
+	UserID string                         `json:"userId"`
+	Config config.SelfAppDisclosureConfig `json:"config"`
+}
+
+// UpdateConfig handles /api/config. GET returns the stored options with their savedAt, or
+// the defaults when there are none, with an ETag honoured in If-None-Match. PATCH changes only the fields present in the body,
+// everything else in the stored config is left as it was
+func UpdateConfig(w http.ResponseWriter, r *http.Request) {
+	web.Recover(web.RequireAdminToken(http.HandlerFunc(handleUpdateConfig))).ServeHTTP(w, r)
+}
+
+func handleUpdateConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPatch {
+		w.Header().Set("Allow", "GET, PATCH")
+		web.WriteJSON(w, r, http.StatusMethodNotAllowed, map[string]string{"message": "Method not allowed"})
+		return
+	}
+
+	query := r.URL.Query()
+	userID := query.Get("userId")
+	if userID == "" {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "User ID is required"})
+		return
+	}
+	if err := config.ValidateConfigID(userID); err != nil {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "Invalid user ID: " + err.Error()})
+		return
+	}
+
+	var patch config.SelfAppDisclosureConfig
+	if r.Method == http.MethodPatch {
+		if err := web.DecodeJSON(r.Body, &patch); err != nil {
+			web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "Invalid JSON: " + err.Error()})
+			return
+		}
+	}
+
+	kvStore, err := config.NewConfigStoreFromEnv()
+	if err != nil {
+		log.Printf("Failed to initialize config store: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	defer kvStore.Close()
+
+	store, err := config.NewTenantConfigStore(kvStore, query.Get("tenantId"))
+	if err != nil {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		options, err := store.GetDisclosureConfig(r.Context(), userID)
+		if err != nil {
+			log.Printf("Failed to get config: %v", err)
+			web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+			return
+		}
+		// Tagged like /api/config/effective, so a polling admin UI gets 304 until the options change
+		version, found, err := store.ConfigVersion(r.Context(), userID)
+		if err != nil {
+			log.Printf("Failed to get config version: %v", err)
+			web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+			return
+		}
+		configVersion := config.DefaultConfigVersion
+		if found {
+			configVersion = strconv.FormatInt(version, 10)
+		}
+		optionsJSON, err := json.Marshal(options)
+		if err != nil {
+			log.Printf("Failed to marshal config: %v", err)
+			web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+			return
+		}
+		if web.NotModified(w, r, web.ETag(userID, configVersion, string(optionsJSON))) {
+			return
+		}
+		web.WriteJSON(w, r, http.StatusOK, UpdateConfigResponse{UserID: userID, Config: options})
+		return
+	}
+
+	merged, err := store.UpdateConfig(r.Context(), userID, patch)
+	var validationErr *config.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		web.WriteJSON(w, r, http.StatusUnprocessableEntity, ValidateConfigResponse{Valid: false, Errors: validationErr.Errors})
+		return
+	case errors.Is(err, config.ErrConflict):
+		web.WriteJSON(w, r, http.StatusConflict, map[string]string{"message": err.Error()})
+		return
+	case err != nil:
+		log.Printf("Failed to update config: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+
+	web.WriteJSON(w, r, http.StatusOK, UpdateConfigResponse{UserID: userID, Config: merged})
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+const testAdminToken = "test-admin-token"
+
+func TestMain(m *testing.M) {
+	// Settings are loaded once per process, so the token has to be in place before any test runs
+	os.Setenv("ADMIN_API_TOKEN", testAdminToken)
+	os.Exit(m.Run())
+}
+
+// useRedis points NewConfigStoreFromEnv at a fresh in-memory Redis
+func useRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	mr.RequireAuth("kv-token")
+	t.Setenv("CONFIG_STORE_BACKEND", "redis")
+	t.Setenv("KV_REST_API_URL", "redis://"+mr.Addr())
+	t.Setenv("KV_REST_API_TOKEN", "kv-token")
+	return mr
+}
+
+func adminRequest(method, target, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+testAdminToken)
+	return r
+}
+
+func TestGetConfigReturnsSavedAt(t *testing.T) {
+	mr := useRedis(t)
+	const userID = "11111111-1111-1111-1111-111111111111"
+	mr.Set(userID, `{"minimumAge":21,"name":true,"savedAt":"2026-01-02T03:04:05Z"}`)
+
+	rec := httptest.NewRecorder()
+	UpdateConfig(rec, adminRequest(http.MethodGet, "/api/config?userId="+userID, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var resp UpdateConfigResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response %s: %v", rec.Body, err)
+	}
+	want := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if resp.UserID != userID || resp.Config.SavedAt == nil || !resp.Config.SavedAt.Equal(want) {
+		t.Errorf("response = %s, want savedAt %s", rec.Body, want.Format(time.RFC3339))
+	}
+	if resp.Config.MinimumAge == nil || *resp.Config.MinimumAge != 21 || resp.Config.Name == nil || !*resp.Config.Name {
+		t.Errorf("config = %s, want the stored options", rec.Body)
+	}
+}
+
+func TestGetConfigWithoutOptions(t *testing.T) {
+	useRedis(t)
+	rec := httptest.NewRecorder()
+	UpdateConfig(rec, adminRequest(http.MethodGet, "/api/config?userId=nobody", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "savedAt") {
+		t.Errorf("defaults = %s, want no savedAt", rec.Body)
+	}
+}
+
+func TestGetConfigNotModified(t *testing.T) {
+	useRedis(t)
+	const userID = "11111111-1111-1111-1111-111111111111"
+	get := func(etag string) *httptest.ResponseRecorder {
+		r := adminRequest(http.MethodGet, "/api/config?userId="+userID, "")
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		UpdateConfig(rec, r)
+		return rec
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status = %d, ETag %q, want 200 with an ETag", first.Code, etag)
+	}
+	if rec := get(etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("revalidation = %d with body %q, want an empty 304", rec.Code, rec.Body)
+	}
+
+	rec := httptest.NewRecorder()
+	UpdateConfig(rec, adminRequest(http.MethodPatch, "/api/config?userId="+userID, `{"minimumAge":21}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %d, body %s", rec.Code, rec.Body)
+	}
+	changed := get(etag)
+	if changed.Code != http.StatusOK || changed.Header().Get("ETag") == etag {
+		t.Errorf("after PATCH = %d with ETag %q, want 200 with a new ETag", changed.Code, changed.Header().Get("ETag"))
+	}
+}
+
+func TestUpdateConfigMethods(t *testing.T) {
+	useRedis(t)
+	rec := httptest.NewRecorder()
+	UpdateConfig(rec, adminRequest(http.MethodPost, "/api/config?userId=a", "{}"))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, PATCH" {
+		t.Errorf("POST = %d with Allow %q, want 405 allowing GET, PATCH", rec.Code, rec.Header().Get("Allow"))
+	}
+
+	rec = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/config?userId=a", nil)
+	UpdateConfig(rec, r)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET without the admin token = %d, want 401", rec.Code)
+	}
+}
+
+func TestPatchConfigMerge(t *testing.T) {
+	const userID = "11111111-1111-1111-1111-111111111111"
+	const stored = `{"minimumAge":21,"ofac":true,"name":true,"excludedCountries":["IRN","PRK"],"nationality_format":"iso3"}`
+	tests := []struct {
+		name   string
+		stored string
+		patch  string
+		code   int
+		// want is the stored config after the request, savedAt aside
+		want string
+	}{
+		{"add a flag", stored, `{"gender":true}`, http.StatusOK,
+			`{"minimumAge":21,"ofac":true,"name":true,"gender":true,"excludedCountries":["IRN","PRK"],"nationality_format":"iso3"}`},
+		{"change a value", stored, `{"minimumAge":30}`, http.StatusOK,
+			`{"minimumAge":30,"ofac":true,"name":true,"excludedCountries":["IRN","PRK"],"nationality_format":"iso3"}`},
+		{"turn a flag off", stored, `{"name":false,"ofac":false}`, http.StatusOK,
+			`{"minimumAge":21,"ofac":false,"name":false,"excludedCountries":["IRN","PRK"],"nationality_format":"iso3"}`},
+		{"empty patch", stored, `{}`, http.StatusOK, stored},
+		{"null leaves a field", stored, `{"excludedCountries":null,"minimumAge":null}`, http.StatusOK, stored},
+		{"empty list clears countries", stored, `{"excludedCountries":[]}`, http.StatusOK,
+			`{"minimumAge":21,"ofac":true,"name":true,"nationality_format":"iso3"}`},
+		{"countries are replaced and deduped", stored, `{"excludedCountries":["CUB","CUB","SYR"]}`, http.StatusOK,
+			`{"minimumAge":21,"ofac":true,"name":true,"excludedCountries":["CUB","SYR"],"nationality_format":"iso3"}`},
+		{"nothing stored", "", `{"name":true}`, http.StatusOK, `{"minimumAge":18,"ofac":true,"name":true}`},
+		{"invalid value", stored, `{"minimumAge":150}`, http.StatusUnprocessableEntity, stored},
+		{"invalid JSON", stored, `{"minimumAge":`, http.StatusBadRequest, stored},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := useRedis(t)
+			if tt.stored != "" {
+				mr.Set(userID, tt.stored)
+			}
+
+			rec := httptest.NewRecorder()
+			UpdateConfig(rec, adminRequest(http.MethodPatch, "/api/config?userId="+userID, tt.patch))
+			if rec.Code != tt.code {
+				t.Fatalf("status = %d, body %s, want %d", rec.Code, rec.Body, tt.code)
+			}
+
+			raw, err := mr.Get(userID)
+			if err != nil {
+				t.Fatalf("nothing stored for %s: %v", userID, err)
+			}
+			var got, want map[string]interface{}
+			if err := json.Unmarshal([]byte(raw), &got); err != nil {
+				t.Fatalf("stored %q: %v", raw, err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			_, saved := got["savedAt"]
+			if saved != (tt.code == http.StatusOK) {
+				t.Errorf("stored config %s, want savedAt only after a successful patch", raw)
+			}
+			delete(got, "savedAt")
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("stored config = %s, want %s", raw, tt.want)
+			}
+		})
+	}
+}
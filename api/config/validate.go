@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+
+	"playground/config"
+	"playground/web"
+)
+
+type ValidateConfigResponse struct {
+	Valid  bool                `json:"valid"`
+	Errors []config.FieldError `json:"errors"`
+}
+
+// ValidateConfig checks a config with the same validator SetConfig enforces, without storing it
+// Invalid configs still get a 200; only malformed requests get an error status
+func ValidateConfig(w http.ResponseWriter, r *http.Request) {
+	web.Recover(http.HandlerFunc(handleValidateConfig)).ServeHTTP(w, r)
+}
+
+func handleValidateConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		web.WriteJSON(w, r, http.StatusMethodNotAllowed, map[string]string{"message": "Method not allowed"})
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "Failed to read request body"})
+		return
+	}
+
+	_, fieldErrors, err := config.DecodeVerificationConfig(body)
+	if err != nil {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "Invalid JSON: " + web.DescribeJSONError(err)})
+		return
+	}
+
+	if fieldErrors == nil {
+		fieldErrors = []config.FieldError{}
+	}
+	web.WriteJSON(w, r, http.StatusOK, ValidateConfigResponse{
+		Valid:  len(fieldErrors) == 0,
+		Errors: fieldErrors,
+	})
+}
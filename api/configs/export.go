@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"playground/config"
+	"playground/web"
+)
+
+// ExportConfigs streams every stored config, exactly as stored, as newline-delimited JSON records
+func ExportConfigs(w http.ResponseWriter, r *http.Request) {
+	web.Recover(web.RequireAdminToken(http.HandlerFunc(handleExportConfigs))).ServeHTTP(w, r)
+}
+
+func handleExportConfigs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		web.WriteJSON(w, r, http.StatusMethodNotAllowed, map[string]string{"message": "Method not allowed"})
+		return
+	}
+
+	configStore, err := config.NewConfigStoreFromEnv()
+	if err != nil {
+		log.Printf("Failed to initialize config store: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	defer configStore.Close()
+
+	ctx := r.Context()
+	ids, err := configStore.ListIDs(ctx, "*")
+	if err == nil {
+		var attestationKeys []string
+		attestationKeys, err = configStore.ListAttestationKeys(ctx)
+		ids = append(ids, attestationKeys...)
+	}
+	if err != nil {
+		log.Printf("Failed to list configs: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	// Headers are already sent, so from here on failures can only be logged
+	enc := json.NewEncoder(w)
+	for _, id := range ids {
+		// The stored value is exported rather than GetConfig's view of it, which would
+		// drop saved disclosure options
+		value, ok, err := configStore.GetValue(ctx, id)
+		if err != nil {
+			log.Printf("Skipping %s during export: %v", id, err)
+			continue
+		}
+		if !ok {
+			// Expired since it was listed
+			continue
+		}
+		if !json.Valid([]byte(value)) {
+			log.Printf("Skipping %s during export: stored value is not JSON", id)
+			continue
+		}
+		if err := enc.Encode(config.Record{ID: id, Config: json.RawMessage(value)}); err != nil {
+			log.Printf("Export aborted: %v", err)
+			return
+		}
+	}
+}
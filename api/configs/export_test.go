@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"playground/config"
+)
+
+const testAdminToken = "test-admin-token"
+
+func TestMain(m *testing.M) {
+	// Settings are loaded once per process, so the token has to be in place before any test runs
+	os.Setenv("ADMIN_API_TOKEN", testAdminToken)
+	os.Exit(m.Run())
+}
+
+// useRedis points NewConfigStoreFromEnv at a fresh in-memory Redis
+func useRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	mr.RequireAuth("kv-token")
+	t.Setenv("CONFIG_STORE_BACKEND", "redis")
+	t.Setenv("KV_REST_API_URL", "redis://"+mr.Addr())
+	t.Setenv("KV_REST_API_TOKEN", "kv-token")
+	return mr
+}
+
+func adminRequest(method, target, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+testAdminToken)
+	return r
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	source := useRedis(t)
+	stored := map[string]string{
+		// Saved options, with disclosure flags a VerificationConfig would drop
+		"11111111-1111-1111-1111-111111111111":             `{"minimumAge":21,"name":true,"nationality":true,"nationality_format":"iso3","savedAt":"2026-01-02T03:04:05Z"}`,
+		"22222222-2222-2222-2222-222222222222":             `{"minimumAge":18,"ofac":true}`,
+		"tenant:acme:33333333-3333-3333-3333-333333333333": `{"minimumAge":30}`,
+		"attestation:1:default":                            `{"minimumAge":25}`,
+	}
+	for key, value := range stored {
+		source.Set(key, value)
+	}
+	// Other values sharing the keyspace must not be exported
+	source.Set(config.ResultCachePrefix+"abc", `{"result":true}`)
+	source.Set(config.StreamTokenPrefix+"def", `{"proof":{}}`)
+	source.Set("tenant:acme:"+config.StreamTokenPrefix+"ghi", `{"proof":{}}`)
+	source.Set("config-version:22222222-2222-2222-2222-222222222222", "3")
+	source.Lpush("audit:22222222-2222-2222-2222-222222222222", `{"result":true}`)
+	source.SAdd("userlist:blocked", "44444444-4444-4444-4444-444444444444")
+
+	export := httptest.NewRecorder()
+	ExportConfigs(export, adminRequest(http.MethodGet, "/api/configs/export", ""))
+	if export.Code != http.StatusOK {
+		t.Fatalf("export status = %d, body %s", export.Code, export.Body)
+	}
+
+	exported := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(export.Body.String()))
+	for scanner.Scan() {
+		record, err := config.DecodeRecord(scanner.Bytes())
+		if err != nil {
+			t.Fatalf("exported line %s doesn't decode: %v", scanner.Text(), err)
+		}
+		exported[record.ID] = string(record.Config)
+	}
+	if !reflect.DeepEqual(exported, stored) {
+		t.Fatalf("exported %v, want %v", exported, stored)
+	}
+
+	target := useRedis(t)
+	imported := httptest.NewRecorder()
+	ImportConfigs(imported, adminRequest(http.MethodPost, "/api/configs/import", export.Body.String()))
+	if imported.Code != http.StatusOK || !strings.Contains(imported.Body.String(), `"created":4`) {
+		t.Fatalf("import = %d %s, want 4 created", imported.Code, imported.Body)
+	}
+	for key, want := range stored {
+		if got, err := target.Get(key); err != nil || got != want {
+			t.Errorf("imported %s = %q, %v, want %q", key, got, err, want)
+		}
+	}
+
+	// Importing the same export again changes nothing
+	again := httptest.NewRecorder()
+	ImportConfigs(again, adminRequest(http.MethodPost, "/api/configs/import", export.Body.String()))
+	if !strings.Contains(again.Body.String(), `"unchanged":4`) {
+		t.Errorf("re-import = %s, want 4 unchanged", again.Body)
+	}
+}
+
+func TestImportRejectsInvalidRecords(t *testing.T) {
+	mr := useRedis(t)
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"missing id", `{"config":{"minimumAge":21}}`},
+		{"missing config", `{"id":"a"}`},
+		{"null config", `{"id":"a","config":null}`},
+		{"config not an object", `{"id":"a","config":[1]}`},
+		{"unknown record field", `{"id":"a","config":{},"extra":1}`},
+		{"invalid minimum age", `{"id":"a","config":{"minimumAge":150}}`},
+		{"wrong type", `{"id":"a","config":{"name":"yes"}}`},
+		{"internal key", `{"id":"userlist:allowed","config":{}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"id":"ok","config":{"minimumAge":21}}` + "\n" + tt.line + "\n"
+			rec := httptest.NewRecorder()
+			ImportConfigs(rec, adminRequest(http.MethodPost, "/api/configs/import", body))
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "line 2") {
+				t.Errorf("import = %d %s, want 400 naming line 2", rec.Code, rec.Body)
+			}
+			if mr.Exists("ok") {
+				t.Error("a valid record was written although the import was rejected")
+			}
+		})
+	}
+}
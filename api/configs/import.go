@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"playground/config"
+	"playground/web"
+)
+
+// maxImportLine bounds a single NDJSON record so one huge line can't exhaust memory
+const maxImportLine = 1 << 20
+
+type ImportConfigsResponse struct {
+	Message string `json:"message"`
+	Created int    `json:"created"`
+	Updated int    `json:"updated"`
+	// Unchanged counts records identical to what was already stored, e.g. when an import is retried
+	Unchanged int      `json:"unchanged"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// ImportConfigs reads newline-delimited JSON records and stores each value as exported
+// Every record is validated before any is written, so a bad line leaves the store untouched
+func ImportConfigs(w http.ResponseWriter, r *http.Request) {
+	web.Recover(web.RequireAdminToken(http.HandlerFunc(handleImportConfigs))).ServeHTTP(w, r)
+}
+
+func handleImportConfigs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		web.WriteJSON(w, r, http.StatusMethodNotAllowed, map[string]string{"message": "Method not allowed"})
+		return
+	}
+
+	// Validate while spooling to disk, so the write pass doesn't need the whole import in memory
+	spool, err := os.CreateTemp("", "config-import-*.ndjson")
+	if err != nil {
+		log.Printf("Failed to create import spool: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	var problems []string
+	scanner := bufio.NewScanner(io.TeeReader(r.Body, spool))
+	scanner.Buffer(make([]byte, 64*1024), maxImportLine)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if _, err := config.DecodeRecord(scanner.Bytes()); err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %s", line, web.DescribeJSONError(err)))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		problems = append(problems, fmt.Sprintf("failed to read import: %v", err))
+	}
+	if len(problems) > 0 {
+		web.WriteJSON(w, r, http.StatusBadRequest, ImportConfigsResponse{Message: "Import rejected, nothing was written", Errors: problems})
+		return
+	}
+
+	configStore, err := config.NewConfigStoreFromEnv()
+	if err != nil {
+		log.Printf("Failed to initialize config store: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	defer configStore.Close()
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		log.Printf("Failed to rewind import spool: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+
+	var resp ImportConfigsResponse
+	ctx := r.Context()
+	scanner = bufio.NewScanner(spool)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLine)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		record, _ := config.DecodeRecord(scanner.Bytes())
+		result, err := configStore.SetRawConfig(ctx, record.ID, string(record.Config))
+		if err != nil {
+			log.Printf("Import failed at %s after %d created, %d updated, %d unchanged: %v", record.ID, resp.Created, resp.Updated, resp.Unchanged, err)
+			resp.Message = "Import failed part-way"
+			resp.Errors = []string{fmt.Sprintf("failed to store %s", record.ID)}
+			web.WriteJSON(w, r, http.StatusInternalServerError, resp)
+			return
+		}
+		switch {
+		case result.Created:
+			resp.Created++
+		case result.Changed:
+			resp.Updated++
+		default:
+			resp.Unchanged++
+		}
+	}
+
+	resp.Message = "Import completed"
+	web.WriteJSON(w, r, http.StatusOK, resp)
+}
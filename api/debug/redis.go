@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"playground/config"
+	"playground/settings"
+	"playground/web"
+)
+
+// probeTimeout bounds the whole Redis probe, so a hung connection still gets an answer
+const probeTimeout = 5 * time.Second
+
+type RedisProbeResponse struct {
+	Status string             `json:"status"`
+	Steps  []config.ProbeStep `json:"steps"`
+}
+
+// DebugRedis checks the Redis credentials and connection without a verification: a ping
+// and a set, get and delete of a throwaway key, each timed. It answers 503 when a step
+// fails, and 404 unless ENABLE_DEBUG_ENDPOINTS=true
+func DebugRedis(w http.ResponseWriter, r *http.Request) {
+	web.Recover(debugEndpoint(web.RequireAdminToken(http.HandlerFunc(handleDebugRedis)))).ServeHTTP(w, r)
+}
+
+// debugEndpoint hides next entirely, as if it didn't exist, unless debug endpoints are enabled
+func debugEndpoint(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg, err := settings.Load(); err != nil || !cfg.EnableDebugEndpoints {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func handleDebugRedis(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		web.WriteJSON(w, r, http.StatusMethodNotAllowed, map[string]string{"message": "Method not allowed"})
+		return
+	}
+
+	// The store's constructor already dials unless REDIS_CONNECT=lazy, so a bad
+	// URL or password can fail here, before any step runs
+	started := time.Now()
+	store, err := config.NewKVConfigStoreFromEnv()
+	if err != nil {
+		web.WriteJSON(w, r, http.StatusServiceUnavailable, RedisProbeResponse{
+			Status: "error",
+			Steps: []config.ProbeStep{{
+				Step:      "connect",
+				LatencyMs: time.Since(started).Milliseconds(),
+				Error:     err.Error(),
+			}},
+		})
+		return
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(r.Context(), probeTimeout)
+	defer cancel()
+	steps, ok := store.Probe(ctx)
+	if !ok {
+		web.WriteJSON(w, r, http.StatusServiceUnavailable, RedisProbeResponse{Status: "error", Steps: steps})
+		return
+	}
+	web.WriteJSON(w, r, http.StatusOK, RedisProbeResponse{Status: "ok", Steps: steps})
+}
@@ -1,97 +1,175 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"time"
 
 	"playground/config"
+	"playground/settings"
+	"playground/web"
 )
 
 type SaveOptionsRequest struct {
-	UserID  string      `json:"userId"`
-	Options interface{} `json:"options"`
+	UserID   string      `json:"userId"`
+	TenantID string      `json:"tenantId,omitempty"`
+	Options  interface{} `json:"options"`
 }
 
+// Values of the mode query parameter: upsert (the default) replaces existing options,
+// create fails with 409 when the user already has some
+const (
+	saveModeUpsert = "upsert"
+	saveModeCreate = "create"
+)
+
 type SaveOptionsResponse struct {
 	Message string `json:"message"`
+	// Errors lists the invalid option values when the options are rejected
+	Errors []config.FieldError `json:"errors,omitempty"`
 }
 
 func GoSaveOptions(w http.ResponseWriter, r *http.Request) {
-	// Enable CORS
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	web.Recover(web.Trace(web.CORS(web.DecompressRequest(web.Gzip(http.HandlerFunc(handleSaveOptions)))))).ServeHTTP(w, r)
+}
 
-	if r.Method == "OPTIONS" {
-		w.WriteHeader(http.StatusOK)
+func handleSaveOptions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		web.WriteJSON(w, r, http.StatusMethodNotAllowed, map[string]string{"message": "Method not allowed"})
 		return
 	}
 
-	if r.Method != "POST" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(map[string]string{"message": "Method not allowed"})
+	cfg, err := settings.Load()
+	if err != nil {
+		log.Printf("Failed to load settings: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	mode := r.URL.Query().Get("mode")
+	switch mode {
+	case "":
+		mode = saveModeUpsert
+	case saveModeUpsert, saveModeCreate:
+	default:
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": fmt.Sprintf("mode must be %q or %q, got %q", saveModeCreate, saveModeUpsert, mode)})
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "Failed to read request body"})
+		return
+	}
+
+	// Only holders of the shared secret may overwrite a user's options.
+	// SAVE_OPTIONS_SKIP_SIGNATURE=true disables the check for local development
+	if !cfg.SkipSaveOptionsSignature {
+		secret := cfg.SaveOptionsSecret
+		if secret == "" {
+			log.Printf("SAVE_OPTIONS_SECRET is not set; rejecting saveOptions request")
+			web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+			return
+		}
+		if !web.VerifySignature(body, r.Header.Get(web.SignatureHeader), secret) {
+			web.WriteJSON(w, r, http.StatusUnauthorized, map[string]string{"message": "Invalid or missing signature"})
+			return
+		}
+	}
 
 	var req SaveOptionsRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid JSON"})
+	if err := web.DecodeJSON(bytes.NewReader(body), &req); err != nil {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "Invalid JSON: " + err.Error()})
 		return
 	}
 
 	if req.UserID == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"message": "User ID is required"})
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "User ID is required"})
+		return
+	}
+	// The ID becomes a store key, so only identifiers the verifier would accept are stored
+	if err := config.ValidateUserID(req.UserID, cfg.UserIDType); err != nil {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": err.Error()})
 		return
 	}
 
 	if req.Options == nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"message": "Options are required"})
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "Options are required"})
+		return
+	}
+
+	// Options are stored as sent, stamped with when they were saved so OPTIONS_MAX_AGE can
+	// tell stale ones apart, but must pass the same checks as a stored config
+	if fields, ok := req.Options.(map[string]interface{}); ok {
+		fields["savedAt"] = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	optionsJSON, err := json.Marshal(req.Options)
+	if err != nil {
+		log.Printf("Failed to marshal options: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error", "error": "Failed to serialize options"})
+		return
+	}
+	var options config.SelfAppDisclosureConfig
+	if err := json.Unmarshal(optionsJSON, &options); err != nil {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "Invalid options: " + err.Error()})
+		return
+	}
+	if errs := config.ValidateDisclosureConfig(options); len(errs) > 0 {
+		web.WriteJSON(w, r, http.StatusUnprocessableEntity, SaveOptionsResponse{Message: "Invalid options", Errors: errs})
 		return
 	}
 
 	// Initialize Redis config store - matching TypeScript implementation
-	configStore, err := config.NewKVConfigStoreFromEnv()
+	configStore, err := config.NewConfigStoreFromEnv()
 	if err != nil {
 		log.Printf("Failed to initialize config store: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"message": "Internal server error", "error": err.Error()})
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error", "error": err.Error()})
 		return
 	}
 	defer configStore.Close()
 
-	// Store options in Redis with 30-minute expiration (matching TypeScript: ex: 1800)
-	ctx := context.Background()
-	optionsJSON, err := json.Marshal(req.Options)
+	// Scope the store to the requesting tenant, falling back to the query parameter
+	tenantID := req.TenantID
+	if tenantID == "" {
+		tenantID = r.URL.Query().Get("tenantId")
+	}
+	tenantStore, err := config.NewTenantConfigStore(configStore, tenantID)
 	if err != nil {
-		log.Printf("Failed to marshal options: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"message": "Internal server error", "error": "Failed to serialize options"})
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": err.Error()})
 		return
 	}
 
-	// Use Redis SET with expiration (1800 seconds = 30 minutes, matching TypeScript)
-	err = configStore.SetWithExpiration(ctx, req.UserID, string(optionsJSON), 30*time.Minute)
-	if err != nil {
+	// Store options in Redis with 30-minute expiration (matching TypeScript: ex: 1800)
+	ctx := context.Background()
+
+	// Use Redis SET with expiration (1800 seconds = 30 minutes, matching TypeScript), bumping
+	// the config version with it so X-Config-Version tells the new options from the old.
+	// mode=create refuses to replace options that are still live instead of overwriting them
+	stored, err := tenantStore.SaveOptions(ctx, req.UserID, string(optionsJSON), 30*time.Minute, mode == saveModeCreate)
+	switch {
+	case errors.Is(err, config.ErrConflict):
+		web.WriteJSON(w, r, http.StatusConflict, map[string]string{"message": "Options were being saved concurrently, please retry"})
+		return
+	case err != nil:
 		log.Printf("Failed to save options to Redis: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"message": "Internal server error", "error": "Failed to save options"})
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error", "error": "Failed to save options"})
+		return
+	case !stored:
+		web.WriteJSON(w, r, http.StatusConflict, map[string]string{"message": "Options already exist for this user"})
 		return
 	}
 
-	log.Printf("Saved options for user: %s, options: %+v\n", req.UserID, req.Options)
+	log.Printf("Saved options for user: %s, options: %s\n", req.UserID, web.Redact(req.Options))
 
 	response := SaveOptionsResponse{
 		Message: "Options saved successfully",
 	}
 
-	json.NewEncoder(w).Encode(response)
+	web.WriteJSON(w, r, http.StatusOK, response)
 }
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"playground/config"
+	"playground/web"
+)
+
+// useRedis points NewConfigStoreFromEnv at a fresh in-memory Redis
+func useRedis(t testing.TB) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	mr.RequireAuth("kv-token")
+	t.Setenv("CONFIG_STORE_BACKEND", "redis")
+	t.Setenv("KV_REST_API_URL", "redis://"+mr.Addr())
+	t.Setenv("KV_REST_API_TOKEN", "kv-token")
+	return mr
+}
+
+// saveOptions posts body signed with the test secret; query is appended to the URL as-is
+func saveOptions(body, query string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "https://example.com/api/go-saveOptions"+query, strings.NewReader(body))
+	r.Header.Set(web.SignatureHeader, web.Sign([]byte(body), testSaveOptionsSecret))
+	rec := httptest.NewRecorder()
+	GoSaveOptions(rec, r)
+	return rec
+}
+
+func TestSaveOptionsBumpsConfigVersion(t *testing.T) {
+	mr := useRedis(t)
+	store, err := config.NewKVConfigStore("redis://"+mr.Addr(), "kv-token")
+	if err != nil {
+		t.Fatalf("NewKVConfigStore: %v", err)
+	}
+	defer store.Close()
+	deps := useTestDeps(t, &mockVerifier{result: validResult()})
+	deps.store = store
+	body := verifyBody(t, numberedSignals(21, 2))
+
+	for i, want := range []string{"1", "2"} {
+		options := `{"userId":"` + testUserID + `","options":{"minimumAge":` + []string{"18", "21"}[i] + `}}`
+		if rec := saveOptions(options, ""); rec.Code != http.StatusOK {
+			t.Fatalf("save %d = %d %s", i+1, rec.Code, rec.Body)
+		}
+		rec := postVerify(body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("verify = %d %s", rec.Code, rec.Body)
+		}
+		if got := rec.Header().Get(configVersionHeader); got != want {
+			t.Errorf("%s after save %d = %q, want %q", configVersionHeader, i+1, got, want)
+		}
+	}
+}
+
+func TestSaveOptionsSignature(t *testing.T) {
+	body := `{"userId":"` + testUserID + `","options":{"minimumAge":18}}`
+	tests := []struct {
+		name      string
+		signature string
+		want      int
+	}{
+		{"valid", web.Sign([]byte(body), testSaveOptionsSecret), http.StatusOK},
+		{"valid with prefix", "sha256=" + web.Sign([]byte(body), testSaveOptionsSecret), http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong secret", web.Sign([]byte(body), "other-secret"), http.StatusUnauthorized},
+		{"other body", web.Sign([]byte(`{"userId":"`+testUserID+`","options":{"minimumAge":21}}`), testSaveOptionsSecret), http.StatusUnauthorized},
+		{"not hex", "not-a-signature", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := useRedis(t)
+			r := httptest.NewRequest(http.MethodPost, "https://example.com/api/go-saveOptions", strings.NewReader(body))
+			if tt.signature != "" {
+				r.Header.Set(web.SignatureHeader, tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			GoSaveOptions(rec, r)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d %s, want %d", rec.Code, rec.Body, tt.want)
+			}
+			if saved := mr.Exists(testUserID); saved != (tt.want == http.StatusOK) {
+				t.Errorf("options saved = %v with status %d", saved, rec.Code)
+			}
+		})
+	}
+}
+
+func TestSaveOptionsModes(t *testing.T) {
+	const existing = `{"minimumAge":18}`
+	options := `{"userId":"` + testUserID + `","options":{"minimumAge":21}}`
+	tests := []struct {
+		name     string
+		query    string
+		stored   bool
+		wantCode int
+		wantAge  float64
+	}{
+		{"default upserts new", "", false, http.StatusOK, 21},
+		{"default upserts existing", "", true, http.StatusOK, 21},
+		{"upsert new", "?mode=upsert", false, http.StatusOK, 21},
+		{"upsert existing", "?mode=upsert", true, http.StatusOK, 21},
+		{"create new", "?mode=create", false, http.StatusOK, 21},
+		{"create existing", "?mode=create", true, http.StatusConflict, 18},
+		{"unknown mode", "?mode=replace", true, http.StatusBadRequest, 18},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := useRedis(t)
+			if tt.stored {
+				mr.Set(testUserID, existing)
+			}
+
+			rec := saveOptions(options, tt.query)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, body %s, want %d", rec.Code, rec.Body, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusConflict && !strings.Contains(rec.Body.String(), "already exist") {
+				t.Errorf("body = %s, want it to say the options already exist", rec.Body)
+			}
+			raw, err := mr.Get(testUserID)
+			var stored map[string]interface{}
+			if err == nil {
+				err = json.Unmarshal([]byte(raw), &stored)
+			}
+			if err != nil || stored["minimumAge"] != tt.wantAge {
+				t.Errorf("stored options = %s, %v, want minimumAge %v", raw, err, tt.wantAge)
+			}
+		})
+	}
+}
+
+func TestSaveOptionsCreateExpired(t *testing.T) {
+	mr := useRedis(t)
+	options := `{"userId":"` + testUserID + `","options":{"minimumAge":21}}`
+	if rec := saveOptions(options, "?mode=create"); rec.Code != http.StatusOK {
+		t.Fatalf("first create = %d %s", rec.Code, rec.Body)
+	}
+	if rec := saveOptions(options, "?mode=create"); rec.Code != http.StatusConflict {
+		t.Fatalf("second create = %d %s, want 409", rec.Code, rec.Body)
+	}
+	// Saved options last 30 minutes; once they are gone create succeeds again
+	mr.FastForward(31 * time.Minute)
+	if rec := saveOptions(options, "?mode=create"); rec.Code != http.StatusOK {
+		t.Errorf("create after expiry = %d %s, want 200", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"playground/config"
+	"playground/settings"
+	"playground/web"
+)
+
+// Progress stages reported while a streamed verification runs
+const (
+	progressReceived     = "received"
+	progressConfigLoaded = "config-loaded"
+	progressVerifying    = "verifying"
+	progressDone         = "done"
+)
+
+const (
+	// streamTokenTTL is how long a submitted proof waits for its stream to be opened
+	streamTokenTTL = 2 * time.Minute
+	// streamKeepAlive is the comment interval that stops proxies closing an idle stream
+	streamKeepAlive = 15 * time.Second
+	// streamAllowedMethods is the Allow header sent with 405 responses; web.CORS answers OPTIONS
+	streamAllowedMethods = "GET, POST, OPTIONS"
+)
+
+type StreamTokenResponse struct {
+	Token string `json:"token"`
+}
+
+type progressKey struct{}
+
+// reportProgress tells a streaming client which stage its verification reached, and
+// marks it as a phase for the slow-request log
+func reportProgress(ctx context.Context, stage string) {
+	web.MarkPhase(ctx, stage)
+	if report, ok := ctx.Value(progressKey{}).(func(string)); ok {
+		report(stage)
+	}
+}
+
+// VerifyStream is the server-sent events variant of Handler for slow verifications.
+// POST takes the same body as /api/go-verify and returns a token; GET ?token= then runs
+// the verification, emitting progress events and a final "result" event with the
+// VerifyResponse. Clients that don't accept text/event-stream get the plain JSON response
+func VerifyStream(w http.ResponseWriter, r *http.Request) {
+	// No Gzip: it buffers output, which would hold events back until the stream ends
+	web.Recover(web.Trace(web.CORS(web.Casing(web.DecompressRequest(http.HandlerFunc(handleVerifyStream)))))).ServeHTTP(w, r)
+}
+
+func handleVerifyStream(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		submitStreamProof(w, r)
+	case http.MethodGet:
+		streamVerification(w, r)
+	default:
+		w.Header().Set("Allow", streamAllowedMethods)
+		web.WriteJSON(w, r, http.StatusMethodNotAllowed, map[string]string{"message": "Method not allowed"})
+	}
+}
+
+// submitStreamProof checks the request the way the verify handler would and parks it
+// in the store under a random token until the stream is opened
+func submitStreamProof(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": web.DescribeJSONError(err)})
+		return
+	}
+	cfg, err := settings.Load()
+	if err != nil {
+		log.Printf("Failed to load settings: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	if _, err := parseVerifyRequest(bytes.NewReader(body), cfg.MaxPublicSignals); err != nil {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	deps, err := loadVerifyDeps()
+	if err != nil {
+		log.Printf("Failed to initialize verify dependencies: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		log.Printf("Failed to generate stream token: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	token := hex.EncodeToString(buf)
+	if err := deps.store.SetWithExpiration(r.Context(), config.StreamTokenPrefix+token, string(body), streamTokenTTL); err != nil {
+		log.Printf("Failed to store streamed proof: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	web.WriteJSON(w, r, http.StatusAccepted, StreamTokenResponse{Token: token})
+}
+
+// streamVerification replays the parked request through handleVerify, forwarding its
+// progress as events while the response is captured for the final event
+func streamVerification(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "token is required"})
+		return
+	}
+	deps, err := loadVerifyDeps()
+	if err != nil {
+		log.Printf("Failed to initialize verify dependencies: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	// Taking the proof deletes it, so a token opens one stream and a replay gets a 404
+	body, ok, err := deps.store.TakeValue(r.Context(), config.StreamTokenPrefix+token)
+	if err != nil {
+		log.Printf("Failed to load streamed proof: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	if !ok {
+		web.WriteJSON(w, r, http.StatusNotFound, map[string]string{"message": "Unknown or expired token"})
+		return
+	}
+
+	verifyReq := r.Clone(r.Context())
+	verifyReq.Method = http.MethodPost
+	verifyReq.Body = io.NopCloser(strings.NewReader(body))
+	verifyReq.ContentLength = int64(len(body))
+
+	// Without SSE support there is nowhere to send progress, so answer like /api/go-verify
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") || !canFlush(w) {
+		handleVerify(w, verifyReq)
+		return
+	}
+
+	progress := make(chan string, 4)
+	verifyReq = verifyReq.WithContext(context.WithValue(r.Context(), progressKey{}, func(stage string) {
+		select {
+		case progress <- stage:
+		case <-r.Context().Done():
+		}
+	}))
+	captured := newCapturedResponse()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(progress)
+		handleVerify(captured, verifyReq)
+	}()
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	// The result event carries the disclosed fields
+	web.NoStore(w)
+	h.Set("Connection", "keep-alive")
+	// Stops nginx-style proxies from buffering the stream
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	rc.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case stage, open := <-progress:
+			if !open {
+				<-done
+				writeEvent(w, progressDone, map[string]string{"stage": progressDone})
+				writeEvent(w, "result", captured.result())
+				rc.Flush()
+				return
+			}
+			writeEvent(w, stage, map[string]string{"stage": stage})
+			rc.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			rc.Flush()
+		case <-r.Context().Done():
+			// handleVerify sees the same cancellation; wait so it is done with the store
+			<-done
+			return
+		}
+	}
+}
+
+func writeEvent(w io.Writer, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		payload = []byte(`{}`)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
+
+// canFlush reports whether w, or a writer it wraps, can push partial output to the client
+func canFlush(w http.ResponseWriter) bool {
+	for {
+		if _, ok := w.(http.Flusher); ok {
+			return true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = u.Unwrap()
+	}
+}
+
+// capturedResponse holds the response handleVerify writes so it can be sent as an event
+// The status isn't kept: the stream itself is already a 200 and VerifyResponse carries the outcome
+type capturedResponse struct {
+	header http.Header
+	body   bytes.Buffer
+}
+
+func newCapturedResponse() *capturedResponse {
+	return &capturedResponse{header: make(http.Header)}
+}
+
+func (c *capturedResponse) Header() http.Header         { return c.header }
+func (c *capturedResponse) WriteHeader(status int)      {}
+func (c *capturedResponse) Write(p []byte) (int, error) { return c.body.Write(p) }
+
+// result is the captured JSON body; plain-text errors are wrapped so the event stays JSON
+func (c *capturedResponse) result() interface{} {
+	if json.Valid(c.body.Bytes()) {
+		return json.RawMessage(c.body.Bytes())
+	}
+	return map[string]string{"message": strings.TrimSpace(c.body.String())}
+}
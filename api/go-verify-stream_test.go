@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// submitStream parks body and returns its stream token
+func submitStream(t *testing.T, body string) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	VerifyStream(rec, httptest.NewRequest(http.MethodPost, "https://example.com/api/go-verify-stream", strings.NewReader(body)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("submit status = %d, body %s", rec.Code, rec.Body)
+	}
+	var resp StreamTokenResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Token == "" {
+		t.Fatalf("submit response %s has no token: %v", rec.Body, err)
+	}
+	return resp.Token
+}
+
+func openStream(token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/api/go-verify-stream?token="+token, nil)
+	r.Header.Set("Accept", "text/event-stream")
+	rec := httptest.NewRecorder()
+	VerifyStream(rec, r)
+	return rec
+}
+
+func TestVerifyStreamTokenIsSingleUse(t *testing.T) {
+	verifier := &mockVerifier{result: validResult()}
+	useTestDeps(t, verifier)
+	token := submitStream(t, verifyBody(t, numberedSignals(21, 2)))
+
+	first := openStream(token)
+	if first.Code != http.StatusOK || !strings.Contains(first.Body.String(), "event: result") {
+		t.Fatalf("first open = %d %s, want the result event", first.Code, first.Body)
+	}
+	replay := openStream(token)
+	if replay.Code != http.StatusNotFound {
+		t.Errorf("replayed open = %d %s, want 404", replay.Code, replay.Body)
+	}
+	if verifier.calls.Load() != 1 {
+		t.Errorf("verifier called %d times, want once", verifier.calls.Load())
+	}
+}
+
+func TestVerifyStreamConcurrentOpens(t *testing.T) {
+	verifier := &mockVerifier{result: validResult()}
+	useTestDeps(t, verifier)
+	token := submitStream(t, verifyBody(t, numberedSignals(21, 2)))
+
+	const opens = 8
+	codes := make(chan int, opens)
+	var wg sync.WaitGroup
+	for i := 0; i < opens; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- openStream(token).Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+	if counts[http.StatusOK] != 1 || counts[http.StatusNotFound] != opens-1 {
+		t.Errorf("status counts = %v, want one 200 and %d 404s", counts, opens-1)
+	}
+	if verifier.calls.Load() != 1 {
+		t.Errorf("verifier called %d times, want once", verifier.calls.Load())
+	}
+}
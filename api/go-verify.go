@@ -1,245 +1,1020 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"playground/config"
+	"playground/jwt"
+	"playground/settings"
+	"playground/verification"
+	"playground/web"
 
 	self "github.com/selfxyz/self/sdk/sdk-go"
 )
 
 type VerifyRequest struct {
-	AttestationID   string      `json:"attestationId"`
-	Proof           interface{} `json:"proof"`
-	PublicSignals   interface{} `json:"publicSignals"`
-	UserContextData interface{} `json:"userContextData"`
-	UserID          string      `json:"userId,omitempty"`
+	AttestationID string      `json:"attestationId"`
+	Proof         interface{} `json:"proof"`
+	// PublicSignals is kept raw so numeric signals can be read without float64 rounding
+	PublicSignals   json.RawMessage `json:"publicSignals"`
+	UserContextData interface{}     `json:"userContextData"`
+	UserID          string          `json:"userId,omitempty"`
+	TenantID        string          `json:"tenantId,omitempty"`
+	// ConfigOverride replaces the stored config for this verification only. The request
+	// must carry an X-Signature made with CONFIG_OVERRIDE_SECRET
+	ConfigOverride *self.VerificationConfig `json:"configOverride,omitempty"`
 }
 
 type VerifyResponse struct {
-	Status              string      `json:"status"`
-	Result              bool        `json:"result"`
-	Message             string      `json:"message,omitempty"`
-	CredentialSubject   interface{} `json:"credentialSubject,omitempty"`
-	VerificationOptions interface{} `json:"verificationOptions,omitempty"`
+	Status              string               `json:"status"`
+	Result              bool                 `json:"result"`
+	Message             string               `json:"message,omitempty"`
+	ErrorCode           string               `json:"errorCode,omitempty"`
+	CredentialSubject   *CredentialSubject   `json:"credentialSubject,omitempty"`
+	VerificationOptions *VerificationOptions `json:"verificationOptions,omitempty"`
+	Warnings            []string             `json:"warnings,omitempty"`
+	// RawDiscloseOutput is the unfiltered disclosure, only set when EXPOSE_RAW_DISCLOSURE=true
+	RawDiscloseOutput interface{} `json:"rawDiscloseOutput,omitempty"`
+	// Disclosure maps each disclosable field's option name to whether its value was disclosed
+	Disclosure map[string]bool `json:"disclosure,omitempty"`
+	// Age is the holder's age in years, set when date_of_birth_format is "age" or "both"
+	Age *int `json:"age,omitempty"`
+	// Token is the signed JWT of this result, only set when ISSUE_JWT=true
+	Token string `json:"token,omitempty"`
+	// ConfigVersion identifies the config the verification ran under: its version number,
+	// "default" for the built-in defaults or "override" for a signed configOverride
+	ConfigVersion string `json:"configVersion,omitempty"`
+	// Timing breaks down where the request's time went, only set for X-Debug-Timing: true
+	// when ENABLE_DEBUG_ENDPOINTS=true
+	Timing *VerifyTiming `json:"timing,omitempty"`
+	// Nonce echoes the userContextData nonce so clients can match the result to their request;
+	// empty when the context carried none
+	Nonce string `json:"nonce"`
 }
 
+// CredentialSubject is the disclosed credential: the SDK's disclose output with every
+// withheld field replaced by the placeholder, or left out when omit is set
+type CredentialSubject struct {
+	self.GenericDiscloseOutput
+
+	fields      []verification.DisclosureField
+	disclosure  map[string]bool
+	placeholder verification.Placeholder
+	omit        bool
+}
+
+// MarshalJSON encodes the disclose output as-is unless withheld fields need rewriting
+func (s CredentialSubject) MarshalJSON() ([]byte, error) {
+	if !s.omit && s.placeholder.IsDefault() {
+		return json.Marshal(s.GenericDiscloseOutput)
+	}
+	out, err := verification.RenderWithheld(s.GenericDiscloseOutput, s.fields, s.disclosure, s.placeholder, s.omit)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+// VerificationOptions echoes the checks a successful verification ran with
+// Fields are in key order so the encoding matches the map this replaced
+type VerificationOptions struct {
+	// ExcludedCountries is a list of codes, names or code/name pairs per excluded_countries_format
+	ExcludedCountries interface{} `json:"excludedCountries"`
+	MinimumAge        *int        `json:"minimumAge"`
+	Ofac              *bool       `json:"ofac"`
+}
+
+// debugTimingHeader asks for the timing breakdown in the verify response
+const debugTimingHeader = "X-Debug-Timing"
+
+// VerifyTiming is the milliseconds a verify request spent in each phase
+type VerifyTiming struct {
+	// DecodeMs covers reading and validating the request body
+	DecodeMs int64 `json:"decodeMs"`
+	// VerificationMs covers the result cache and the SDK verification, retries included
+	VerificationMs int64 `json:"verificationMs"`
+	// ConfigLookupMs covers reading the saved options and the config version
+	ConfigLookupMs int64 `json:"configLookupMs"`
+	// FilteringMs covers applying the disclosure options and building the response
+	FilteringMs int64 `json:"filteringMs"`
+}
+
+// checkUserAccess answers 403 and returns false when userID is blocked or missing from an
+// active allow list. The lists are global, so they are read from the untenanted store
+func checkUserAccess(w http.ResponseWriter, r *http.Request, store config.ConfigStore, userID string) bool {
+	access, err := config.CheckUserAccess(r.Context(), store, userID)
+	if err != nil {
+		log.Printf("[%s] Failed to check user lists: %v", web.RequestID(r), err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	switch access {
+	case config.UserBlocked:
+		web.WriteJSON(w, r, http.StatusForbidden, VerifyResponse{
+			Status:    "error",
+			Result:    false,
+			Message:   "This user is blocked from verifying",
+			ErrorCode: verification.ErrorCodeUserBlocked,
+		})
+		return false
+	case config.UserNotAllowed:
+		web.WriteJSON(w, r, http.StatusForbidden, VerifyResponse{
+			Status:    "error",
+			Result:    false,
+			Message:   "This user is not on the allow list",
+			ErrorCode: verification.ErrorCodeUserNotAllowed,
+		})
+		return false
+	}
+	return true
+}
+
+// isJSONNull reports whether raw is missing or an explicit null
+func isJSONNull(raw json.RawMessage) bool {
+	raw = bytes.TrimSpace(raw)
+	return len(raw) == 0 || bytes.Equal(raw, []byte("null"))
+}
+
+const (
+	// configVersionHeader repeats VerifyResponse.ConfigVersion for clients that only read headers
+	configVersionHeader = "X-Config-Version"
+	// configOverrideVersion labels verifications that ran under a signed configOverride
+	configOverrideVersion = "override"
+)
+
+// parsedVerifyRequest is a verify request that passed decoding and pre-flight validation
+type parsedVerifyRequest struct {
+	VerifyRequest
+	attestation     verification.Attestation
+	proof           self.VcAndDiscloseProof
+	publicSignals   []string
+	userContextData string
+	// nonce is the user context's nonce, empty when it carried none
+	nonce string
+	// objectContext is set for JSON object contexts, the only shape that can carry a nonce
+	objectContext bool
+}
+
+// requestError is a client error together with the status it should be reported with
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &requestError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+// parseVerifyRequest decodes and validates a verify request body, accepting as many public
+// signals as limits allows. It touches no shared state or dependencies, so it is safe to
+// drive with arbitrary input (e.g. from a fuzzer), and every failure it returns is a *requestError
+func parseVerifyRequest(body io.Reader, limits verification.PublicSignalLimits) (*parsedVerifyRequest, error) {
+	var req VerifyRequest
+	if err := web.DecodeJSON(body, &req); err != nil {
+		return nil, badRequest("Invalid JSON: %s", err)
+	}
+
+	// Validate required fields - equivalent to TypeScript validation
+	if req.Proof == nil || isJSONNull(req.PublicSignals) || req.AttestationID == "" || req.UserContextData == nil {
+		return nil, badRequest("Proof, publicSignals, attestationId and userContextData are required")
+	}
+
+	// Reject unknown attestations and malformed proofs before spending a verification on them
+	attestation, ok := verification.LookupAttestation(req.AttestationID)
+	if !ok {
+		return nil, badRequest("Unknown attestationId %q, expected one of: %s", req.AttestationID, strings.Join(verification.AcceptedAttestationIDs(), ", "))
+	}
+	if err := verification.ValidateProofShape(req.Proof); err != nil {
+		return nil, badRequest("Invalid proof: %s", err)
+	}
+
+	// Convert req.Proof to self.VcAndDiscloseProof
+	proofBytes, err := json.Marshal(req.Proof)
+	if err != nil {
+		return nil, badRequest("Invalid proof format")
+	}
+	var vcProof self.VcAndDiscloseProof
+	if err := json.Unmarshal(proofBytes, &vcProof); err != nil {
+		return nil, badRequest("Invalid proof structure: %s", web.DescribeJSONError(err))
+	}
+
+	// Convert req.PublicSignals to []string; numbers are accepted as well as strings, and
+	// oversized arrays are rejected before they are fully decoded
+	publicSignals, err := verification.ParsePublicSignals(req.PublicSignals, limits.For(attestation))
+	if err != nil {
+		return nil, badRequest("Invalid public signals structure: %s", err)
+	}
+	if err := verification.ValidatePublicSignals(attestation, publicSignals); err != nil {
+		return nil, badRequest("Invalid public signals: %s", err)
+	}
+
+	// Object contexts are checked field by field and re-serialized canonically; other
+	// shapes, such as the SDK's hex-encoded context, are passed on as before
+	var userContextData, nonce string
+	fields, objectContext := req.UserContextData.(map[string]interface{})
+	if objectContext {
+		userContext, err := verification.ParseUserContext(fields)
+		if err != nil {
+			return nil, badRequest("Invalid userContextData: %s", err)
+		}
+		if userContextData, err = userContext.Canonical(); err != nil {
+			return nil, badRequest("Invalid user context data format")
+		}
+		nonce = userContext.Nonce
+	} else {
+		userContextDataBytes, err := json.Marshal(req.UserContextData)
+		if err != nil {
+			return nil, badRequest("Invalid user context data format")
+		}
+		userContextData = string(userContextDataBytes)
+	}
+
+	return &parsedVerifyRequest{
+		VerifyRequest:   req,
+		attestation:     attestation,
+		proof:           vcProof,
+		publicSignals:   publicSignals,
+		userContextData: userContextData,
+		nonce:           nonce,
+		objectContext:   objectContext,
+	}, nil
+}
+
+// recordAudit appends the outcome of a verification attempt to the user's audit log
+// Audit failures are logged but never fail the request
+func recordAudit(ctx context.Context, store *config.TenantConfigStore, maxEntries int, requestID, userID, attestationID string, result bool, errorCode string) {
+	if userID == "" {
+		return
+	}
+	entry := config.AuditEntry{
+		Timestamp:     time.Now().UTC(),
+		UserID:        userID,
+		AttestationID: attestationID,
+		Result:        result,
+		ErrorCode:     errorCode,
+		RequestID:     requestID,
+	}
+	if err := store.AppendAudit(ctx, userID, entry, maxEntries); err != nil {
+		log.Printf("[%s] Failed to record audit entry: %v", requestID, err)
+	}
+}
+
+// recordUniqueUser counts a successfully verified user towards the unique user stats, on
+// backends that keep them. Like auditing, a failure is logged but never fails the request
+func recordUniqueUser(ctx context.Context, store config.ConfigStore, requestID, userID string) {
+	counter, ok := store.(config.UniqueUserCounter)
+	if !ok || userID == "" {
+		return
+	}
+	if err := counter.RecordUniqueUser(ctx, userID, time.Now()); err != nil {
+		log.Printf("[%s] Failed to record unique user: %v", requestID, err)
+	}
+}
+
+// recordEvent hands a completed verification to the analytics sink; failures are only logged
+func recordEvent(ctx context.Context, sink verification.VerificationSink, event verification.VerificationEvent) {
+	event.Timestamp = time.Now().UTC()
+	if err := sink.Record(ctx, event); err != nil {
+		log.Printf("[%s] Failed to record verification event: %v", event.RequestID, err)
+	}
+}
+
+// verifyDeps is everything the verify handler can share across requests on one instance.
+// Building it once avoids a Redis dial and verifier construction on every call; on Vercel
+// it is built by the first request after a cold start and reused while the instance is warm
+type verifyDeps struct {
+	store   config.ConfigStore
+	params  map[string]verification.VerifierParams
+	allowed map[string]bool
+	// endpointHosts restricts request-derived endpoints, see ALLOWED_ENDPOINT_HOSTS
+	endpointHosts verification.EndpointHosts
+	verifiers     *verification.VerifierCache
+	limiter       *verification.Limiter
+	sink          verification.VerificationSink
+	settings      *settings.Config
+	// signer is nil unless ISSUE_JWT is enabled
+	signer *jwt.Signer
+	// newVerifier builds a verifier on a cache miss; tests and benchmarks swap in a fake
+	newVerifier verifierFactory
+}
+
+// verifierFactory builds the verifier for one attestation type, scope and endpoint
+type verifierFactory func(scope, endpoint string, allowedIds map[self.AttestationId]bool, store self.ConfigStore, userIDType self.UserIDType) (verification.Verifier, error)
+
+// newBackendVerifier is the verifierFactory outside tests: the SDK's verifier, on testnet
+func newBackendVerifier(scope, endpoint string, allowedIds map[self.AttestationId]bool, store self.ConfigStore, userIDType self.UserIDType) (verification.Verifier, error) {
+	v, err := self.NewBackendVerifier(scope, endpoint, true, allowedIds, store, userIDType)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// allowedCodes lists the allowed attestation codes in a stable order for error messages
+func (d *verifyDeps) allowedCodes() []string {
+	codes := make([]string, 0, len(d.allowed))
+	for code := range d.allowed {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+var (
+	// sharedDeps is only ever set to a fully built value, so the fast path needs no lock
+	sharedDeps   atomic.Pointer[verifyDeps]
+	verifyDepsMu sync.Mutex
+)
+
+// loadVerifyDeps lazily builds the shared dependencies. Unlike sync.Once, a failed build
+// isn't remembered: the next request tries again, so a Redis blip during a cold start
+// doesn't wedge the instance until it is recycled. The mutex keeps concurrent first
+// requests from dialing the store more than once
+func loadVerifyDeps() (*verifyDeps, error) {
+	if deps := sharedDeps.Load(); deps != nil {
+		return deps, nil
+	}
+
+	verifyDepsMu.Lock()
+	defer verifyDepsMu.Unlock()
+	if deps := sharedDeps.Load(); deps != nil {
+		return deps, nil
+	}
+	deps, err := buildVerifyDeps()
+	if err != nil {
+		return nil, err
+	}
+	sharedDeps.Store(deps)
+	return deps, nil
+}
+
+// buildVerifyDeps constructs the dependencies, closing the store again if a later step fails.
+// The store is never closed once shared: it pools its connections for the life of the instance
+func buildVerifyDeps() (*verifyDeps, error) {
+	cfg, err := settings.Load()
+	if err != nil {
+		return nil, err
+	}
+	// Initialize config store - equivalent to TypeScript lines 52-55
+	store, err := config.NewConfigStoreFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize config store: %w", err)
+	}
+	params, err := verification.LoadVerifierParams()
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to load verifier parameters: %w", err)
+	}
+	allowed, err := verification.AllowedAttestations()
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	endpointHosts := verification.AllowedEndpointHosts()
+	if err := verification.ValidateVerifierParams(params, allowed, endpointHosts); err != nil {
+		store.Close()
+		return nil, err
+	}
+	sink, err := verification.NewSink(cfg.VerifySink)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	signer, err := jwt.Load()
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	verification.LogVerifierEndpoints(params, endpointHosts)
+	if cfg.ExposeRawDisclosure {
+		log.Printf("WARNING: EXPOSE_RAW_DISCLOSURE is enabled; verify responses include unfiltered PII. Do not use this in production")
+	}
+	return &verifyDeps{
+		store:         store,
+		params:        params,
+		allowed:       allowed,
+		endpointHosts: endpointHosts,
+		verifiers:     verification.NewVerifierCache(),
+		limiter:       verification.NewLimiter(cfg.MaxConcurrentVerifications),
+		sink:          sink,
+		settings:      cfg,
+		signer:        signer,
+		newVerifier:   newBackendVerifier,
+	}, nil
+}
+
+// ReloadVerifiers re-reads ATTESTATION_VERIFIERS and ALLOWED_ATTESTATIONS and swaps them
+// in together with an emptied verifier cache; it is the hook for anything that changes
+// those settings at runtime, such as an admin toggle, and cmd/server calls it on SIGHUP.
+// New requests see the new settings; requests in flight finish with the dependencies they
+// started with. Invalid settings leave the current ones in place. It does nothing before
+// the first request has built the dependencies
+func ReloadVerifiers() error {
+	verifyDepsMu.Lock()
+	defer verifyDepsMu.Unlock()
+	current := sharedDeps.Load()
+	if current == nil {
+		return nil
+	}
+
+	params, err := verification.LoadVerifierParams()
+	if err != nil {
+		return fmt.Errorf("failed to load verifier parameters: %w", err)
+	}
+	allowed, err := verification.AllowedAttestations()
+	if err != nil {
+		return err
+	}
+	endpointHosts := verification.AllowedEndpointHosts()
+	if err := verification.ValidateVerifierParams(params, allowed, endpointHosts); err != nil {
+		return err
+	}
+
+	next := *current
+	next.params = params
+	next.allowed = allowed
+	next.endpointHosts = endpointHosts
+	generation := next.verifiers.Invalidate()
+	sharedDeps.Store(&next)
+	log.Printf("Reloaded verifier settings, verifier generation %d", generation)
+	verification.LogVerifierEndpoints(params, endpointHosts)
+	return nil
+}
+
+// CloseShared releases the dependencies shared across verify requests, closing the pooled
+// config store. Long-lived servers call it once every request has finished
+func CloseShared() error {
+	deps := sharedDeps.Load()
+	if deps == nil {
+		return nil
+	}
+	return deps.store.Close()
+}
+
+// PruneShared prunes stale options and audit entries from the shared config store, building
+// the shared dependencies first if no request has yet. ok is false when the backend can't prune
+func PruneShared(ctx context.Context, retention time.Duration) (result config.PruneResult, ok bool, err error) {
+	deps, err := loadVerifyDeps()
+	if err != nil {
+		return config.PruneResult{}, false, err
+	}
+	pruner, ok := deps.store.(config.Pruner)
+	if !ok {
+		return config.PruneResult{}, false, nil
+	}
+	result, err = pruner.Prune(ctx, retention)
+	return result, true, err
+}
+
+// verifyAllowedMethods is the Allow header sent with 405 responses; web.CORS answers OPTIONS
+const verifyAllowedMethods = "POST, OPTIONS"
+
 // Handler is the equivalent of the TypeScript handler function (lines 37-55)
 func Handler(w http.ResponseWriter, r *http.Request) {
-	if r.Method == http.MethodPost {
+	web.Recover(web.Trace(web.CORS(web.Deadline(web.Casing(web.DecompressRequest(web.Gzip(http.HandlerFunc(handleVerify)))))))).ServeHTTP(w, r)
+}
 
-		var req VerifyRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid JSON", http.StatusBadRequest)
-			return
-		}
+func handleVerify(w http.ResponseWriter, r *http.Request) {
+	// Set up front so the plain-text errors written with http.Error aren't cached either
+	web.NoStore(w)
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", verifyAllowedMethods)
+		web.WriteJSON(w, r, http.StatusMethodNotAllowed, map[string]string{"message": "Method not allowed"})
+		return
+	}
 
-		// Validate required fields - equivalent to TypeScript validation
-		if req.Proof == nil || req.PublicSignals == nil || req.AttestationID == "" || req.UserContextData == nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "Proof, publicSignals, attestationId and userContextData are required",
-			})
-			return
+	var timing VerifyTiming
+	decodeStarted := time.Now()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "Invalid JSON: " + web.DescribeJSONError(err)})
+		return
+	}
+	cfg, err := settings.Load()
+	if err != nil {
+		log.Printf("Failed to load settings: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	parsed, err := parseVerifyRequest(bytes.NewReader(body), cfg.MaxPublicSignals)
+	timing.DecodeMs = time.Since(decodeStarted).Milliseconds()
+	if err != nil {
+		status := http.StatusBadRequest
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			status = reqErr.status
 		}
+		web.WriteJSON(w, r, status, map[string]string{"message": err.Error()})
+		return
+	}
+	req := parsed.VerifyRequest
+	attestation := parsed.attestation
+	reportProgress(r.Context(), progressReceived)
+
+	deps, err := loadVerifyDeps()
+	if err != nil {
+		log.Printf("Failed to initialize verify dependencies: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
 
-		// Convert req.Proof to self.VcAndDiscloseProof
-		proofBytes, err := json.Marshal(req.Proof)
-		if err != nil {
-			log.Printf("Failed to marshal proof: %v", err)
-			http.Error(w, "Invalid proof format", http.StatusBadRequest)
-			return
-		}
+	if !deps.allowed[attestation.Code] {
+		web.WriteJSON(w, r, http.StatusBadRequest, VerifyResponse{
+			Status:    "error",
+			Result:    false,
+			Message:   fmt.Sprintf("attestationId %q is not allowed, allowed types: %s", req.AttestationID, strings.Join(deps.allowedCodes(), ", ")),
+			ErrorCode: verification.ErrorCodeAttestationNotAllowed,
+		})
+		return
+	}
 
-		var vcProof self.VcAndDiscloseProof
-		if err := json.Unmarshal(proofBytes, &vcProof); err != nil {
-			log.Printf("Failed to unmarshal proof to VcAndDiscloseProof: %v", err)
-			http.Error(w, "Invalid proof structure", http.StatusBadRequest)
-			return
+	// Without its field list the filter can't tell what to withhold, so refuse rather than
+	// return a subject that may carry fields the user never agreed to share
+	if !attestation.Supported() {
+		web.WriteJSON(w, r, http.StatusNotImplemented, VerifyResponse{
+			Status:    "error",
+			Result:    false,
+			Message:   fmt.Sprintf("attestationId %q has no disclosure filter yet", req.AttestationID),
+			ErrorCode: verification.ErrorCodeAttestationUnsupported,
+		})
+		return
+	}
+
+	// The SDK's hex-encoded contexts have no nonce field, so only object contexts can lack one
+	if deps.settings.RequireNonce && parsed.objectContext && parsed.nonce == "" {
+		web.WriteJSON(w, r, http.StatusBadRequest, VerifyResponse{
+			Status:    "error",
+			Result:    false,
+			Message:   "userContextData must carry a nonce",
+			ErrorCode: verification.ErrorCodeNonceRequired,
+		})
+		return
+	}
+
+	// Reject replays of old contexts before spending a verification on them
+	if err := verification.CheckContextTimestamp(req.UserContextData, time.Now(), deps.settings.MaxClockSkew); err != nil {
+		resp := VerifyResponse{Status: "error", Result: false, Message: err.Error()}
+		if errors.Is(err, verification.ErrStaleContext) {
+			resp.ErrorCode = verification.ErrorCodeStaleContext
 		}
+		web.WriteJSON(w, r, http.StatusBadRequest, resp)
+		return
+	}
 
-		// Convert req.PublicSignals to []string
-		publicSignalsBytes, err := json.Marshal(req.PublicSignals)
-		if err != nil {
-			log.Printf("Failed to marshal public signals: %v", err)
-			http.Error(w, "Invalid public signals format", http.StatusBadRequest)
+	// Catch clients sending identifiers in the wrong format before attempting verification
+	if req.UserID != "" {
+		if err := config.ValidateUserID(req.UserID, deps.settings.UserIDType); err != nil {
+			web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": err.Error()})
 			return
 		}
-
-		var publicSignals []string
-		if err := json.Unmarshal(publicSignalsBytes, &publicSignals); err != nil {
-			log.Printf("Failed to unmarshal public signals to []string: %v", err)
-			http.Error(w, "Invalid public signals structure", http.StatusBadRequest)
+		if !checkUserAccess(w, r, deps.store, req.UserID) {
 			return
 		}
+	}
 
-		// Convert req.UserContextData to string
-		userContextDataBytes, err := json.Marshal(req.UserContextData)
-		if err != nil {
-			log.Printf("Failed to marshal user context data: %v", err)
-			http.Error(w, "Invalid user context data format", http.StatusBadRequest)
+	// Only trusted callers may replace the rules, otherwise clients could weaken their own checks
+	if req.ConfigOverride != nil {
+		if !web.VerifySignature(body, r.Header.Get(web.SignatureHeader), deps.settings.ConfigOverrideSecret) {
+			web.WriteJSON(w, r, http.StatusUnauthorized, map[string]string{"message": "configOverride requires a valid signature"})
 			return
 		}
-		userContextDataStr := string(userContextDataBytes)
-
-		// Initialize config store - equivalent to TypeScript lines 52-55
-		configStore, err := config.NewKVConfigStoreFromEnv()
-		if err != nil {
-			log.Printf("Failed to initialize config store: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		req.ConfigOverride.ExcludedCountries = config.DedupeCountries(req.ConfigOverride.ExcludedCountries)
+		if errs := config.ValidateVerificationConfig(*req.ConfigOverride); len(errs) > 0 {
+			web.WriteJSON(w, r, http.StatusUnprocessableEntity, map[string]interface{}{"message": "Invalid configOverride", "errors": errs})
 			return
 		}
+	}
 
-		// Define allowed attestation types
-		allowedIds := map[self.AttestationId]bool{
-			self.Passport: true,
-			self.EUCard:   true,
-		}
+	// Scope the store to the requesting tenant, falling back to the query parameter
+	tenantID := req.TenantID
+	if tenantID == "" {
+		tenantID = r.URL.Query().Get("tenantId")
+	}
+	configStore, err := config.NewTenantConfigStore(deps.store, tenantID)
+	if err != nil {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	// Pick the verifier parameters configured for this attestation type
+	params, ok := deps.params[attestation.Code]
+	if !ok {
+		web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{
+			"message": fmt.Sprintf("No verifier is configured for attestationId %q", attestation.Code),
+		})
+		return
+	}
+
+	verifyEndpoint := params.Endpoint
+	if verifyEndpoint == "" {
 		// Get the host from the request to match the QR code endpoint
 		scheme := "https"
 		if r.Header.Get("X-Forwarded-Proto") != "" {
 			scheme = r.Header.Get("X-Forwarded-Proto")
 		}
 		host := r.Host
-		verifyEndpoint := fmt.Sprintf("%s://%s/api/go-verify", scheme, host)
+		verifyEndpoint = fmt.Sprintf("%s://%s/api/go-verify", scheme, host)
+		// Configured endpoints were checked at startup; a derived one depends on the Host header
+		if !deps.endpointHosts.Allows(verifyEndpoint) {
+			log.Printf("[%s] Rejecting request for endpoint %s, host not on ALLOWED_ENDPOINT_HOSTS", web.RequestID(r), verifyEndpoint)
+			web.WriteJSON(w, r, http.StatusMisdirectedRequest, map[string]string{"message": "This host is not an allowed verification endpoint"})
+			return
+		}
+	}
 
-		verifier, err := self.NewBackendVerifier(
-			"self-playground-go",
+	cacheKey := strings.Join([]string{tenantID, attestation.Code, params.Scope, verifyEndpoint}, "|")
+	verifier, err := deps.verifiers.Get(cacheKey, func() (verification.Verifier, error) {
+		// Each verifier only accepts the attestation type it was configured for
+		allowedIds := map[self.AttestationId]bool{
+			attestation.ID: true,
+		}
+		return deps.newVerifier(
+			params.Scope,
 			verifyEndpoint,
-			true, // Use testnet
 			allowedIds,
-			configStore,
-			self.UserIDTypeUUID, // Use UUID format for user IDs
+			config.ResolvedConfigStore{VerificationConfigStore: configStore, Policy: deps.settings.Policy()},
+			deps.settings.UserIDType,
 		)
-		if err != nil {
-			log.Printf("Failed to initialize verifier: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return
-		}
+	})
+	if err != nil {
+		log.Printf("Failed to initialize verifier: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
 
-		ctx := context.Background()
+	reportProgress(r.Context(), progressConfigLoaded)
 
-		result, err := verifier.Verify(
-			ctx,
-			req.AttestationID,
-			vcProof,
-			publicSignals,
-			userContextDataStr,
-		)
+	// Config lookups for this request, including the SDK's own, prefer attestation-specific configs
+	ctx := config.WithAttestation(r.Context(), attestation.Code)
+	if req.ConfigOverride != nil {
+		ctx = config.WithConfigOverride(ctx, *req.ConfigOverride)
+	}
+	requestID := web.RequestID(r)
+
+	// Repeat verifications of an identical proof are served from the cache, but only while
+	// the user's resolved config is the one the cached result was decided under
+	var resultCache *verification.ResultCache
+	var resultKey string
+	if ttl := deps.settings.VerifyResultCacheTTL; ttl > 0 {
+		if key, err := verification.ResultCacheKey(attestation.Code, parsed.proof, parsed.publicSignals, parsed.userContextData); err == nil {
+			resultCache = verification.NewResultCache(configStore, ttl)
+			resultKey = key
+		}
+	}
+	fingerprint := func(ctx context.Context, userID string) (string, error) {
+		cfg, err := config.ResolvedConfigStore{VerificationConfigStore: configStore, Policy: deps.settings.Policy()}.GetConfig(ctx, userID)
 		if err != nil {
-			log.Printf("Verification failed: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(VerifyResponse{
-				Status:  "error",
-				Result:  false,
-				Message: "Verification failed",
-			})
-			return
+			return "", err
 		}
+		return verification.ConfigFingerprint(cfg)
+	}
 
-		if result == nil || !result.IsValidDetails.IsValid {
-			log.Printf("Verification failed - invalid result")
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(VerifyResponse{
+	started := time.Now()
+	result, cached := resultCache.Get(ctx, resultKey, fingerprint)
+	if !cached {
+		// Bound concurrent verifications so a burst can't starve the whole instance of CPU
+		if !deps.limiter.Acquire(ctx) {
+			if web.DeadlineExceeded(r) {
+				web.WriteDeadlineExceeded(w, r)
+				return
+			}
+			w.Header().Set("Retry-After", "1")
+			web.WriteJSON(w, r, http.StatusServiceUnavailable, VerifyResponse{
 				Status:  "error",
 				Result:  false,
-				Message: "Verification failed",
+				Message: "Too many verifications in progress, please retry",
 			})
 			return
 		}
 
-		// Get config from configStore - equivalent to TypeScript: configStore.getConfig(result.userData.userIdentifier)
-		configResult, err := configStore.GetConfig(ctx, result.UserData.UserIdentifier)
+		reportProgress(ctx, progressVerifying)
+
+		// Retry transient RPC failures; invalid proofs fail on the first attempt
+		result, err = verification.WithRetry(ctx, requestID, func(ctx context.Context) (*self.VerificationResult, error) {
+			return verifier.Verify(
+				ctx,
+				attestation.Code,
+				parsed.proof,
+				parsed.publicSignals,
+				parsed.userContextData,
+			)
+		})
+		deps.limiter.Release()
+		if err != nil && web.DeadlineExceeded(r) {
+			log.Printf("[%s] Verification ran out of time: %v", requestID, err)
+			web.WriteDeadlineExceeded(w, r)
+			return
+		}
 		if err != nil {
-			log.Printf("Failed to get config: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			log.Printf("[%s] Verification failed: %v", requestID, err)
+			recordAudit(ctx, configStore, deps.settings.AuditLogMaxEntries, requestID, req.UserID, attestation.Code, false, verification.ErrorCodeVerificationFailed)
+			web.WriteJSON(w, r, http.StatusInternalServerError, VerifyResponse{
+				Status:    "error",
+				Result:    false,
+				Message:   "Verification failed",
+				ErrorCode: verification.ErrorCodeVerificationFailed,
+			})
 			return
 		}
+		resultCache.Put(ctx, resultKey, result, fingerprint)
+	}
 
-		// Type cast to SelfAppDisclosureConfig - equivalent to TypeScript: as unknown as SelfAppDisclosureConfig
-		saveOptions := interface{}(configResult).(config.SelfAppDisclosureConfig)
-
-		// Check if verification is valid - equivalent to TypeScript: if (result.isValidDetails.isValid)
-		if result.IsValidDetails.IsValid {
-			// Create filtered subject - equivalent to TypeScript: const filteredSubject = { ...result.discloseOutput };
-			// Copy the struct to modify it
-			filteredSubject := result.DiscloseOutput
+	if result == nil || !result.IsValidDetails.IsValid {
+		log.Printf("Verification failed - invalid result")
+		userID := req.UserID
+		if result != nil && result.UserData.UserIdentifier != "" {
+			userID = result.UserData.UserIdentifier
+		}
+		recordAudit(ctx, configStore, deps.settings.AuditLogMaxEntries, requestID, userID, attestation.Code, false, verification.ErrorCodeInvalidProof)
+		recordEvent(ctx, deps.sink, verification.VerificationEvent{
+			RequestID:     requestID,
+			TenantID:      tenantID,
+			AttestationID: attestation.Code,
+			ErrorCode:     verification.ErrorCodeInvalidProof,
+			DurationMs:    time.Since(started).Milliseconds(),
+		})
+		web.WriteJSON(w, r, http.StatusInternalServerError, VerifyResponse{
+			Status:    "error",
+			Result:    false,
+			Message:   "Verification failed",
+			ErrorCode: verification.ErrorCodeInvalidProof,
+		})
+		return
+	}
+	// The identifier the proof commits to is the one that counts; the request's userId is only a hint
+	if !checkUserAccess(w, r, deps.store, result.UserData.UserIdentifier) {
+		return
+	}
+	recordAudit(ctx, configStore, deps.settings.AuditLogMaxEntries, requestID, result.UserData.UserIdentifier, attestation.Code, true, "")
+	recordUniqueUser(ctx, deps.store, requestID, result.UserData.UserIdentifier)
+	timing.VerificationMs = time.Since(started).Milliseconds()
+	lookupStarted := time.Now()
+
+	// Get the saved options - equivalent to TypeScript: configStore.getConfig(result.userData.userIdentifier)
+	// as unknown as SelfAppDisclosureConfig. Go can't reinterpret the config, so the options are decoded
+	// with their disclosure flags and resolved the same way as the config the verifier enforced
+	saveOptions, err := configStore.GetDisclosureConfig(ctx, result.UserData.UserIdentifier)
+	if err != nil {
+		log.Printf("Failed to get config: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	// Options saved too long ago may predate a policy change; use the defaults instead, or
+	// refuse, as the deployment chooses. The SDK has already enforced them by this point
+	var optionsExpired bool
+	if saveOptions.OlderThan(deps.settings.OptionsMaxAge, time.Now()) {
+		if deps.settings.StaleOptionsPolicy == settings.StaleOptionsReject {
+			web.WriteJSON(w, r, http.StatusConflict, VerifyResponse{
+				Status:    "error",
+				Result:    false,
+				Message:   "Saved options are older than OPTIONS_MAX_AGE, save them again",
+				ErrorCode: verification.ErrorCodeOptionsExpired,
+			})
+			return
+		}
+		log.Printf("[%s] Saved options from %s are older than %s, using the defaults", requestID, saveOptions.SavedAt.Format(time.RFC3339), deps.settings.OptionsMaxAge)
+		saveOptions = config.DefaultDisclosureConfig()
+		optionsExpired = true
+	}
+	if req.ConfigOverride != nil {
+		saveOptions = config.ApplyConfigOverride(saveOptions, *req.ConfigOverride)
+	}
+	saveOptions = deps.settings.Policy().ResolveDisclosureConfig(saveOptions)
+
+	// Report which config applied, so "which rules ran" is answerable from the response alone
+	configVersion := configOverrideVersion
+	if req.ConfigOverride == nil {
+		version, found, err := configStore.ConfigVersion(ctx, result.UserData.UserIdentifier)
+		switch {
+		case err != nil:
+			// Only diagnostic, so it doesn't fail an otherwise successful verification
+			log.Printf("[%s] Failed to get config version: %v", requestID, err)
+			configVersion = ""
+		case found:
+			configVersion = strconv.FormatInt(version, 10)
+		default:
+			configVersion = config.DefaultConfigVersion
+		}
+	}
+	if configVersion != "" {
+		w.Header().Set(configVersionHeader, configVersion)
+	}
+	timing.ConfigLookupMs = time.Since(lookupStarted).Milliseconds()
+	filteringStarted := time.Now()
+
+	// Check if verification is valid - equivalent to TypeScript: if (result.isValidDetails.isValid)
+	if result.IsValidDetails.IsValid {
+		// Create filtered subject - equivalent to TypeScript: const filteredSubject = { ...result.discloseOutput };
+		// Copy the struct to modify it
+		filteredSubject := result.DiscloseOutput
+
+		// With age-gated disclosure, a failed age check withholds every field whatever its flag says
+		ageGateFailed := saveOptions.AgeGatedDisclosure != nil && *saveOptions.AgeGatedDisclosure &&
+			saveOptions.MinimumAge != nil && !result.IsValidDetails.IsMinimumAgeValid
+		disclosed := func(flag *bool) bool {
+			return !ageGateFailed && flag != nil && *flag
+		}
 
-			// Apply disclosure filters based on saveOptions - EXACT equivalent to TypeScript conditions
+		// A valid proof with no disclosed data at all, when the options ask for some, points at
+		// an SDK or circuit problem; report it rather than answering with blank fields
+		requested := false
+		for _, field := range attestation.Fields {
+			requested = requested || disclosed(field.Enabled(saveOptions))
+		}
+		if requested && verification.DisclosureEmpty(result.DiscloseOutput) {
+			log.Printf("[%s] Valid verification returned an empty disclosure: %s", requestID, web.Redact(result))
+			web.WriteJSON(w, r, http.StatusBadGateway, VerifyResponse{
+				Status:    "error",
+				Result:    false,
+				Message:   "Verification returned no disclosed data",
+				ErrorCode: verification.ErrorCodeEmptyDisclosure,
+			})
+			return
+		}
 
-			// TypeScript: if (!saveOptions.issuing_state && filteredSubject)
-			if saveOptions.IssuingState == nil || !*saveOptions.IssuingState {
-				filteredSubject.IssuingState = "Not disclosed"
+		// Fields the deployment requires must be in the proof, whatever the user chose to share
+		var missing []string
+		for _, flag := range deps.settings.RequiredDisclosures {
+			for _, field := range attestation.Fields {
+				if field.Flag == flag && !verification.Disclosed(result.DiscloseOutput, field) {
+					missing = append(missing, flag)
+				}
 			}
+		}
+		if len(missing) > 0 {
+			log.Printf("[%s] Proof is missing required disclosures: %s", requestID, strings.Join(missing, ", "))
+			web.WriteJSON(w, r, http.StatusUnprocessableEntity, VerifyResponse{
+				Status:    "error",
+				Result:    false,
+				Message:   "Required fields were not disclosed: " + strings.Join(missing, ", "),
+				ErrorCode: verification.ErrorCodeRequiredDisclosureMissing,
+			})
+			return
+		}
 
-			// TypeScript: if (!saveOptions.name && filteredSubject)
-			if saveOptions.Name == nil || !*saveOptions.Name {
-				filteredSubject.Name = "Not disclosed"
+		// Apply disclosure filters based on saveOptions - equivalent to the TypeScript
+		// if (!saveOptions.<flag> && filteredSubject) conditions, one per disclosable field.
+		// The same decision fills the disclosure map, so the two can't disagree. A disclosed
+		// value that fails its format check is withheld with a warning instead of failing
+		// the whole verification
+		var warnings []string
+		if optionsExpired {
+			warnings = append(warnings, "Saved options are older than OPTIONS_MAX_AGE, the defaults were applied")
+		}
+		disclosure := make(map[string]bool, len(attestation.Fields))
+		for _, field := range attestation.Fields {
+			disclosure[field.Flag] = disclosed(field.Enabled(saveOptions))
+			if disclosure[field.Flag] {
+				// The value itself is PII, so only the field name is logged
+				if err := verification.CheckDisclosed(filteredSubject, field); err != nil {
+					log.Printf("[%s] Withholding malformed %s", requestID, field.Flag)
+					warnings = append(warnings, fmt.Sprintf("%s was withheld because its value is malformed", field.Flag))
+					disclosure[field.Flag] = false
+				}
 			}
-
-			// TypeScript: if (!saveOptions.nationality && filteredSubject)
-			if saveOptions.Nationality == nil || !*saveOptions.Nationality {
-				filteredSubject.Nationality = "Not disclosed"
+			if !disclosure[field.Flag] {
+				verification.Withhold(&filteredSubject, field)
 			}
+		}
 
-			// TypeScript: if (!saveOptions.date_of_birth && filteredSubject)
-			if saveOptions.DateOfBirth == nil || !*saveOptions.DateOfBirth {
-				filteredSubject.DateOfBirth = "Not disclosed"
+		if disclosure["nationality"] {
+			var warning string
+			filteredSubject.Nationality, warning = verification.FormatNationality(filteredSubject.Nationality, saveOptions.NationalityFormat)
+			if warning != "" {
+				warnings = append(warnings, warning)
 			}
-
-			// TypeScript: if (!saveOptions.passport_number && filteredSubject)
-			if saveOptions.PassportNumber == nil || !*saveOptions.PassportNumber {
-				filteredSubject.IdNumber = "Not disclosed"
+		}
+		var age *int
+		if disclosure["date_of_birth"] {
+			var warning string
+			filteredSubject.DateOfBirth, age, warning = verification.FormatDateOfBirth(filteredSubject.DateOfBirth, saveOptions.DateOfBirthFormat, time.Now())
+			// Only the age leaves the service in this format, not the date itself
+			if saveOptions.DateOfBirthFormat == verification.DateOfBirthFormatAge {
+				disclosure["date_of_birth"] = false
 			}
-
-			// TypeScript: if (!saveOptions.gender && filteredSubject)
-			if saveOptions.Gender == nil || !*saveOptions.Gender {
-				filteredSubject.Gender = "Not disclosed"
+			if warning != "" {
+				log.Printf("[%s] %s", requestID, warning)
+				warnings = append(warnings, warning)
 			}
+		}
 
-			// TypeScript: if (!saveOptions.expiry_date && filteredSubject)
-			if saveOptions.ExpiryDate == nil || !*saveOptions.ExpiryDate {
-				filteredSubject.ExpiryDate = "Not disclosed"
-			}
+		// Clients that prefer absent fields over the sentinel ask per request or in their options;
+		// deployments can swap the sentinel itself with UNDISCLOSED_PLACEHOLDER
+		omit, _ := strconv.ParseBool(r.URL.Query().Get("omitUndisclosed"))
+		omit = omit || (saveOptions.OmitUndisclosed != nil && *saveOptions.OmitUndisclosed)
+		subject := &CredentialSubject{
+			GenericDiscloseOutput: filteredSubject,
+			fields:                attestation.Fields,
+			disclosure:            disclosure,
+			placeholder:           verification.ParsePlaceholder(deps.settings.UndisclosedPlaceholder),
+			omit:                  omit,
+		}
 
-			// Create excluded countries array with country code mapping (like TypeScript)
-			var excludedCountriesForResponse []string
-			if saveOptions.ExcludedCountries != nil {
-				excludedCountriesForResponse = make([]string, len(saveOptions.ExcludedCountries))
-				for i, countryCode := range saveOptions.ExcludedCountries {
-					excludedCountriesForResponse[i] = string(countryCode)
-				}
+		// Create excluded countries array with country code mapping (like TypeScript),
+		// as codes unless the options ask for names
+		excludedCountriesForResponse, warning := verification.FormatExcludedCountries(saveOptions.ExcludedCountries, saveOptions.ExcludedCountriesFormat)
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+
+		recordEvent(ctx, deps.sink, verification.VerificationEvent{
+			RequestID:         requestID,
+			TenantID:          tenantID,
+			AttestationID:     attestation.Code,
+			Result:            true,
+			MinimumAge:        saveOptions.MinimumAge,
+			Ofac:              saveOptions.Ofac,
+			ExcludedCountries: saveOptions.ExcludedCountries,
+			DurationMs:        time.Since(started).Milliseconds(),
+		})
+
+		// Only debug deployments may echo the unfiltered disclosure back
+		var rawDiscloseOutput interface{}
+		if deps.settings.ExposeRawDisclosure {
+			rawDiscloseOutput = result.DiscloseOutput
+		}
+
+		verificationOptions := &VerificationOptions{
+			ExcludedCountries: excludedCountriesForResponse,
+			MinimumAge:        saveOptions.MinimumAge,
+			Ofac:              saveOptions.Ofac,
+		}
+
+		// The token carries the same filtered claims as the response, never the raw disclosure
+		var token string
+		if deps.signer != nil {
+			now := time.Now()
+			claims := map[string]interface{}{
+				"iss":                 params.Scope,
+				"sub":                 result.UserData.UserIdentifier,
+				"iat":                 now.Unix(),
+				"exp":                 now.Add(jwt.TokenTTL).Unix(),
+				"jti":                 requestID,
+				"attestationId":       attestation.Code,
+				"result":              result.IsValidDetails.IsValid,
+				"credentialSubject":   subject,
+				"verificationOptions": verificationOptions,
+			}
+			if age != nil {
+				claims["age"] = *age
 			}
+			token, err = deps.signer.Sign(claims)
+			if err != nil {
+				log.Printf("Failed to sign verification token: %v", err)
+				web.WriteJSON(w, r, http.StatusInternalServerError, VerifyResponse{
+					Status:  "error",
+					Result:  false,
+					Message: "Failed to sign verification token",
+				})
+				return
+			}
+		}
 
-			// Return successful verification result with filtered data
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(VerifyResponse{
-				Status:            "success",
-				Result:            result.IsValidDetails.IsValid,
-				CredentialSubject: filteredSubject,
-				VerificationOptions: map[string]interface{}{
-					"minimumAge":        saveOptions.MinimumAge,
-					"ofac":              saveOptions.Ofac,
-					"excludedCountries": excludedCountriesForResponse,
-				},
-			})
-		} else {
-			// Handle failed verification case - equivalent to TypeScript lines 127-134
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(VerifyResponse{
-				Status:  "error",
-				Result:  result.IsValidDetails.IsValid,
-				Message: "Verification failed",
-			})
+		// Timing is a debugging aid, so production responses never carry it
+		var debugTiming *VerifyTiming
+		if want, _ := strconv.ParseBool(r.Header.Get(debugTimingHeader)); want && deps.settings.EnableDebugEndpoints {
+			timing.FilteringMs = time.Since(filteringStarted).Milliseconds()
+			debugTiming = &timing
 		}
+
+		// Return successful verification result with filtered data
+		web.WriteJSON(w, r, http.StatusOK, VerifyResponse{
+			Status:              "success",
+			Result:              result.IsValidDetails.IsValid,
+			CredentialSubject:   subject,
+			VerificationOptions: verificationOptions,
+			Warnings:            warnings,
+			RawDiscloseOutput:   rawDiscloseOutput,
+			Disclosure:          disclosure,
+			Age:                 age,
+			Token:               token,
+			ConfigVersion:       configVersion,
+			Timing:              debugTiming,
+			Nonce:               parsed.nonce,
+		})
+	} else {
+		// Handle failed verification case - equivalent to TypeScript lines 127-134
+		web.WriteJSON(w, r, http.StatusBadRequest, VerifyResponse{
+			Status:  "error",
+			Result:  result.IsValidDetails.IsValid,
+			Message: "Verification failed",
+		})
 	}
 }
@@ -0,0 +1,786 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"playground/config"
+	"playground/settings"
+	"playground/verification"
+	"playground/web"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+)
+
+const (
+	testAdminToken        = "test-admin-token"
+	testSaveOptionsSecret = "test-save-options-secret"
+	testUserID            = "11111111-1111-1111-1111-111111111111"
+)
+
+func TestMain(m *testing.M) {
+	// Settings are loaded once per process, so the secrets have to be in place before any test runs
+	os.Setenv("ADMIN_API_TOKEN", testAdminToken)
+	os.Setenv("SAVE_OPTIONS_SECRET", testSaveOptionsSecret)
+	os.Exit(m.Run())
+}
+
+// mockVerifier stands in for the SDK verifier, answering every proof with result and err
+type mockVerifier struct {
+	result *self.VerificationResult
+	err    error
+	calls  atomic.Int64
+}
+
+func (m *mockVerifier) Verify(ctx context.Context, attestationId string, proof self.VcAndDiscloseProof, pubSignals []string, userContextData string) (*self.VerificationResult, error) {
+	m.calls.Add(1)
+	return m.result, m.err
+}
+
+// validResult is a successful verification of testUserID disclosing every field
+func validResult() *self.VerificationResult {
+	return &self.VerificationResult{
+		AttestationId:  self.Passport,
+		IsValidDetails: self.IsValidDetails{IsValid: true, IsMinimumAgeValid: true, IsOfacValid: true},
+		DiscloseOutput: self.GenericDiscloseOutput{
+			IssuingState: "GBR",
+			Name:         "JOHN DOE",
+			IdNumber:     "123456789",
+			Nationality:  "GBR",
+			DateOfBirth:  "1990-01-02",
+			Gender:       "M",
+			ExpiryDate:   "2030-01-01",
+		},
+		UserData: self.UserData{UserIdentifier: testUserID},
+	}
+}
+
+// useTestDeps shares dependencies backed by a memory store and verifier for the rest of the
+// test. Their settings are a copy, so a test may change them before sending requests
+func useTestDeps(tb testing.TB, verifier verification.Verifier) *verifyDeps {
+	tb.Helper()
+	cfg, err := settings.Load()
+	if err != nil {
+		tb.Fatalf("settings.Load: %v", err)
+	}
+	params, err := verification.LoadVerifierParams()
+	if err != nil {
+		tb.Fatalf("LoadVerifierParams: %v", err)
+	}
+	allowed, err := verification.AllowedAttestations()
+	if err != nil {
+		tb.Fatalf("AllowedAttestations: %v", err)
+	}
+	own := *cfg
+	deps := &verifyDeps{
+		store:     config.NewMemoryConfigStore(),
+		params:    params,
+		allowed:   allowed,
+		verifiers: verification.NewVerifierCache(),
+		limiter:   verification.NewLimiter(own.MaxConcurrentVerifications),
+		sink:      verification.NoopSink{},
+		settings:  &own,
+		newVerifier: func(scope, endpoint string, allowedIds map[self.AttestationId]bool, store self.ConfigStore, userIDType self.UserIDType) (verification.Verifier, error) {
+			return verifier, nil
+		},
+	}
+	previous := sharedDeps.Swap(deps)
+	tb.Cleanup(func() { sharedDeps.Store(previous) })
+	return deps
+}
+
+// discardLogs silences the standard logger until tb ends, so benchmarks don't time log output
+func discardLogs(tb testing.TB) {
+	log.SetOutput(io.Discard)
+	tb.Cleanup(func() { log.SetOutput(os.Stderr) })
+}
+
+// numberedSignals returns n decimal public signals of the given width in digits
+func numberedSignals(n, digits int) []string {
+	signals := make([]string, n)
+	for i := range signals {
+		signals[i] = strings.Repeat("9", digits-1) + string(rune('0'+i%10))
+	}
+	return signals
+}
+
+// verifyBody is a well-formed passport verify request for testUserID carrying signals
+func verifyBody(tb testing.TB, signals []string) string {
+	tb.Helper()
+	body, err := json.Marshal(map[string]interface{}{
+		"attestationId": "1",
+		"proof": map[string]interface{}{
+			"a": []string{"1", "2"},
+			"b": [][]string{{"3", "4"}, {"5", "6"}},
+			"c": []string{"7", "8"},
+		},
+		"publicSignals": signals,
+		"userContextData": map[string]interface{}{
+			"userIdentifier": testUserID,
+			"nonce":          "test-nonce",
+			"timestamp":      time.Now().UTC().Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		tb.Fatalf("json.Marshal: %v", err)
+	}
+	return string(body)
+}
+
+// postVerify sends body through the full verify middleware chain
+func postVerify(body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	Handler(rec, httptest.NewRequest(http.MethodPost, "https://example.com/api/go-verify", strings.NewReader(body)))
+	return rec
+}
+
+// decodeVerifyResponse decodes rec's body, keeping credentialSubject as a plain map
+func decodeVerifyResponse(tb testing.TB, rec *httptest.ResponseRecorder) map[string]interface{} {
+	tb.Helper()
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		tb.Fatalf("response %q is not JSON: %v", rec.Body, err)
+	}
+	return resp
+}
+
+func TestVerifyWithMockVerifier(t *testing.T) {
+	verifier := &mockVerifier{result: validResult()}
+	useTestDeps(t, verifier)
+
+	rec := postVerify(verifyBody(t, numberedSignals(21, 2)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	resp := decodeVerifyResponse(t, rec)
+	if resp["status"] != "success" || resp["result"] != true {
+		t.Errorf("response = %v, want a successful result", resp)
+	}
+	if verifier.calls.Load() != 1 {
+		t.Errorf("verifier called %d times, want once", verifier.calls.Load())
+	}
+}
+
+func TestReloadVerifiers(t *testing.T) {
+	var built []*mockVerifier
+	deps := useTestDeps(t, nil)
+	deps.newVerifier = func(scope, endpoint string, allowedIds map[self.AttestationId]bool, store self.ConfigStore, userIDType self.UserIDType) (verification.Verifier, error) {
+		v := &mockVerifier{result: validResult()}
+		built = append(built, v)
+		return v, nil
+	}
+	body := verifyBody(t, numberedSignals(21, 2))
+
+	for i := 0; i < 2; i++ {
+		if rec := postVerify(body); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+		}
+	}
+	if len(built) != 1 || built[0].calls.Load() != 2 {
+		t.Fatalf("built %d verifiers before the reload, want one serving both requests", len(built))
+	}
+
+	t.Setenv("ATTESTATION_VERIFIERS", `{"1":{"scope":"reloaded-scope"}}`)
+	t.Setenv("ALLOWED_ATTESTATIONS", "1")
+	if err := ReloadVerifiers(); err != nil {
+		t.Fatalf("ReloadVerifiers: %v", err)
+	}
+	reloaded := sharedDeps.Load()
+	if got := reloaded.verifiers.Generation(); got != 1 {
+		t.Errorf("generation after reload = %d, want 1", got)
+	}
+	if reloaded.params["1"].Scope != "reloaded-scope" || reloaded.allowed["2"] {
+		t.Errorf("reloaded params %v, allowed %v, want the new settings", reloaded.params, reloaded.allowed)
+	}
+	// Requests that started before the reload keep the dependencies they loaded
+	if deps.params["1"].Scope != verification.DefaultScope || !deps.allowed["2"] {
+		t.Errorf("previous dependencies changed to params %v, allowed %v", deps.params, deps.allowed)
+	}
+
+	if rec := postVerify(body); rec.Code != http.StatusOK {
+		t.Fatalf("status after reload = %d, body %s", rec.Code, rec.Body)
+	}
+	if len(built) != 2 || built[1].calls.Load() != 1 || built[0].calls.Load() != 2 {
+		t.Errorf("after the reload: %d verifiers built, want a second one serving the new request", len(built))
+	}
+
+	// Invalid settings leave the current ones, and the cached verifier, in place
+	t.Setenv("ALLOWED_ATTESTATIONS", "unknown")
+	if err := ReloadVerifiers(); err == nil {
+		t.Fatal("ReloadVerifiers accepted an unknown attestation")
+	}
+	if sharedDeps.Load() != reloaded || reloaded.verifiers.Generation() != 1 {
+		t.Error("a failed reload replaced the dependencies")
+	}
+}
+
+// storeOptions saves optionsJSON for testUserID in the store deps verify against
+func storeOptions(tb testing.TB, deps *verifyDeps, optionsJSON string) {
+	tb.Helper()
+	if _, err := deps.store.SaveOptions(context.Background(), testUserID, optionsJSON, time.Hour, false); err != nil {
+		tb.Fatalf("SaveOptions: %v", err)
+	}
+}
+
+func TestAgeGatedDisclosure(t *testing.T) {
+	tests := []struct {
+		name       string
+		gated      bool
+		ageValid   bool
+		wantShared bool
+	}{
+		{"gate off, age passes", false, true, true},
+		{"gate off, age fails", false, false, true},
+		{"gate on, age passes", true, true, true},
+		{"gate on, age fails", true, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validResult()
+			result.IsValidDetails.IsMinimumAgeValid = tt.ageValid
+			deps := useTestDeps(t, &mockVerifier{result: result})
+			storeOptions(t, deps, `{"minimumAge":18,"name":true,"nationality":true,"age_gated_disclosure":`+strconv.FormatBool(tt.gated)+`}`)
+
+			rec := postVerify(verifyBody(t, numberedSignals(21, 2)))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			resp := decodeVerifyResponse(t, rec)
+			subject, _ := resp["credentialSubject"].(map[string]interface{})
+			wantName, wantNationality := verification.NotDisclosed, verification.NotDisclosed
+			if tt.wantShared {
+				wantName, wantNationality = "JOHN DOE", "GBR"
+			}
+			if subject["name"] != wantName || subject["nationality"] != wantNationality {
+				t.Errorf("credentialSubject name %v, nationality %v, want %q, %q", subject["name"], subject["nationality"], wantName, wantNationality)
+			}
+			disclosure, _ := resp["disclosure"].(map[string]interface{})
+			if disclosure["name"] != tt.wantShared {
+				t.Errorf("disclosure.name = %v, want %v", disclosure["name"], tt.wantShared)
+			}
+			// Fields that weren't asked for stay withheld either way
+			if subject["gender"] != verification.NotDisclosed {
+				t.Errorf("credentialSubject gender = %v, want it withheld", subject["gender"])
+			}
+		})
+	}
+}
+
+func TestVerifyAttestationNotAllowed(t *testing.T) {
+	tests := []struct {
+		name     string
+		allowed  map[string]bool
+		wantCode int
+	}{
+		{"allowed", map[string]bool{"1": true}, http.StatusOK},
+		{"allowed with others", map[string]bool{"1": true, "2": true}, http.StatusOK},
+		{"not allowed", map[string]bool{"2": true}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := &mockVerifier{result: validResult()}
+			deps := useTestDeps(t, verifier)
+			deps.allowed = tt.allowed
+
+			rec := postVerify(verifyBody(t, numberedSignals(21, 2)))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, body %s, want %d", rec.Code, rec.Body, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK {
+				return
+			}
+			if resp := decodeVerifyResponse(t, rec); resp["errorCode"] != verification.ErrorCodeAttestationNotAllowed {
+				t.Errorf("errorCode = %v, want %s", resp["errorCode"], verification.ErrorCodeAttestationNotAllowed)
+			}
+			if verifier.calls.Load() != 0 {
+				t.Error("a disallowed attestation reached the verifier")
+			}
+		})
+	}
+}
+
+func TestVerifyRequestEncodings(t *testing.T) {
+	body := verifyBody(t, numberedSignals(21, 2))
+	compress := func(data []byte) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(data)
+		gz.Close()
+		return buf.Bytes()
+	}
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		wantCode int
+		wantMsg  string
+	}{
+		{"plain", "", []byte(body), http.StatusOK, ""},
+		{"gzip", "gzip", compress([]byte(body)), http.StatusOK, ""},
+		{"unsupported", "br", []byte(body), http.StatusUnsupportedMediaType, "Unsupported Content-Encoding"},
+		{"gzip bomb", "gzip", compress(append([]byte(body), make([]byte, web.MaxRequestBodySize)...)), http.StatusBadRequest, "exceeds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestDeps(t, &mockVerifier{result: validResult()})
+			r := httptest.NewRequest(http.MethodPost, "https://example.com/api/go-verify", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				r.Header.Set("Content-Encoding", tt.encoding)
+			}
+			rec := httptest.NewRecorder()
+			Handler(rec, r)
+			if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantMsg) {
+				t.Errorf("status = %d, body %s, want %d mentioning %q", rec.Code, rec.Body, tt.wantCode, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestUndisclosedPlaceholder(t *testing.T) {
+	tests := []struct {
+		name        string
+		placeholder string
+		omit        bool
+		// want is what the withheld name field holds; nil is JSON null
+		want    interface{}
+		present bool
+	}{
+		{"default", verification.NotDisclosed, false, verification.NotDisclosed, true},
+		{"null", verification.NullPlaceholder, false, nil, true},
+		{"custom string", "REDACTED", false, "REDACTED", true},
+		{"empty string", "", false, "", true},
+		{"omitted", "REDACTED", true, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := useTestDeps(t, &mockVerifier{result: validResult()})
+			deps.settings.UndisclosedPlaceholder = tt.placeholder
+			storeOptions(t, deps, `{"minimumAge":18,"nationality":true,"omit_undisclosed":`+strconv.FormatBool(tt.omit)+`}`)
+
+			rec := postVerify(verifyBody(t, numberedSignals(21, 2)))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			subject, _ := decodeVerifyResponse(t, rec)["credentialSubject"].(map[string]interface{})
+			name, present := subject["name"]
+			if present != tt.present || name != tt.want {
+				t.Errorf("withheld name = %#v (present %v), want %#v (present %v)", name, present, tt.want, tt.present)
+			}
+			if subject["nationality"] != "GBR" {
+				t.Errorf("disclosed nationality = %#v, want GBR", subject["nationality"])
+			}
+		})
+	}
+}
+
+func TestVerifyEndpointHosts(t *testing.T) {
+	tests := []struct {
+		name     string
+		hosts    verification.EndpointHosts
+		wantCode int
+	}{
+		{"unrestricted", nil, http.StatusOK},
+		{"matching host", verification.EndpointHosts{"example.com"}, http.StatusOK},
+		{"matching wildcard", verification.EndpointHosts{"other.org", "*.com"}, http.StatusOK},
+		{"other host", verification.EndpointHosts{"playground.self.xyz"}, http.StatusMisdirectedRequest},
+		{"parent domain only", verification.EndpointHosts{"*.example.com"}, http.StatusMisdirectedRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := &mockVerifier{result: validResult()}
+			deps := useTestDeps(t, verifier)
+			deps.endpointHosts = tt.hosts
+
+			// postVerify sends the request to example.com
+			rec := postVerify(verifyBody(t, numberedSignals(21, 2)))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, body %s, want %d", rec.Code, rec.Body, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK && verifier.calls.Load() != 0 {
+				t.Error("a request for a disallowed host reached the verifier")
+			}
+		})
+	}
+}
+
+func TestRequiredDisclosuresOnVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		required []string
+		// withheld lists output fields the proof left empty
+		withheld     []string
+		wantCode     int
+		wantMissing  string
+		wantRevealed []string
+	}{
+		{"nothing required", nil, nil, http.StatusOK, "", nil},
+		{"required overrides the options", []string{"nationality", "gender"}, nil, http.StatusOK, "", []string{"nationality", "gender"}},
+		{"required field missing", []string{"nationality"}, []string{"Nationality"}, http.StatusUnprocessableEntity, "nationality", nil},
+		{"every missing field is named", []string{"nationality", "gender", "name"}, []string{"Nationality", "Gender"}, http.StatusUnprocessableEntity, "nationality, gender", nil},
+		{"unrequired field missing", []string{"gender"}, []string{"Nationality"}, http.StatusOK, "", []string{"gender"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validResult()
+			for _, field := range tt.withheld {
+				reflect.ValueOf(&result.DiscloseOutput).Elem().FieldByName(field).SetString("")
+			}
+			deps := useTestDeps(t, &mockVerifier{result: result})
+			deps.settings.RequiredDisclosures = tt.required
+			// The options only ask for the name
+			storeOptions(t, deps, `{"minimumAge":18,"name":true}`)
+
+			rec := postVerify(verifyBody(t, numberedSignals(21, 2)))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, body %s, want %d", rec.Code, rec.Body, tt.wantCode)
+			}
+			resp := decodeVerifyResponse(t, rec)
+			if tt.wantMissing != "" {
+				if resp["errorCode"] != verification.ErrorCodeRequiredDisclosureMissing || !strings.HasSuffix(resp["message"].(string), ": "+tt.wantMissing) {
+					t.Errorf("response = %v, want %s naming %s", resp, verification.ErrorCodeRequiredDisclosureMissing, tt.wantMissing)
+				}
+				return
+			}
+			disclosure, _ := resp["disclosure"].(map[string]interface{})
+			for _, flag := range tt.wantRevealed {
+				if disclosure[flag] != true {
+					t.Errorf("disclosure.%s = %v, want true", flag, disclosure[flag])
+				}
+			}
+			if disclosure["date_of_birth"] != false {
+				t.Errorf("disclosure.date_of_birth = %v, want it withheld", disclosure["date_of_birth"])
+			}
+		})
+	}
+}
+
+func TestVerifyWithholdsMalformedFields(t *testing.T) {
+	const allFields = `{"minimumAge":18,"issuing_state":true,"name":true,"passport_number":true,"nationality":true,"date_of_birth":true,"gender":true,"expiry_date":true}`
+	tests := []struct {
+		name   string
+		output string
+		value  string
+		// flag and key name the corrupt field in the options and in credentialSubject
+		flag, key string
+	}{
+		{"date of birth", "DateOfBirth", "not a date", "date_of_birth", "dateOfBirth"},
+		{"impossible date", "DateOfBirth", "1990-13-45", "date_of_birth", "dateOfBirth"},
+		{"expiry date", "ExpiryDate", "someday", "expiry_date", "expiryDate"},
+		{"gender", "Gender", "Q", "gender", "gender"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validResult()
+			reflect.ValueOf(&result.DiscloseOutput).Elem().FieldByName(tt.output).SetString(tt.value)
+			deps := useTestDeps(t, &mockVerifier{result: result})
+			storeOptions(t, deps, allFields)
+
+			rec := postVerify(verifyBody(t, numberedSignals(21, 2)))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			resp := decodeVerifyResponse(t, rec)
+			if resp["status"] != "success" || resp["result"] != true {
+				t.Errorf("response = %v, want the verification to still succeed", resp)
+			}
+			warnings, _ := resp["warnings"].([]interface{})
+			want := tt.flag + " was withheld because its value is malformed"
+			if len(warnings) != 1 || warnings[0] != want {
+				t.Errorf("warnings = %v, want only %q", warnings, want)
+			}
+			if strings.Contains(rec.Body.String(), tt.value) {
+				t.Errorf("response %s carries the malformed value", rec.Body)
+			}
+
+			subject, _ := resp["credentialSubject"].(map[string]interface{})
+			disclosure, _ := resp["disclosure"].(map[string]interface{})
+			if subject[tt.key] != verification.NotDisclosed || disclosure[tt.flag] != false {
+				t.Errorf("%s = %v with disclosure %v, want it withheld", tt.key, subject[tt.key], disclosure[tt.flag])
+			}
+			// The valid fields around it are disclosed as usual
+			for key, want := range map[string]string{"name": "JOHN DOE", "nationality": "GBR", "issuingState": "GBR", "idNumber": "123456789"} {
+				if subject[key] != want {
+					t.Errorf("%s = %v, want %q", key, subject[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyNonce(t *testing.T) {
+	long := strings.Repeat("n", verification.MaxNonceLength+1)
+	hexContext := "0x" + strings.Repeat("00", 64)
+	tests := []struct {
+		name     string
+		require  bool
+		context  interface{}
+		wantCode int
+		// wantNonce is the echoed nonce, or the error code for rejected requests
+		wantNonce string
+		wantError string
+	}{
+		{"absent", false, map[string]interface{}{}, http.StatusOK, "", ""},
+		{"present", false, map[string]interface{}{"nonce": "n-1"}, http.StatusOK, "n-1", ""},
+		{"too long", false, map[string]interface{}{"nonce": long}, http.StatusBadRequest, "", ""},
+		{"hex context", false, hexContext, http.StatusOK, "", ""},
+		{"required and absent", true, map[string]interface{}{}, http.StatusBadRequest, "", verification.ErrorCodeNonceRequired},
+		{"required and present", true, map[string]interface{}{"nonce": "n-1"}, http.StatusOK, "n-1", ""},
+		{"required and too long", true, map[string]interface{}{"nonce": long}, http.StatusBadRequest, "", ""},
+		{"required with a hex context", true, hexContext, http.StatusOK, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := useTestDeps(t, &mockVerifier{result: validResult()})
+			deps.settings.RequireNonce = tt.require
+
+			var req map[string]interface{}
+			if err := json.Unmarshal([]byte(verifyBody(t, numberedSignals(21, 2))), &req); err != nil {
+				t.Fatalf("json.Unmarshal: %v", err)
+			}
+			if fields, ok := tt.context.(map[string]interface{}); ok {
+				fields["userIdentifier"] = testUserID
+				fields["timestamp"] = time.Now().UTC().Format(time.RFC3339)
+			}
+			req["userContextData"] = tt.context
+			body, err := json.Marshal(req)
+			if err != nil {
+				t.Fatalf("json.Marshal: %v", err)
+			}
+
+			rec := postVerify(string(body))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, body %s, want %d", rec.Code, rec.Body, tt.wantCode)
+			}
+			if rec.Code != http.StatusOK {
+				if tt.wantError != "" {
+					if resp := decodeVerifyResponse(t, rec); resp["errorCode"] != tt.wantError {
+						t.Errorf("errorCode = %v, want %s", resp["errorCode"], tt.wantError)
+					}
+				}
+				return
+			}
+			resp := decodeVerifyResponse(t, rec)
+			// The field is always present, empty when the context carried no nonce
+			if nonce, ok := resp["nonce"]; !ok || nonce != tt.wantNonce {
+				t.Errorf("nonce = %v (present %v), want %q", nonce, ok, tt.wantNonce)
+			}
+		})
+	}
+}
+
+func TestVerifyResponsesAreNotCached(t *testing.T) {
+	body := verifyBody(t, numberedSignals(21, 2))
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		verifier verification.Verifier
+		build    error
+		wantCode int
+	}{
+		{"success", http.MethodPost, body, &mockVerifier{result: validResult()}, nil, http.StatusOK},
+		{"invalid JSON", http.MethodPost, `{`, &mockVerifier{result: validResult()}, nil, http.StatusBadRequest},
+		{"wrong method", http.MethodGet, "", &mockVerifier{result: validResult()}, nil, http.StatusMethodNotAllowed},
+		{"verifier can't be built", http.MethodPost, body, nil, errors.New("bad scope"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			discardLogs(t)
+			deps := useTestDeps(t, tt.verifier)
+			if tt.build != nil {
+				deps.newVerifier = func(scope, endpoint string, allowedIds map[self.AttestationId]bool, store self.ConfigStore, userIDType self.UserIDType) (verification.Verifier, error) {
+					return nil, tt.build
+				}
+			}
+			rec := httptest.NewRecorder()
+			Handler(rec, httptest.NewRequest(tt.method, "https://example.com/api/go-verify", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, body %s, want %d", rec.Code, rec.Body, tt.wantCode)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", got)
+			}
+			if got := rec.Header().Get("Pragma"); got != "no-cache" {
+				t.Errorf("Pragma = %q, want no-cache", got)
+			}
+		})
+	}
+}
+
+// FuzzVerifyDecode feeds arbitrary bodies to parseVerifyRequest, which must never panic and
+// must reject what it can't use with a client error. Run it with
+// go test ./api -run '^$' -fuzz FuzzVerifyDecode
+func FuzzVerifyDecode(f *testing.F) {
+	valid := verifyBody(f, numberedSignals(21, 2))
+	f.Add([]byte(valid))
+	f.Add([]byte(verifyBody(f, numberedSignals(21, 77))))
+	// Truncated, and oversized: more signals than the cap, and a signal too long to be one
+	f.Add([]byte(valid[:len(valid)/2]))
+	f.Add([]byte(verifyBody(f, numberedSignals(65, 2))))
+	f.Add([]byte(verifyBody(f, append(numberedSignals(20, 2), strings.Repeat("9", 4096)))))
+	f.Add([]byte(strings.Replace(valid, `"publicSignals":["`, `"publicSignals":[1e999999,"`, 1)))
+	f.Add([]byte(`{"attestationId":"1","proof":{"a":[],"b":[[]],"c":null},"publicSignals":[],"userContextData":{}}`))
+	f.Add([]byte(`{"attestationId":"passport","proof":{"pi_a":["1","2"],"pi_b":[["1","2"],["3","4"]],"pi_c":["1","2"]},"publicSignals":"1","userContextData":"00"}`))
+	f.Add([]byte(strings.Repeat("[", 10000)))
+	f.Add([]byte(`null`))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		parsed, err := parseVerifyRequest(bytes.NewReader(body), verification.PublicSignalLimits{})
+		if err != nil {
+			var reqErr *requestError
+			if !errors.As(err, &reqErr) || reqErr.status < 400 || reqErr.status > 499 || reqErr.message == "" {
+				t.Fatalf("error %#v for %q is not a client error", err, body)
+			}
+			return
+		}
+		if parsed.attestation.Code == "" || len(parsed.publicSignals) != parsed.attestation.PublicSignals {
+			t.Fatalf("accepted %q as %+v", body, parsed)
+		}
+	})
+}
+
+// BenchmarkVerifyHandler measures a whole verify request with the ZK verification mocked out:
+// decoding, parsing, the config lookups, the disclosure filter and encoding the response.
+// Small requests carry two-digit signals, large ones 77-digit field elements
+func BenchmarkVerifyHandler(b *testing.B) {
+	discardLogs(b)
+	for _, bc := range []struct {
+		name   string
+		digits int
+	}{
+		{"small signals", 2},
+		{"large signals", 77},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			useTestDeps(b, &mockVerifier{result: validResult()})
+			body := verifyBody(b, numberedSignals(21, bc.digits))
+			b.SetBytes(int64(len(body)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if rec := postVerify(body); rec.Code != http.StatusOK {
+					b.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkVerifyDeps compares getting a verifier from the shared dependencies with
+// building the dependencies per request, as the handler did before they were shared. The
+// store is Redis, so the rebuilt case dials it every time
+func BenchmarkVerifyDeps(b *testing.B) {
+	discardLogs(b)
+	useRedis(b)
+	previous := sharedDeps.Swap(nil)
+	b.Cleanup(func() {
+		if deps := sharedDeps.Swap(previous); deps != nil {
+			deps.store.Close()
+		}
+	})
+
+	getVerifier := func(deps *verifyDeps) {
+		params := deps.params["1"]
+		_, err := deps.verifiers.Get("|1|"+params.Scope+"|https://example.com/api/go-verify", func() (verification.Verifier, error) {
+			return deps.newVerifier(params.Scope, "https://example.com/api/go-verify", map[self.AttestationId]bool{self.Passport: true},
+				config.ResolvedConfigStore{VerificationConfigStore: deps.store, Policy: deps.settings.Policy()}, deps.settings.UserIDType)
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.Run("shared", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			deps, err := loadVerifyDeps()
+			if err != nil {
+				b.Fatal(err)
+			}
+			getVerifier(deps)
+		}
+	})
+	b.Run("rebuilt per request", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			deps, err := buildVerifyDeps()
+			if err != nil {
+				b.Fatal(err)
+			}
+			getVerifier(deps)
+			// The first command on a fresh store pays for the dial
+			if err := deps.store.Ping(context.Background()); err != nil {
+				b.Fatal(err)
+			}
+			deps.store.Close()
+		}
+	})
+}
+
+// BenchmarkParseVerifyRequest measures decoding and validating a verify request on its own
+func BenchmarkParseVerifyRequest(b *testing.B) {
+	for _, bc := range []struct {
+		name   string
+		digits int
+	}{
+		{"small signals", 2},
+		{"large signals", 77},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			body := verifyBody(b, numberedSignals(21, bc.digits))
+			b.SetBytes(int64(len(body)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := parseVerifyRequest(strings.NewReader(body), verification.PublicSignalLimits{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkCredentialSubject measures encoding the filtered subject, with the default
+// sentinel and with withheld fields rewritten or dropped
+func BenchmarkCredentialSubject(b *testing.B) {
+	attestation, _ := verification.LookupAttestation("1")
+	disclosure := map[string]bool{"name": true, "nationality": true}
+	for _, bc := range []struct {
+		name        string
+		placeholder string
+		omit        bool
+	}{
+		{"default placeholder", verification.NotDisclosed, false},
+		{"custom placeholder", "withheld", false},
+		{"omitted", verification.NotDisclosed, true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			subject := CredentialSubject{
+				GenericDiscloseOutput: validResult().DiscloseOutput,
+				fields:                attestation.Fields,
+				disclosure:            disclosure,
+				placeholder:           verification.ParsePlaceholder(bc.placeholder),
+				omit:                  bc.omit,
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := json.Marshal(subject); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
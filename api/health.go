@@ -0,0 +1,13 @@
+package handler
+
+import (
+	"net/http"
+
+	"playground/health"
+	"playground/web"
+)
+
+// GoHealth is the original health endpoint, kept as an alias of the readiness probe
+func GoHealth(w http.ResponseWriter, r *http.Request) {
+	web.Recover(web.CORS(health.Readiness(health.DefaultChecker))).ServeHTTP(w, r)
+}
@@ -0,0 +1,13 @@
+package handler
+
+import (
+	"net/http"
+
+	"playground/health"
+	"playground/web"
+)
+
+// Healthz is the liveness probe: 200 whenever the function is running
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	web.Recover(http.HandlerFunc(health.Liveness)).ServeHTTP(w, r)
+}
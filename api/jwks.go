@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"playground/jwt"
+	"playground/web"
+)
+
+// JWKS publishes the public key verify tokens are signed with, served at /.well-known/jwks.json
+func JWKS(w http.ResponseWriter, r *http.Request) {
+	web.Recover(web.CORS(http.HandlerFunc(handleJWKS))).ServeHTTP(w, r)
+}
+
+func handleJWKS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		web.WriteJSON(w, r, http.StatusMethodNotAllowed, map[string]string{"message": "Method not allowed"})
+		return
+	}
+
+	signer, err := jwt.Load()
+	if err != nil {
+		log.Printf("Failed to load JWT signer: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	if signer == nil {
+		web.WriteJSON(w, r, http.StatusNotFound, map[string]string{"message": "Token issuance is not enabled"})
+		return
+	}
+
+	// Receivers refetch on unknown kids, so a short cache is enough to absorb load
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	web.WriteJSON(w, r, http.StatusOK, signer.JWKS())
+}
@@ -0,0 +1,13 @@
+package handler
+
+import (
+	"net/http"
+
+	"playground/health"
+	"playground/web"
+)
+
+// Readyz is the readiness probe: 503 while the config store is unreachable
+func Readyz(w http.ResponseWriter, r *http.Request) {
+	web.Recover(health.Readiness(health.DefaultChecker)).ServeHTTP(w, r)
+}
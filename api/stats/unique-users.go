@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"playground/config"
+	"playground/web"
+)
+
+const defaultUniqueUsersWindow = 24 * time.Hour
+
+type UniqueUsersResponse struct {
+	Window string `json:"window"`
+	// Count is a HyperLogLog estimate, typically within 1% of the true number
+	Count int64 `json:"count"`
+}
+
+// UniqueUsers estimates how many distinct users verified successfully in the last
+// window (default 24h, in whole hours up to UNIQUE_USERS_RETENTION)
+func UniqueUsers(w http.ResponseWriter, r *http.Request) {
+	web.Recover(web.RequireAdminToken(http.HandlerFunc(handleUniqueUsers))).ServeHTTP(w, r)
+}
+
+func handleUniqueUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		web.WriteJSON(w, r, http.StatusMethodNotAllowed, map[string]string{"message": "Method not allowed"})
+		return
+	}
+
+	window := defaultUniqueUsersWindow
+	if raw := r.URL.Query().Get("window"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		retention := config.UniqueUsersRetention()
+		if err != nil || d < config.UniqueUsersBucket || d > retention {
+			web.WriteJSON(w, r, http.StatusBadRequest, map[string]string{
+				"message": fmt.Sprintf("window must be a duration between %s and %s, got %q", config.UniqueUsersBucket, retention, raw),
+			})
+			return
+		}
+		window = d
+	}
+
+	store, err := config.NewConfigStoreFromEnv()
+	if err != nil {
+		log.Printf("Failed to initialize config store: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	defer store.Close()
+
+	counter, ok := store.(config.UniqueUserCounter)
+	if !ok {
+		web.WriteJSON(w, r, http.StatusNotImplemented, map[string]string{"message": "The config store backend doesn't keep unique user stats"})
+		return
+	}
+	count, err := counter.CountUniqueUsers(r.Context(), window, time.Now())
+	if err != nil {
+		log.Printf("Failed to count unique users: %v", err)
+		web.WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+		return
+	}
+	web.WriteJSON(w, r, http.StatusOK, UniqueUsersResponse{Window: window.String(), Count: count})
+}
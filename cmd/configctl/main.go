@@ -0,0 +1,183 @@
+// Command configctl seeds and inspects verification configs in the config store
+//
+// Usage:
+//
+//	configctl get <userId> [--attestation CODE]
+//	configctl set <userId> [--attestation CODE] [--min-age N] [--ofac] [--exclude RUS,IRN]
+//	configctl list
+//	configctl migrate
+//
+// With --attestation, set writes the attestation-specific config (use "default" as the
+// user ID for the attestation-wide default) and get applies the verify-time lookup precedence.
+// migrate creates the Postgres schema and is only meaningful with CONFIG_STORE_BACKEND=postgres
+//
+// It selects the backend from CONFIG_STORE_BACKEND (and KV_REST_API_URL/KV_REST_API_TOKEN
+// for Redis) like the API handlers, and prints JSON
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"playground/config"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+	"github.com/selfxyz/self/sdk/sdk-go/common"
+)
+
+// configEntry is the JSON shape printed for a single stored config
+type configEntry struct {
+	ID     string                  `json:"id"`
+	Config self.VerificationConfig `json:"config"`
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: configctl get <userId> [--attestation CODE] | set <userId> [--attestation CODE] [--min-age N] [--ofac] [--exclude RUS,IRN] | list | migrate")
+	os.Exit(2)
+}
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	store, err := config.NewConfigStoreFromEnv()
+	if err != nil {
+		log.Fatalf("failed to initialize config store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	switch os.Args[1] {
+	case "get":
+		if len(os.Args) < 3 {
+			usage()
+		}
+		err = runGet(ctx, store, os.Args[2], os.Args[3:])
+	case "set":
+		if len(os.Args) < 3 {
+			usage()
+		}
+		err = runSet(ctx, store, os.Args[2], os.Args[3:])
+	case "list":
+		err = runList(ctx, store)
+	case "migrate":
+		err = runMigrate(ctx, store)
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatalf("configctl %s: %v", os.Args[1], err)
+	}
+}
+
+func runGet(ctx context.Context, store config.ConfigStore, id string, args []string) error {
+	flags := flag.NewFlagSet("get", flag.ExitOnError)
+	attestation := flags.String("attestation", "", "resolve the config as a verification of this attestation would")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := validateIDs(id, *attestation); err != nil {
+		return err
+	}
+	if *attestation != "" {
+		ctx = config.WithAttestation(ctx, *attestation)
+	}
+
+	cfg, err := store.GetConfig(ctx, id)
+	if err != nil {
+		return err
+	}
+	return printJSON(configEntry{ID: id, Config: cfg})
+}
+
+func runSet(ctx context.Context, store config.ConfigStore, id string, args []string) error {
+	flags := flag.NewFlagSet("set", flag.ExitOnError)
+	minAge := flags.Int("min-age", 0, "minimum age required (0 leaves it unset)")
+	ofac := flags.Bool("ofac", false, "enable the OFAC check")
+	exclude := flags.String("exclude", "", "comma-separated 3-letter country codes to exclude")
+	attestation := flags.String("attestation", "", "store the config for this attestation type only")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := validateIDs(id, *attestation); err != nil {
+		return err
+	}
+	if *attestation != "" {
+		id = config.AttestationConfigKey(*attestation, id)
+	}
+
+	cfg := self.VerificationConfig{Ofac: ofac}
+	if *minAge > 0 {
+		cfg.MinimumAge = minAge
+	}
+	for _, code := range strings.Split(*exclude, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			cfg.ExcludedCountries = append(cfg.ExcludedCountries, common.Country3LetterCode(strings.ToUpper(code)))
+		}
+	}
+
+	result, err := store.SetConfigWithResult(ctx, id, cfg)
+	if err != nil {
+		return err
+	}
+	return printJSON(struct {
+		configEntry
+		config.SetConfigResult
+	}{configEntry{ID: id, Config: cfg}, result})
+}
+
+// validateIDs checks the ID and attestation given on the command line, see config.ValidateConfigID
+func validateIDs(id, attestation string) error {
+	if err := config.ValidateConfigID(id); err != nil {
+		return fmt.Errorf("invalid ID: %w", err)
+	}
+	if err := config.ValidateConfigID(attestation); err != nil {
+		return fmt.Errorf("invalid attestation: %w", err)
+	}
+	return nil
+}
+
+func runList(ctx context.Context, store config.ConfigStore) error {
+	ids, err := store.ListIDs(ctx, "*")
+	if err != nil {
+		return err
+	}
+	attestationKeys, err := store.ListAttestationKeys(ctx)
+	if err != nil {
+		return err
+	}
+	ids = append(ids, attestationKeys...)
+
+	entries := make([]configEntry, 0, len(ids))
+	for _, id := range ids {
+		cfg, err := store.GetConfig(ctx, id)
+		if err != nil {
+			// Other data can share the keyspace; skip anything that isn't a config
+			log.Printf("skipping %s: %v", id, err)
+			continue
+		}
+		entries = append(entries, configEntry{ID: id, Config: cfg})
+	}
+	return printJSON(entries)
+}
+
+func runMigrate(ctx context.Context, store config.ConfigStore) error {
+	pg, ok := store.(*config.PostgresConfigStore)
+	if !ok {
+		return fmt.Errorf("migrate requires CONFIG_STORE_BACKEND=postgres")
+	}
+	return pg.Migrate(ctx)
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,197 @@
+// Command server runs the Go API handlers as one long-lived HTTP server, for deployments
+// outside Vercel such as Kubernetes. Routes match the Vercel paths, plus /healthz, /readyz
+// and /health at the root for probes
+//
+// It listens on PORT (default 8080) and reads the same environment as the handlers.
+// The root path returns a JSON status unless SERVE_LANDING=true (see rootHandler).
+// On SIGINT or SIGTERM it stops accepting connections, waits up to SHUTDOWN_TIMEOUT
+// (default 30s) for in-flight requests, then closes the shared config store. SIGHUP
+// re-validates the verifier settings and drops the cached verifiers, see api.ReloadVerifiers.
+// MAX_IN_FLIGHT caps the requests handled at once, see web.Admission. With TLS_CERT_FILE
+// and TLS_KEY_FILE it serves HTTPS, hardened by TLS_MIN_VERSION and TLS_CIPHER_SUITES
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	api "playground/api"
+	apiconfig "playground/api/config"
+	apiconfigs "playground/api/configs"
+	apidebug "playground/api/debug"
+	apistats "playground/api/stats"
+	"playground/settings"
+	"playground/verification"
+	"playground/web"
+)
+
+// route is one path the server exposes
+type route struct {
+	path    string
+	handler http.HandlerFunc
+}
+
+func main() {
+	// Fail at startup on bad settings instead of on the first request
+	cfg, err := settings.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := checkVerifierParams(); err != nil {
+		log.Fatal(err)
+	}
+
+	root, err := rootHandler()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	routes := []route{
+		// {$} matches only "/" itself, so unknown paths still 404
+		{"/{$}", root},
+		{"/api/go-verify", api.Handler},
+		{"/api/go-verify-stream", api.VerifyStream},
+		{"/api/go-saveOptions", api.GoSaveOptions},
+		{"/api/audit", api.Audit},
+		{"/api/attestations", api.Attestations},
+		{"/api/jwks", api.JWKS},
+		{"/.well-known/jwks.json", api.JWKS},
+		{"/api/config", apiconfig.UpdateConfig},
+		{"/api/config/effective", apiconfig.EffectiveConfig},
+		{"/api/config/validate", apiconfig.ValidateConfig},
+		{"/api/configs/export", apiconfigs.ExportConfigs},
+		{"/api/configs/import", apiconfigs.ImportConfigs},
+		{"/api/debug/redis", apidebug.DebugRedis},
+		{"/api/stats/unique-users", apistats.UniqueUsers},
+	}
+	for _, prefix := range []string{"", "/api"} {
+		routes = append(routes,
+			route{prefix + "/healthz", api.Healthz},
+			route{prefix + "/readyz", api.Readyz},
+			route{prefix + "/health", api.GoHealth},
+		)
+	}
+	for _, rt := range routes {
+		if err := web.Register(mux, rt.path, rt.handler); err != nil {
+			log.Printf("Skipping route: %v", err)
+		}
+	}
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           web.Admission(web.PreflightFallback(mux), cfg.MaxInFlight, cfg.AdmissionQueueTimeout),
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	certFile, keyFile, serveTLS, err := tlsFiles()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if serveTLS {
+		if server.TLSConfig, err = tlsConfig(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		log.Printf("Listening on %s", server.Addr)
+		serve := server.ListenAndServe
+		if serveTLS {
+			serve = func() error { return server.ListenAndServeTLS(certFile, keyFile) }
+		}
+		if err := serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	stopPruner := startPruner(cfg)
+	stopReloader := reloadOnHangup()
+	<-ctx.Done()
+	stop()
+	stopPruner()
+	stopReloader()
+
+	log.Printf("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout())
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		// Requests may still be using the store, so leave it to the process exit
+		log.Printf("Shutdown did not finish cleanly, skipping cleanup: %v", err)
+		return
+	}
+	if err := api.CloseShared(); err != nil {
+		log.Printf("Failed to close config store: %v", err)
+	}
+}
+
+// reloadOnHangup calls api.ReloadVerifiers on every SIGHUP until stop is called. Requests
+// in flight finish with the verifier they started with; the next ones build fresh verifiers
+func reloadOnHangup() (stop func()) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range hangup {
+			if err := api.ReloadVerifiers(); err != nil {
+				log.Printf("Keeping the current verifier settings: %v", err)
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(hangup)
+		close(hangup)
+		<-done
+	}
+}
+
+const defaultShutdownTimeout = 30 * time.Second
+
+func shutdownTimeout() time.Duration {
+	return envDuration("SHUTDOWN_TIMEOUT", defaultShutdownTimeout)
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		log.Printf("Ignoring invalid %s %q, using %s", name, raw, fallback)
+		return fallback
+	}
+	return d
+}
+
+// checkVerifierParams validates the verifier settings up front; proofs are bound to the
+// endpoint, so a dev URL or a host outside ALLOWED_ENDPOINT_HOSTS must stop the server
+func checkVerifierParams() error {
+	params, err := verification.LoadVerifierParams()
+	if err != nil {
+		return err
+	}
+	allowed, err := verification.AllowedAttestations()
+	if err != nil {
+		return err
+	}
+	hosts := verification.AllowedEndpointHosts()
+	if err := verification.ValidateVerifierParams(params, allowed, hosts); err != nil {
+		return err
+	}
+	verification.LogVerifierEndpoints(params, hosts)
+	return nil
+}
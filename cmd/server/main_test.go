@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckVerifierParamsAllowedAttestations(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed string
+		wantErr string
+	}{
+		{"unset", "", ""},
+		{"one type", "passport", ""},
+		{"only separators", ",", "allows no attestation types"},
+		{"unknown type", "3", "unknown attestation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ALLOWED_ATTESTATIONS", tt.allowed)
+			err := checkVerifierParams()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkVerifierParams() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkVerifierParams() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
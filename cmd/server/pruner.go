@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	api "playground/api"
+	"playground/settings"
+)
+
+// startPruner runs the background pruner when PRUNE_ENABLED=true, every PRUNE_INTERVAL
+// removing expired options and audit entries older than PRUNE_RETENTION. The returned
+// function stops it and waits for a run in progress to finish
+func startPruner(cfg *settings.Config) (stop func()) {
+	if !cfg.PruneEnabled {
+		return func() {}
+	}
+	interval, retention := cfg.PruneInterval, cfg.PruneRetention
+	log.Printf("Pruner running every %s with %s retention", interval, retention)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				prune(ctx, retention)
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// prune runs one pass; failures are logged and retried at the next tick
+func prune(ctx context.Context, retention time.Duration) {
+	result, ok, err := api.PruneShared(ctx, retention)
+	switch {
+	case err != nil && ctx.Err() == nil:
+		log.Printf("Pruning failed: %v", err)
+	case err != nil:
+		// Stopped by shutdown
+	case !ok:
+		log.Printf("The config store backend doesn't support pruning")
+	default:
+		log.Printf("Pruned %s", result)
+	}
+}
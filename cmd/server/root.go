@@ -0,0 +1,57 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"strconv"
+
+	"playground/web"
+)
+
+// serviceName identifies this API at its root
+const serviceName = "self-verify"
+
+//go:embed landing.html
+var defaultLanding string
+
+// rootHandler answers GET / with a small JSON status document, or with the HTML landing
+// page when SERVE_LANDING=true, which is meant for local development. LANDING_TEMPLATE
+// replaces the built-in page with an html/template file; it receives .Service
+func rootHandler() (http.HandlerFunc, error) {
+	serve, err := strconv.ParseBool(envOr("SERVE_LANDING", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("SERVE_LANDING must be true or false, got %q", os.Getenv("SERVE_LANDING"))
+	}
+	if !serve {
+		return func(w http.ResponseWriter, r *http.Request) {
+			web.WriteJSON(w, r, http.StatusOK, map[string]string{"service": serviceName, "status": "ok"})
+		}, nil
+	}
+
+	source := defaultLanding
+	if path := os.Getenv("LANDING_TEMPLATE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read LANDING_TEMPLATE: %w", err)
+		}
+		source = string(data)
+	}
+	tmpl, err := template.New("landing").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse landing template: %w", err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		tmpl.Execute(w, struct{ Service string }{serviceName})
+	}, nil
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// tlsFiles returns TLS_CERT_FILE and TLS_KEY_FILE; ok is false when the server should
+// serve plain HTTP, e.g. behind a TLS-terminating load balancer
+func tlsFiles() (certFile, keyFile string, ok bool, err error) {
+	certFile, keyFile = os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")
+	switch {
+	case certFile == "" && keyFile == "":
+		return "", "", false, nil
+	case certFile == "" || keyFile == "":
+		return "", "", false, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	return certFile, keyFile, true, nil
+}
+
+// tlsConfig builds the server's TLS settings from TLS_MIN_VERSION ("1.2", the default, or
+// "1.3") and TLS_CIPHER_SUITES, a comma-separated list of Go cipher suite names that
+// restricts the TLS 1.2 suites. Versions below 1.2 and insecure suites are rejected
+func tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	switch raw := os.Getenv("TLS_MIN_VERSION"); raw {
+	case "", "1.2":
+	case "1.3":
+		cfg.MinVersion = tls.VersionTLS13
+	case "1.0", "1.1":
+		return nil, fmt.Errorf("TLS_MIN_VERSION %s is below the minimum of 1.2", raw)
+	default:
+		return nil, fmt.Errorf("TLS_MIN_VERSION must be 1.2 or 1.3, got %q", raw)
+	}
+
+	raw := os.Getenv("TLS_CIPHER_SUITES")
+	if strings.TrimSpace(raw) == "" {
+		return cfg, nil
+	}
+	// TLS 1.3 suites aren't configurable in Go, so a list would silently do nothing
+	if cfg.MinVersion == tls.VersionTLS13 {
+		return nil, errors.New("TLS_CIPHER_SUITES only applies to TLS 1.2 and can't be combined with TLS_MIN_VERSION=1.3")
+	}
+	suites := make(map[string]uint16)
+	for _, s := range tls.CipherSuites() {
+		suites[s.Name] = s.ID
+	}
+	var unknown []string
+	for _, name := range strings.Split(raw, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		id, ok := suites[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		cfg.CipherSuites = append(cfg.CipherSuites, id)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("TLS_CIPHER_SUITES has unknown or insecure suites: %s", strings.Join(unknown, ", "))
+	}
+	return cfg, nil
+}
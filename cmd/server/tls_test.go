@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/tls"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTLSConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		minVersion  string
+		suites      string
+		wantVersion uint16
+		wantSuites  []uint16
+		wantErr     string
+	}{
+		{"defaults", "", "", tls.VersionTLS12, nil, ""},
+		{"1.2", "1.2", "", tls.VersionTLS12, nil, ""},
+		{"1.3", "1.3", "", tls.VersionTLS13, nil, ""},
+		{"1.0", "1.0", "", 0, nil, "below the minimum of 1.2"},
+		{"1.1", "1.1", "", 0, nil, "below the minimum of 1.2"},
+		{"not a version", "TLS12", "", 0, nil, "must be 1.2 or 1.3"},
+		{"suites", "", " TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 , TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384 ", tls.VersionTLS12,
+			[]uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, ""},
+		{"blank suites", "", " , ", tls.VersionTLS12, nil, ""},
+		{"unknown suite", "", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_MADE_UP", 0, nil, "unknown or insecure suites: TLS_MADE_UP"},
+		{"insecure suite", "", "TLS_RSA_WITH_RC4_128_SHA", 0, nil, "unknown or insecure suites: TLS_RSA_WITH_RC4_128_SHA"},
+		{"suites with 1.3", "1.3", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", 0, nil, "can't be combined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TLS_MIN_VERSION", tt.minVersion)
+			t.Setenv("TLS_CIPHER_SUITES", tt.suites)
+			cfg, err := tlsConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("tlsConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("tlsConfig(): %v", err)
+			}
+			if cfg.MinVersion != tt.wantVersion {
+				t.Errorf("MinVersion = %s, want %s", tls.VersionName(cfg.MinVersion), tls.VersionName(tt.wantVersion))
+			}
+			if !reflect.DeepEqual(cfg.CipherSuites, tt.wantSuites) {
+				t.Errorf("CipherSuites = %v, want %v", cfg.CipherSuites, tt.wantSuites)
+			}
+		})
+	}
+}
+
+func TestTLSFiles(t *testing.T) {
+	tests := []struct {
+		cert, key string
+		wantOK    bool
+		wantErr   bool
+	}{
+		{"", "", false, false},
+		{"cert.pem", "key.pem", true, false},
+		{"cert.pem", "", false, true},
+		{"", "key.pem", false, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("TLS_CERT_FILE", tt.cert)
+		t.Setenv("TLS_KEY_FILE", tt.key)
+		cert, key, ok, err := tlsFiles()
+		if ok != tt.wantOK || (err != nil) != tt.wantErr || (ok && (cert != tt.cert || key != tt.key)) {
+			t.Errorf("tlsFiles() with %q, %q = %q, %q, %v, %v", tt.cert, tt.key, cert, key, ok, err)
+		}
+	}
+}
+
+// TestTLSHandshakeVersions serves with the configured settings and checks which client
+// versions can still connect
+func TestTLSHandshakeVersions(t *testing.T) {
+	tests := []struct {
+		minVersion string
+		client     uint16
+		wantOK     bool
+	}{
+		{"1.2", tls.VersionTLS10, false},
+		{"1.2", tls.VersionTLS11, false},
+		{"1.2", tls.VersionTLS12, true},
+		{"1.2", tls.VersionTLS13, true},
+		{"1.3", tls.VersionTLS12, false},
+		{"1.3", tls.VersionTLS13, true},
+	}
+	for _, tt := range tests {
+		t.Run("min "+tt.minVersion+", "+tls.VersionName(tt.client)+" client", func(t *testing.T) {
+			t.Setenv("TLS_MIN_VERSION", tt.minVersion)
+			t.Setenv("TLS_CIPHER_SUITES", "")
+			cfg, err := tlsConfig()
+			if err != nil {
+				t.Fatalf("tlsConfig(): %v", err)
+			}
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			server.TLS = cfg
+			// Refused handshakes are the point of the test, not worth logging
+			server.Config.ErrorLog = log.New(io.Discard, "", 0)
+			server.StartTLS()
+			defer server.Close()
+
+			client := server.Client()
+			transport := client.Transport.(*http.Transport)
+			transport.TLSClientConfig.MinVersion = tt.client
+			transport.TLSClientConfig.MaxVersion = tt.client
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err == nil) != tt.wantOK {
+				t.Errorf("handshake error = %v, want success %v", err, tt.wantOK)
+			}
+		})
+	}
+}
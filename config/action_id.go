@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ActionIdStrategy derives the action ID the verifier looks configs up by
+type ActionIdStrategy interface {
+	ActionId(userIdentifier string, userDefinedData string) string
+}
+
+// IdentityStrategy uses the user identifier as the action ID, so every user has their own config
+type IdentityStrategy struct{}
+
+func (IdentityStrategy) ActionId(userIdentifier string, userDefinedData string) string {
+	return userIdentifier
+}
+
+// LengthThresholdStrategy picks Long when the user-defined data is longer than Threshold
+// bytes and Short otherwise, sorting users into two shared configs
+type LengthThresholdStrategy struct {
+	Threshold int
+	Short     string
+	Long      string
+}
+
+func (s LengthThresholdStrategy) ActionId(userIdentifier string, userDefinedData string) string {
+	if len(userDefinedData) > s.Threshold {
+		return s.Long
+	}
+	return s.Short
+}
+
+// StaticStrategy maps every verification to the same action ID
+type StaticStrategy struct {
+	ID string
+}
+
+func (s StaticStrategy) ActionId(userIdentifier string, userDefinedData string) string {
+	return s.ID
+}
+
+// Defaults for the length strategy, matching the CustomConfigStore example
+const (
+	defaultActionIdLengthThreshold = 10
+	defaultActionIdShort           = "standard-user-config"
+	defaultActionIdLong            = "premium-user-config"
+)
+
+// ActionIdStrategyFromEnv selects the strategy named by ACTION_ID_STRATEGY:
+//   - identity (default): the user identifier
+//   - length: ACTION_ID_LONG when userDefinedData is longer than ACTION_ID_LENGTH_THRESHOLD,
+//     otherwise ACTION_ID_SHORT
+//   - static: ACTION_ID_STATIC for everyone
+func ActionIdStrategyFromEnv() (ActionIdStrategy, error) {
+	switch name := os.Getenv("ACTION_ID_STRATEGY"); name {
+	case "", "identity":
+		return IdentityStrategy{}, nil
+	case "length":
+		s := LengthThresholdStrategy{
+			Threshold: defaultActionIdLengthThreshold,
+			Short:     envOr("ACTION_ID_SHORT", defaultActionIdShort),
+			Long:      envOr("ACTION_ID_LONG", defaultActionIdLong),
+		}
+		if raw := os.Getenv("ACTION_ID_LENGTH_THRESHOLD"); raw != "" {
+			threshold, err := strconv.Atoi(raw)
+			if err != nil || threshold < 0 {
+				return nil, fmt.Errorf("ACTION_ID_LENGTH_THRESHOLD must be a non-negative integer, got %q", raw)
+			}
+			s.Threshold = threshold
+		}
+		return s, nil
+	case "static":
+		id := os.Getenv("ACTION_ID_STATIC")
+		if id == "" {
+			return nil, fmt.Errorf("ACTION_ID_STRATEGY=static requires ACTION_ID_STATIC")
+		}
+		return StaticStrategy{ID: id}, nil
+	default:
+		return nil, fmt.Errorf("unknown ACTION_ID_STRATEGY %q", name)
+	}
+}
+
+// actionIdOrIdentity treats an unset strategy as the identity strategy, so stores built
+// directly rather than from the environment keep the original behavior
+func actionIdOrIdentity(s ActionIdStrategy) ActionIdStrategy {
+	if s == nil {
+		return IdentityStrategy{}
+	}
+	return s
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
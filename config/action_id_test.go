@@ -0,0 +1,99 @@
+package config
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIdentityStrategy(t *testing.T) {
+	tests := []struct{ user, data string }{
+		{"user-1", ""},
+		{"user-1", "a long user-defined payload"},
+		{"0xabc123", "x"},
+	}
+	for _, tt := range tests {
+		if got := (IdentityStrategy{}).ActionId(tt.user, tt.data); got != tt.user {
+			t.Errorf("ActionId(%q, %q) = %q, want the user identifier", tt.user, tt.data, got)
+		}
+	}
+}
+
+func TestLengthThresholdStrategy(t *testing.T) {
+	s := LengthThresholdStrategy{Threshold: 10, Short: "short", Long: "long"}
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"", "short"},
+		{"123456789", "short"},
+		{"1234567890", "short"},
+		{"12345678901", "long"},
+		{"a much longer user-defined payload", "long"},
+	}
+	for _, tt := range tests {
+		if got := s.ActionId("user-1", tt.data); got != tt.want {
+			t.Errorf("ActionId with %d bytes of data = %q, want %q", len(tt.data), got, tt.want)
+		}
+	}
+}
+
+func TestStaticStrategy(t *testing.T) {
+	s := StaticStrategy{ID: "shared"}
+	for _, user := range []string{"user-1", "user-2", ""} {
+		if got := s.ActionId(user, "data"); got != "shared" {
+			t.Errorf("ActionId(%q) = %q, want shared", user, got)
+		}
+	}
+}
+
+func TestActionIdStrategyFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    ActionIdStrategy
+		wantErr bool
+	}{
+		{"unset", nil, IdentityStrategy{}, false},
+		{"identity", map[string]string{"ACTION_ID_STRATEGY": "identity"}, IdentityStrategy{}, false},
+		{"length defaults", map[string]string{"ACTION_ID_STRATEGY": "length"},
+			LengthThresholdStrategy{Threshold: defaultActionIdLengthThreshold, Short: defaultActionIdShort, Long: defaultActionIdLong}, false},
+		{"length configured", map[string]string{"ACTION_ID_STRATEGY": "length", "ACTION_ID_LENGTH_THRESHOLD": "0", "ACTION_ID_SHORT": "s", "ACTION_ID_LONG": "l"},
+			LengthThresholdStrategy{Threshold: 0, Short: "s", Long: "l"}, false},
+		{"negative threshold", map[string]string{"ACTION_ID_STRATEGY": "length", "ACTION_ID_LENGTH_THRESHOLD": "-1"}, nil, true},
+		{"threshold not a number", map[string]string{"ACTION_ID_STRATEGY": "length", "ACTION_ID_LENGTH_THRESHOLD": "ten"}, nil, true},
+		{"static", map[string]string{"ACTION_ID_STRATEGY": "static", "ACTION_ID_STATIC": "shared"}, StaticStrategy{ID: "shared"}, false},
+		{"static without an ID", map[string]string{"ACTION_ID_STRATEGY": "static"}, nil, true},
+		{"unknown", map[string]string{"ACTION_ID_STRATEGY": "random"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"ACTION_ID_STRATEGY", "ACTION_ID_LENGTH_THRESHOLD", "ACTION_ID_SHORT", "ACTION_ID_LONG", "ACTION_ID_STATIC"} {
+				t.Setenv(name, tt.env[name])
+			}
+			got, err := ActionIdStrategyFromEnv()
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ActionIdStrategyFromEnv = %#v, %v, want %#v, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestStoreActionIds checks that stores from NewConfigStoreFromEnv use the configured strategy
+func TestStoreActionIds(t *testing.T) {
+	t.Setenv("CONFIG_STORE_BACKEND", "memory")
+	t.Setenv("ACTION_ID_STRATEGY", "static")
+	t.Setenv("ACTION_ID_STATIC", "shared")
+	store, err := NewConfigStoreFromEnv()
+	if err != nil {
+		t.Fatalf("NewConfigStoreFromEnv: %v", err)
+	}
+	defer store.Close()
+	if got, err := store.GetActionId(context.Background(), "user-1", "data"); err != nil || got != "shared" {
+		t.Errorf("GetActionId = %q, %v, want shared", got, err)
+	}
+
+	// A store built directly has no strategy and falls back to the identity
+	if got, _ := NewMemoryConfigStore().GetActionId(context.Background(), "user-1", "data"); got != "user-1" {
+		t.Errorf("GetActionId without a strategy = %q, want user-1", got)
+	}
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type attestationContextKey struct{}
+
+// AttestationDefaultID is the ID under which a per-attestation default config is stored
+const AttestationDefaultID = "default"
+
+// WithAttestation records the attestation being verified, so config lookups made with the
+// returned context (including those the SDK makes internally) prefer attestation-specific configs
+func WithAttestation(ctx context.Context, attestationID string) context.Context {
+	return context.WithValue(ctx, attestationContextKey{}, attestationID)
+}
+
+// AttestationFromContext returns the attestation recorded by WithAttestation, or ""
+func AttestationFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(attestationContextKey{}).(string)
+	return id
+}
+
+// attestationPrefix namespaces per-attestation configs, which ListIDs leaves out
+const attestationPrefix = "attestation:"
+
+// AttestationConfigKey is the key of the config for id under one attestation type
+// Use AttestationDefaultID as id for the attestation-wide default
+func AttestationConfigKey(attestationID, id string) string {
+	return attestationPrefix + attestationID + ":" + id
+}
+
+// ValidateConfigID rejects IDs that could address keys they don't own. Derived keys such
+// as attestation:<code>:<id> and tenant:<tenant>:<id> are joined with ':', so an ID
+// containing one could name another user's per-attestation config or another tenant's
+// data. Handlers apply it to IDs and attestation codes taken from requests; verified user
+// IDs are UUIDs or hex and never contain ':'
+func ValidateConfigID(id string) error {
+	if strings.Contains(id, ":") {
+		return fmt.Errorf("%q must not contain ':'", id)
+	}
+	return nil
+}
+
+// configKeys lists the keys consulted for id, most specific first:
+//  1. the (attestation, id) config
+//  2. the id-only config, as stored by saveOptions and before per-attestation configs existed
+//  3. the attestation's default config, scoped to id's tenant when TenantConfigStore
+//     prefixed it, so tenants never read each other's or the untenanted default
+//
+// When all are missing the caller falls back to DefaultVerificationConfig
+func configKeys(ctx context.Context, id string) []string {
+	attestationID := AttestationFromContext(ctx)
+	if attestationID == "" {
+		return []string{id}
+	}
+	return []string{
+		AttestationConfigKey(attestationID, id),
+		id,
+		AttestationConfigKey(attestationID, tenantScope(id)+AttestationDefaultID),
+	}
+}
+
+// tenantScope returns the "tenant:<tenant>:" prefix TenantConfigStore put on id, or "".
+// IDs from requests can't contain ':' (see ValidateConfigID), so only the store adds one
+func tenantScope(id string) string {
+	if !strings.HasPrefix(id, tenantPrefix) {
+		return ""
+	}
+	end := strings.Index(id[len(tenantPrefix):], ":")
+	if end < 0 {
+		return ""
+	}
+	return id[:len(tenantPrefix)+end+1]
+}
+
+// lookup returns the first stored value among configKeys(ctx, id) in one round trip,
+// or redis.Nil when none exists
+func (kv *KVConfigStore) lookup(ctx context.Context, id string) (string, error) {
+	keys := configKeys(ctx, id)
+	if len(keys) == 1 {
+		var value string
+		err := kv.withReconnect(ctx, func(client *redis.Client) (err error) {
+			value, err = client.Get(ctx, id).Result()
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+		return kv.cipher.open(id, value)
+	}
+
+	var values []interface{}
+	err := kv.withReconnect(ctx, func(client *redis.Client) (err error) {
+		values, err = client.MGet(ctx, keys...).Result()
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("unexpected value type %T", value)
+		}
+		return kv.cipher.open(keys[i], s)
+	}
+	return "", redis.Nil
+}
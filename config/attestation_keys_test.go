@@ -0,0 +1,118 @@
+package config
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+)
+
+func TestConfigKeys(t *testing.T) {
+	tests := []struct {
+		name        string
+		attestation string
+		id          string
+		want        []string
+	}{
+		{"no attestation", "", "user-1", []string{"user-1"}},
+		{"passport", "1", "user-1", []string{"attestation:1:user-1", "user-1", "attestation:1:default"}},
+		{"tenant-scoped", "2", "tenant:acme:user-1", []string{"attestation:2:tenant:acme:user-1", "tenant:acme:user-1", "attestation:2:tenant:acme:default"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.attestation != "" {
+				ctx = WithAttestation(ctx, tt.attestation)
+			}
+			if got := configKeys(ctx, tt.id); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("configKeys(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfigID(t *testing.T) {
+	tests := []struct {
+		id      string
+		wantErr bool
+	}{
+		{"0b1c2d3e-1111-4222-8333-444455556666", false},
+		{"0xabc123", false},
+		{AttestationDefaultID, false},
+		{"", false},
+		{"attestation:1:victim", true},
+		{"tenant:other:user-1", true},
+		{"user:1", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateConfigID(tt.id); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateConfigID(%q) = %v, want error %v", tt.id, err, tt.wantErr)
+		}
+	}
+}
+
+// TestAttestationConfigLookup checks the precedence documented on configKeys against a store
+func TestAttestationConfigLookup(t *testing.T) {
+	store, mr := newTestKVStore(t)
+	passport := WithAttestation(context.Background(), "1")
+
+	lookup := func() int {
+		t.Helper()
+		cfg, err := store.GetConfig(passport, "user-1")
+		if err != nil {
+			t.Fatalf("GetConfig: %v", err)
+		}
+		return *cfg.MinimumAge
+	}
+
+	mr.Set(AttestationConfigKey("1", AttestationDefaultID), `{"minimumAge":30}`)
+	if got := lookup(); got != 30 {
+		t.Errorf("with only the attestation default, minimumAge = %d, want 30", got)
+	}
+	mr.Set("user-1", `{"minimumAge":21}`)
+	if got := lookup(); got != 21 {
+		t.Errorf("with a user config, minimumAge = %d, want 21", got)
+	}
+	mr.Set(AttestationConfigKey("1", "user-1"), `{"minimumAge":40}`)
+	if got := lookup(); got != 40 {
+		t.Errorf("with a per-attestation user config, minimumAge = %d, want 40", got)
+	}
+	if cfg, _ := store.GetConfig(WithAttestation(context.Background(), "2"), "user-1"); *cfg.MinimumAge != 21 {
+		t.Errorf("another attestation got minimumAge %d, want the user config's 21", *cfg.MinimumAge)
+	}
+}
+
+// TestTenantAttestationDefaults checks that each tenant reads its own attestation default,
+// written through its TenantConfigStore, and never another tenant's or the untenanted one
+func TestTenantAttestationDefaults(t *testing.T) {
+	t.Setenv("TENANT_IDS", "a,b")
+	store, mr := newTestKVStore(t)
+	passport := WithAttestation(context.Background(), "1")
+	mr.Set(AttestationConfigKey("1", AttestationDefaultID), `{"minimumAge":99}`)
+
+	tenants := map[string]int{"a": 21, "b": 30}
+	for tenant, age := range tenants {
+		scoped, err := NewTenantConfigStore(store, tenant)
+		if err != nil {
+			t.Fatalf("NewTenantConfigStore(%q): %v", tenant, err)
+		}
+		if _, err := scoped.SetConfig(passport, AttestationConfigKey("1", AttestationDefaultID), self.VerificationConfig{MinimumAge: intPtr(age)}); err != nil {
+			t.Fatalf("SetConfig: %v", err)
+		}
+	}
+	if !mr.Exists("attestation:1:tenant:a:default") || !mr.Exists("attestation:1:tenant:b:default") {
+		t.Fatalf("tenant defaults stored under %v, want attestation:1:tenant:<tenant>:default", mr.Keys())
+	}
+
+	for tenant, age := range tenants {
+		scoped, _ := NewTenantConfigStore(store, tenant)
+		cfg, err := scoped.GetConfig(passport, "user-1")
+		if err != nil {
+			t.Fatalf("GetConfig: %v", err)
+		}
+		if *cfg.MinimumAge != age {
+			t.Errorf("tenant %s minimumAge = %d, want its own default's %d", tenant, *cfg.MinimumAge, age)
+		}
+	}
+}
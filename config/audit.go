@@ -0,0 +1,69 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// AuditEntry records the outcome of one verification attempt
+// It deliberately holds no disclosed fields, only identifiers and the outcome
+type AuditEntry struct {
+	Timestamp     time.Time `json:"timestamp"`
+	UserID        string    `json:"userId"`
+	AttestationID string    `json:"attestationId"`
+	Result        bool      `json:"result"`
+	ErrorCode     string    `json:"errorCode,omitempty"`
+	RequestID     string    `json:"requestId"`
+}
+
+// auditPrefix namespaces the per-user audit lists, which ListIDs leaves out
+const auditPrefix = "audit:"
+
+func auditKey(userID string) string {
+	return auditPrefix + userID
+}
+
+// AppendAudit pushes entry onto the user's audit list, trimming it to the newest max entries
+func (kv *KVConfigStore) AppendAudit(ctx context.Context, userID string, entry AuditEntry, max int) error {
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	key := auditKey(userID)
+	client := kv.redisClient()
+	pipe := client.TxPipeline()
+	pipe.LPush(ctx, key, entryJSON)
+	pipe.LTrim(ctx, key, 0, int64(max-1))
+	if _, err := pipe.Exec(ctx); err != nil {
+		kv.noteConnError(ctx, client, err)
+		return fmt.Errorf("failed to append audit entry in Redis: %w", err)
+	}
+	return nil
+}
+
+// ReadAudit returns the user's audit entries, newest first
+func (kv *KVConfigStore) ReadAudit(ctx context.Context, userID string) ([]AuditEntry, error) {
+	var raw []string
+	err := kv.withReconnect(ctx, func(client *redis.Client) (err error) {
+		raw, err = client.LRange(ctx, auditKey(userID), 0, -1).Result()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log from Redis: %w", err)
+	}
+
+	entries := make([]AuditEntry, 0, len(raw))
+	for _, item := range raw {
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(item), &entry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
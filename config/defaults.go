@@ -0,0 +1,112 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+)
+
+const (
+	// fallbackMinimumAge and fallbackOfac apply when DEFAULT_MIN_AGE and DEFAULT_OFAC are unset
+	fallbackMinimumAge = 18
+	fallbackOfac       = true
+
+	// minimumAgeLowerBound and minimumAgeUpperBound bound any configured minimum age;
+	// the disclose circuit encodes the age as two digits
+	minimumAgeLowerBound = 1
+	minimumAgeUpperBound = 99
+
+	// defaultMaxExcludedCountries caps excluded-country lists unless MAX_EXCLUDED_COUNTRIES says otherwise
+	defaultMaxExcludedCountries = 40
+)
+
+// MaxExcludedCountriesFromEnv parses MAX_EXCLUDED_COUNTRIES, how many distinct countries a
+// config may exclude (default 40). settings.Load reports a bad value at startup
+func MaxExcludedCountriesFromEnv() (int, error) {
+	raw := os.Getenv("MAX_EXCLUDED_COUNTRIES")
+	if raw == "" {
+		return defaultMaxExcludedCountries, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 {
+		return defaultMaxExcludedCountries, fmt.Errorf("MAX_EXCLUDED_COUNTRIES must be a positive integer, got %q", raw)
+	}
+	return limit, nil
+}
+
+// maxExcludedCountries is MAX_EXCLUDED_COUNTRIES read once. A bad value has already failed
+// startup, so the default it falls back to is never enforced in a running server
+var maxExcludedCountries = sync.OnceValue(func() int {
+	limit, _ := MaxExcludedCountriesFromEnv()
+	return limit
+})
+
+// DefaultDisclosureConfig returns the options applied when none are saved for an ID:
+// the default checks, with nothing disclosed
+func DefaultDisclosureConfig() SelfAppDisclosureConfig {
+	defaults := DefaultVerificationConfig()
+	return SelfAppDisclosureConfig{
+		MinimumAge: defaults.MinimumAge,
+		Ofac:       defaults.Ofac,
+	}
+}
+
+// OlderThan reports whether o was saved more than maxAge before now. Options without
+// SavedAt predate the timestamp and never count as old; maxAge 0 disables the check
+func (o SelfAppDisclosureConfig) OlderThan(maxAge time.Duration, now time.Time) bool {
+	return maxAge > 0 && o.SavedAt != nil && now.Sub(*o.SavedAt) > maxAge
+}
+
+// DefaultVerificationConfigFromEnv parses DEFAULT_MIN_AGE and DEFAULT_OFAC, which override the
+// 18/true defaults. Bad values fall back to those defaults and are reported together;
+// settings.Load reports them at startup
+func DefaultVerificationConfigFromEnv() (self.VerificationConfig, error) {
+	var errs []error
+	minimumAge := fallbackMinimumAge
+	if raw := os.Getenv("DEFAULT_MIN_AGE"); raw != "" {
+		age, err := strconv.Atoi(raw)
+		if err != nil || age < minimumAgeLowerBound || age > minimumAgeUpperBound {
+			errs = append(errs, fmt.Errorf("DEFAULT_MIN_AGE must be an integer between %d and %d, got %q", minimumAgeLowerBound, minimumAgeUpperBound, raw))
+		} else {
+			minimumAge = age
+		}
+	}
+
+	ofac := fallbackOfac
+	if raw := os.Getenv("DEFAULT_OFAC"); raw != "" {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("DEFAULT_OFAC must be true or false, got %q", raw))
+		} else {
+			ofac = enabled
+		}
+	}
+
+	return self.VerificationConfig{
+		MinimumAge: &minimumAge,
+		Ofac:       &ofac,
+	}, errors.Join(errs...)
+}
+
+// envDefaults is DefaultVerificationConfigFromEnv read once. Bad values have already failed
+// startup, so the fallbacks it keeps are never served by a running server
+var envDefaults = sync.OnceValue(func() self.VerificationConfig {
+	defaults, _ := DefaultVerificationConfigFromEnv()
+	return defaults
+})
+
+// DefaultVerificationConfig returns the config applied when no config is stored for an ID.
+// Each call gets its own copy, so callers may modify it
+func DefaultVerificationConfig() self.VerificationConfig {
+	defaults := envDefaults()
+	minimumAge, ofac := *defaults.MinimumAge, *defaults.Ofac
+	return self.VerificationConfig{
+		MinimumAge: &minimumAge,
+		Ofac:       &ofac,
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultVerificationConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		minAge, ofac string
+		wantAge      int
+		wantOfac     bool
+		wantErr      string
+	}{
+		{"", "", fallbackMinimumAge, fallbackOfac, ""},
+		{"21", "false", 21, false, ""},
+		{"0", "", 0, false, "DEFAULT_MIN_AGE"},
+		{"adult", "", 0, false, "DEFAULT_MIN_AGE"},
+		{"", "maybe", 0, false, "DEFAULT_OFAC"},
+		{"150", "maybe", 0, false, "DEFAULT_MIN_AGE must be an integer between 1 and 99, got \"150\"\nDEFAULT_OFAC"},
+	}
+	for _, tt := range tests {
+		t.Setenv("DEFAULT_MIN_AGE", tt.minAge)
+		t.Setenv("DEFAULT_OFAC", tt.ofac)
+		got, err := DefaultVerificationConfigFromEnv()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("DEFAULT_MIN_AGE=%q DEFAULT_OFAC=%q: error = %v, want %q", tt.minAge, tt.ofac, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || *got.MinimumAge != tt.wantAge || *got.Ofac != tt.wantOfac {
+			t.Errorf("DEFAULT_MIN_AGE=%q DEFAULT_OFAC=%q: got %d/%v, %v, want %d/%v", tt.minAge, tt.ofac, *got.MinimumAge, *got.Ofac, err, tt.wantAge, tt.wantOfac)
+		}
+	}
+}
+
+// TestDefaultVerificationConfigCopies checks that changing one caller's defaults leaves the next caller's alone
+func TestDefaultVerificationConfigCopies(t *testing.T) {
+	first := DefaultVerificationConfig()
+	*first.MinimumAge = 99
+	*first.Ofac = !*first.Ofac
+	if second := DefaultVerificationConfig(); *second.MinimumAge == 99 || *second.Ofac == *first.Ofac {
+		t.Errorf("DefaultVerificationConfig returned shared values: %d/%v", *second.MinimumAge, *second.Ofac)
+	}
+}
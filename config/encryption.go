@@ -0,0 +1,82 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// encryptedPrefix marks values sealed by valueCipher; anything without it is legacy plaintext
+const encryptedPrefix = "enc:v1:"
+
+// valueCipher encrypts stored values with AES-256-GCM. A nil *valueCipher stores plaintext
+type valueCipher struct {
+	aead cipher.AEAD
+}
+
+// cipherFromEnv reads CONFIG_ENCRYPTION_KEY, a base64-encoded 32-byte key
+// Unset leaves values in plaintext; a malformed key is an error rather than a silent fallback
+func cipherFromEnv() (*valueCipher, error) {
+	raw := os.Getenv("CONFIG_ENCRYPTION_KEY")
+	if raw == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("CONFIG_ENCRYPTION_KEY must be base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("CONFIG_ENCRYPTION_KEY must decode to 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &valueCipher{aead: aead}, nil
+}
+
+// seal encrypts value with a random nonce prepended to the ciphertext. The Redis key is
+// authenticated too, so a value copied to another key fails to open
+func (c *valueCipher) seal(key, value string) (string, error) {
+	if c == nil {
+		return value, nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), []byte(key))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a value written by seal. Values without the prefix are legacy plaintext and
+// returned as they are, so a store can be migrated gradually. It fails closed: an encrypted
+// value that doesn't decrypt, or one found while no key is configured, is an error
+func (c *valueCipher) open(key, stored string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, encryptedPrefix)
+	if !ok {
+		return stored, nil
+	}
+	if c == nil {
+		return "", errors.New("value is encrypted but CONFIG_ENCRYPTION_KEY is not set")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", fmt.Errorf("failed to decrypt value of %s: malformed ciphertext", key)
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, []byte(key))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value of %s: %w", key, err)
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import "strings"
+
+// Configs share one keyspace with values that aren't configs. These prefixes mark the
+// latter so ListIDs can leave them out
+const (
+	// ResultCachePrefix namespaces cached verification results
+	ResultCachePrefix = "verify-result:"
+	// StreamTokenPrefix namespaces proofs waiting for their verify stream to be opened
+	StreamTokenPrefix = "verify-stream:"
+)
+
+// internalPrefixes are the prefixes of every key that holds something other than a config
+var internalPrefixes = []string{
+	configVersionPrefix,
+	userListPrefix,
+	uniqueUsersPrefix,
+	auditPrefix,
+	ResultCachePrefix,
+	StreamTokenPrefix,
+}
+
+// isInternalKey reports whether key holds the store's own bookkeeping or another value
+// that isn't a config. TenantConfigStore prefixes the keys it writes, so the check also
+// looks past a tenant:<id>: prefix
+func isInternalKey(key string) bool {
+	if rest, ok := strings.CutPrefix(key, "tenant:"); ok {
+		if _, unscoped, ok := strings.Cut(rest, ":"); ok && isInternalKey(unscoped) {
+			return true
+		}
+	}
+	for _, prefix := range internalPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAttestationKey reports whether key holds a per-attestation config
+func isAttestationKey(key string) bool {
+	return strings.HasPrefix(key, attestationPrefix)
+}
+
+// isUserConfigKey reports whether key holds a config stored for a single ID, tenant-scoped or not
+func isUserConfigKey(key string) bool {
+	return !isInternalKey(key) && !isAttestationKey(key)
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+)
+
+// MemoryConfigStore is a ConfigStore kept in process memory, for CI and local runs
+// Values are stored as the same JSON Redis would hold, so both backends behave alike.
+// Every serverless instance gets its own copy, so don't use it for real deployments
+type MemoryConfigStore struct {
+	mu     sync.Mutex
+	values map[string]memoryValue
+	audit  map[string][]AuditEntry
+	// versions counts config changes per key; see SetConfigResult
+	versions map[string]int64
+
+	actionIds ActionIdStrategy
+}
+
+type memoryValue struct {
+	value string
+	// expiresAt is zero for values that never expire
+	expiresAt time.Time
+}
+
+// NewMemoryConfigStore creates an empty in-memory store
+func NewMemoryConfigStore() *MemoryConfigStore {
+	return &MemoryConfigStore{
+		values:   make(map[string]memoryValue),
+		audit:    make(map[string][]AuditEntry),
+		versions: make(map[string]int64),
+	}
+}
+
+// get returns the live value of key; the caller must hold m.mu
+func (m *MemoryConfigStore) get(key string) (string, bool) {
+	v, ok := m.values[key]
+	if !ok {
+		return "", false
+	}
+	if !v.expiresAt.IsZero() && time.Now().After(v.expiresAt) {
+		delete(m.values, key)
+		return "", false
+	}
+	return v.value, true
+}
+
+// lookup returns the first stored value among configKeys(ctx, id)
+func (m *MemoryConfigStore) lookup(ctx context.Context, id string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range configKeys(ctx, id) {
+		if value, ok := m.get(key); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func (m *MemoryConfigStore) GetActionId(ctx context.Context, userIdentifier string, userDefinedData string) (string, error) {
+	return actionIdOrIdentity(m.actionIds).ActionId(userIdentifier, userDefinedData), nil
+}
+
+func (m *MemoryConfigStore) SetConfig(ctx context.Context, id string, config self.VerificationConfig) (bool, error) {
+	result, err := m.SetConfigWithResult(ctx, id, config)
+	return result.Created, err
+}
+
+func (m *MemoryConfigStore) SetConfigWithResult(ctx context.Context, id string, config self.VerificationConfig) (SetConfigResult, error) {
+	configJSON, err := encodeConfig(config)
+	if err != nil {
+		return SetConfigResult{}, err
+	}
+	return m.SetRawConfig(ctx, id, configJSON)
+}
+
+func (m *MemoryConfigStore) SetRawConfig(ctx context.Context, id string, configJSON string) (SetConfigResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, existed := m.get(id)
+	if existed && stored == configJSON {
+		return SetConfigResult{Version: m.versions[id]}, nil
+	}
+	m.values[id] = memoryValue{value: configJSON}
+	m.versions[id]++
+	return SetConfigResult{Created: !existed, Changed: true, Version: m.versions[id]}, nil
+}
+
+func (m *MemoryConfigStore) GetConfig(ctx context.Context, id string) (self.VerificationConfig, error) {
+	configJSON, ok := m.lookup(ctx, id)
+	if !ok {
+		return DefaultVerificationConfig(), nil
+	}
+
+	var config self.VerificationConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return self.VerificationConfig{}, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return config, nil
+}
+
+func (m *MemoryConfigStore) GetDisclosureConfig(ctx context.Context, id string) (SelfAppDisclosureConfig, error) {
+	optionsJSON, ok := m.lookup(ctx, id)
+	if !ok {
+		return DefaultDisclosureConfig(), nil
+	}
+
+	var options SelfAppDisclosureConfig
+	if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+		return SelfAppDisclosureConfig{}, fmt.Errorf("failed to unmarshal options: %w", err)
+	}
+	return options, nil
+}
+
+func (m *MemoryConfigStore) UpdateConfig(ctx context.Context, id string, patch SelfAppDisclosureConfig) (SelfAppDisclosureConfig, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, _ := m.get(id)
+	merged, err := mergeStoredConfig(stored, patch)
+	if err != nil {
+		return SelfAppDisclosureConfig{}, err
+	}
+	mergedJSON, err := json.Marshal(merged)
+	if err != nil {
+		return SelfAppDisclosureConfig{}, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	m.values[id] = memoryValue{value: string(mergedJSON), expiresAt: m.values[id].expiresAt}
+	m.versions[id]++
+	return merged, nil
+}
+
+func (m *MemoryConfigStore) SetWithExpiration(ctx context.Context, key string, value string, expiration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v := memoryValue{value: value}
+	if expiration > 0 {
+		v.expiresAt = time.Now().Add(expiration)
+	}
+	m.values[key] = v
+	return nil
+}
+
+func (m *MemoryConfigStore) SaveOptions(ctx context.Context, id string, optionsJSON string, expiration time.Duration, onlyIfAbsent bool) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.get(id); ok && onlyIfAbsent {
+		return false, nil
+	}
+	v := memoryValue{value: optionsJSON}
+	if expiration > 0 {
+		v.expiresAt = time.Now().Add(expiration)
+	}
+	m.values[id] = v
+	m.versions[id]++
+	return true, nil
+}
+
+func (m *MemoryConfigStore) GetValue(ctx context.Context, key string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.get(key)
+	return value, ok, nil
+}
+
+func (m *MemoryConfigStore) TakeValue(ctx context.Context, key string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.get(key)
+	delete(m.values, key)
+	return value, ok, nil
+}
+
+func (m *MemoryConfigStore) AppendAudit(ctx context.Context, userID string, entry AuditEntry, max int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := append([]AuditEntry{entry}, m.audit[userID]...)
+	if len(entries) > max {
+		entries = entries[:max]
+	}
+	m.audit[userID] = entries
+	return nil
+}
+
+func (m *MemoryConfigStore) ReadAudit(ctx context.Context, userID string) ([]AuditEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]AuditEntry{}, m.audit[userID]...), nil
+}
+
+// ListIDs returns every config key matching the glob pattern, leaving out other values
+// such as cached results, and per-attestation configs; see ListAttestationKeys
+func (m *MemoryConfigStore) ListIDs(ctx context.Context, pattern string) ([]string, error) {
+	return m.keys(pattern, isUserConfigKey)
+}
+
+// ListAttestationKeys returns the keys of every per-attestation config
+func (m *MemoryConfigStore) ListAttestationKeys(ctx context.Context) ([]string, error) {
+	return m.keys(attestationPrefix+"*", isAttestationKey)
+}
+
+// keys returns the live keys matching the glob pattern that keep accepts
+func (m *MemoryConfigStore) keys(pattern string, keep func(key string) bool) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []string
+	for key := range m.values {
+		if _, ok := m.get(key); !ok || !keep(key) {
+			continue
+		}
+		matched, err := path.Match(pattern, key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if matched {
+			ids = append(ids, key)
+		}
+	}
+	return ids, nil
+}
+
+func (m *MemoryConfigStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *MemoryConfigStore) Close() error {
+	return nil
+}
@@ -0,0 +1,19 @@
+-- Schema for the Postgres config store (CONFIG_STORE_BACKEND=postgres)
+-- Safe to run more than once
+
+CREATE TABLE IF NOT EXISTS configs (
+    user_id    text PRIMARY KEY,
+    config     jsonb NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    -- Set for short-lived entries such as saved options; NULL never expires
+    expires_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id         bigserial PRIMARY KEY,
+    user_id    text NOT NULL,
+    entry      jsonb NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_user_id_idx ON audit_log (user_id, id DESC);
@@ -0,0 +1,4 @@
+-- Counts changes to each config; see SetConfigResult
+-- Safe to run more than once
+
+ALTER TABLE configs ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 0;
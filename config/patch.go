@@ -0,0 +1,74 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// updateAttempts is how many times UpdateConfig retries after losing a concurrent write
+const updateAttempts = 3
+
+// ErrConflict is returned when UpdateConfig keeps losing races with concurrent writers
+var ErrConflict = errors.New("config was modified concurrently, retry the update")
+
+// MergeDisclosureConfig applies patch on top of base. Nil pointers and a nil country
+// list leave the base value unchanged; an empty country list clears it
+func MergeDisclosureConfig(base, patch SelfAppDisclosureConfig) SelfAppDisclosureConfig {
+	merged := base
+	for _, field := range []struct{ dst, src **bool }{
+		{&merged.IssuingState, &patch.IssuingState},
+		{&merged.Name, &patch.Name},
+		{&merged.PassportNumber, &patch.PassportNumber},
+		{&merged.Nationality, &patch.Nationality},
+		{&merged.DateOfBirth, &patch.DateOfBirth},
+		{&merged.Gender, &patch.Gender},
+		{&merged.ExpiryDate, &patch.ExpiryDate},
+		{&merged.Ofac, &patch.Ofac},
+		{&merged.AgeGatedDisclosure, &patch.AgeGatedDisclosure},
+		{&merged.OmitUndisclosed, &patch.OmitUndisclosed},
+	} {
+		if *field.src != nil {
+			*field.dst = *field.src
+		}
+	}
+	if patch.MinimumAge != nil {
+		merged.MinimumAge = patch.MinimumAge
+	}
+	if patch.ExcludedCountries != nil {
+		merged.ExcludedCountries = patch.ExcludedCountries
+	}
+	if patch.NationalityFormat != "" {
+		merged.NationalityFormat = patch.NationalityFormat
+	}
+	if patch.ExcludedCountriesFormat != "" {
+		merged.ExcludedCountriesFormat = patch.ExcludedCountriesFormat
+	}
+	if patch.DateOfBirthFormat != "" {
+		merged.DateOfBirthFormat = patch.DateOfBirthFormat
+	}
+	return merged
+}
+
+// mergeStoredConfig merges patch into the stored JSON (empty when nothing is stored)
+// and validates the result the same way SetConfig does, stamping it as saved now
+func mergeStoredConfig(stored string, patch SelfAppDisclosureConfig) (SelfAppDisclosureConfig, error) {
+	base := SelfAppDisclosureConfig{}
+	if stored == "" {
+		defaults := DefaultVerificationConfig()
+		base.MinimumAge = defaults.MinimumAge
+		base.Ofac = defaults.Ofac
+	} else if err := json.Unmarshal([]byte(stored), &base); err != nil {
+		return SelfAppDisclosureConfig{}, fmt.Errorf("failed to unmarshal stored config: %w", err)
+	}
+
+	merged := MergeDisclosureConfig(base, patch)
+	merged.ExcludedCountries = DedupeCountries(merged.ExcludedCountries)
+	now := time.Now().UTC()
+	merged.SavedAt = &now
+	if errs := ValidateDisclosureConfig(merged); len(errs) > 0 {
+		return SelfAppDisclosureConfig{}, &ValidationError{Errors: errs}
+	}
+	return merged, nil
+}
@@ -0,0 +1,392 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	self "github.com/selfxyz/self/sdk/sdk-go"
+)
+
+var (
+	//go:embed migrations/0001_create_configs.sql
+	schemaCreateConfigs string
+	//go:embed migrations/0002_config_versions.sql
+	schemaConfigVersions string
+)
+
+// PostgresSchema creates the tables PostgresConfigStore uses, applying migrations/ in order
+var PostgresSchema = schemaCreateConfigs + "\n" + schemaConfigVersions
+
+// liveRow restricts a configs query to entries that haven't expired
+const liveRow = "(expires_at IS NULL OR expires_at > now())"
+
+// PostgresConfigStore is a ConfigStore backed by the configs and audit_log tables
+type PostgresConfigStore struct {
+	db        *sql.DB
+	actionIds ActionIdStrategy
+}
+
+// NewPostgresConfigStore opens a connection pool to dsn and checks it is reachable
+func NewPostgresConfigStore(dsn string) (*PostgresConfigStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Postgres: %w", err)
+	}
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(30 * time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to Postgres: %w", err)
+	}
+	return &PostgresConfigStore{db: db}, nil
+}
+
+// NewPostgresConfigStoreFromEnv creates a Postgres config store from DATABASE_URL
+func NewPostgresConfigStoreFromEnv() (*PostgresConfigStore, error) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		return nil, fmt.Errorf("DATABASE_URL environment variable is required")
+	}
+	return NewPostgresConfigStore(dsn)
+}
+
+// Migrate applies PostgresSchema
+func (p *PostgresConfigStore) Migrate(ctx context.Context) error {
+	if _, err := p.db.ExecContext(ctx, PostgresSchema); err != nil {
+		return fmt.Errorf("failed to apply Postgres schema: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresConfigStore) GetActionId(ctx context.Context, userIdentifier string, userDefinedData string) (string, error) {
+	return actionIdOrIdentity(p.actionIds).ActionId(userIdentifier, userDefinedData), nil
+}
+
+// SetConfig stores config under id and reports whether it was newly created
+func (p *PostgresConfigStore) SetConfig(ctx context.Context, id string, config self.VerificationConfig) (bool, error) {
+	result, err := p.SetConfigWithResult(ctx, id, config)
+	return result.Created, err
+}
+
+// SetConfigWithResult upserts the config for id unless a live row already holds equal
+// JSON; xmax is 0 only for a freshly inserted row
+func (p *PostgresConfigStore) SetConfigWithResult(ctx context.Context, id string, config self.VerificationConfig) (SetConfigResult, error) {
+	configJSON, err := encodeConfig(config)
+	if err != nil {
+		return SetConfigResult{}, err
+	}
+	return p.SetRawConfig(ctx, id, configJSON)
+}
+
+// SetRawConfig upserts configJSON for id the same way SetConfigWithResult does
+func (p *PostgresConfigStore) SetRawConfig(ctx context.Context, id string, configJSON string) (SetConfigResult, error) {
+	result := SetConfigResult{Changed: true}
+	err := p.db.QueryRowContext(ctx, `
+		INSERT INTO configs (user_id, config, version, updated_at, expires_at)
+		VALUES ($1, $2, 1, now(), NULL)
+		ON CONFLICT (user_id) DO UPDATE
+		SET config = EXCLUDED.config, version = configs.version + 1, updated_at = now(), expires_at = NULL
+		WHERE configs.config IS DISTINCT FROM EXCLUDED.config
+			OR NOT (configs.expires_at IS NULL OR configs.expires_at > now())
+		RETURNING xmax = 0, version`,
+		id, configJSON,
+	).Scan(&result.Created, &result.Version)
+	if err == sql.ErrNoRows {
+		// The WHERE skipped the update: the same config is already stored
+		err = p.db.QueryRowContext(ctx, `SELECT version FROM configs WHERE user_id = $1`, id).Scan(&result.Version)
+		result.Changed = false
+	}
+	if err != nil {
+		return SetConfigResult{}, fmt.Errorf("failed to set config in Postgres: %w", err)
+	}
+	return result, nil
+}
+
+// UpdateConfig merges patch into the config stored under id and returns the result
+// The write only succeeds if updated_at is unchanged since the read, otherwise it retries
+func (p *PostgresConfigStore) UpdateConfig(ctx context.Context, id string, patch SelfAppDisclosureConfig) (SelfAppDisclosureConfig, error) {
+	for attempt := 0; attempt < updateAttempts; attempt++ {
+		var stored string
+		var updatedAt time.Time
+		err := p.db.QueryRowContext(ctx,
+			`SELECT config, updated_at FROM configs WHERE user_id = $1 AND `+liveRow, id,
+		).Scan(&stored, &updatedAt)
+		exists := err == nil
+		if err != nil && err != sql.ErrNoRows {
+			return SelfAppDisclosureConfig{}, fmt.Errorf("failed to read config from Postgres: %w", err)
+		}
+
+		merged, err := mergeStoredConfig(stored, patch)
+		if err != nil {
+			return SelfAppDisclosureConfig{}, err
+		}
+		mergedJSON, err := json.Marshal(merged)
+		if err != nil {
+			return SelfAppDisclosureConfig{}, fmt.Errorf("failed to marshal config: %w", err)
+		}
+
+		var result sql.Result
+		if exists {
+			result, err = p.db.ExecContext(ctx,
+				`UPDATE configs SET config = $2, version = version + 1, updated_at = now() WHERE user_id = $1 AND updated_at = $3`,
+				id, string(mergedJSON), updatedAt,
+			)
+		} else {
+			// Only replace a row that expired; a live one means another writer got there first
+			result, err = p.db.ExecContext(ctx, `
+				INSERT INTO configs (user_id, config, version, updated_at, expires_at)
+				VALUES ($1, $2, 1, now(), NULL)
+				ON CONFLICT (user_id) DO UPDATE
+				SET config = EXCLUDED.config, version = configs.version + 1, updated_at = now(), expires_at = NULL
+				WHERE NOT (configs.expires_at IS NULL OR configs.expires_at > now())`,
+				id, string(mergedJSON),
+			)
+		}
+		if err != nil {
+			return SelfAppDisclosureConfig{}, fmt.Errorf("failed to update config in Postgres: %w", err)
+		}
+		if n, err := result.RowsAffected(); err == nil && n == 1 {
+			return merged, nil
+		}
+	}
+	return SelfAppDisclosureConfig{}, ErrConflict
+}
+
+// SetWithExpiration stores value under key until expiration passes
+func (p *PostgresConfigStore) SetWithExpiration(ctx context.Context, key string, value string, expiration time.Duration) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO configs (user_id, config, updated_at, expires_at)
+		VALUES ($1, $2, now(), now() + $3 * interval '1 millisecond')
+		ON CONFLICT (user_id) DO UPDATE
+		SET config = EXCLUDED.config, updated_at = now(), expires_at = EXCLUDED.expires_at`,
+		key, value, expiration.Milliseconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set key with expiration in Postgres: %w", err)
+	}
+	return nil
+}
+
+// SaveOptions upserts the options row and bumps its version in one statement. With
+// onlyIfAbsent a live row is left alone; an expired one is replaced either way
+func (p *PostgresConfigStore) SaveOptions(ctx context.Context, id string, optionsJSON string, expiration time.Duration, onlyIfAbsent bool) (bool, error) {
+	res, err := p.db.ExecContext(ctx, `
+		INSERT INTO configs (user_id, config, version, updated_at, expires_at)
+		VALUES ($1, $2, 1, now(), now() + $3 * interval '1 millisecond')
+		ON CONFLICT (user_id) DO UPDATE
+		SET config = EXCLUDED.config, version = configs.version + 1, updated_at = now(), expires_at = EXCLUDED.expires_at
+		WHERE NOT $4 OR (configs.expires_at IS NOT NULL AND configs.expires_at <= now())`,
+		id, optionsJSON, expiration.Milliseconds(), onlyIfAbsent,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to save options to Postgres: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to save options to Postgres: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetValue reads a raw live value from the configs table
+func (p *PostgresConfigStore) GetValue(ctx context.Context, key string) (string, bool, error) {
+	var value string
+	err := p.db.QueryRowContext(ctx, `SELECT config FROM configs WHERE user_id = $1 AND `+liveRow, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get key from Postgres: %w", err)
+	}
+	return value, true, nil
+}
+
+// TakeValue deletes the row for key and returns its value if it was still live
+func (p *PostgresConfigStore) TakeValue(ctx context.Context, key string) (string, bool, error) {
+	var value string
+	var live bool
+	err := p.db.QueryRowContext(ctx, `DELETE FROM configs WHERE user_id = $1 RETURNING config, `+liveRow, key).Scan(&value, &live)
+	if err == sql.ErrNoRows || (err == nil && !live) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to take key from Postgres: %w", err)
+	}
+	return value, true, nil
+}
+
+// lookup returns the first live value among configKeys(ctx, id), or sql.ErrNoRows
+func (p *PostgresConfigStore) lookup(ctx context.Context, id string) (string, error) {
+	keys := configKeys(ctx, id)
+	rows, err := p.db.QueryContext(ctx,
+		`SELECT user_id, config FROM configs WHERE user_id = ANY($1) AND `+liveRow,
+		pq.Array(keys),
+	)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	found := make(map[string]string, len(keys))
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return "", err
+		}
+		found[key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	for _, key := range keys {
+		if value, ok := found[key]; ok {
+			return value, nil
+		}
+	}
+	return "", sql.ErrNoRows
+}
+
+func (p *PostgresConfigStore) GetConfig(ctx context.Context, id string) (self.VerificationConfig, error) {
+	configJSON, err := p.lookup(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return DefaultVerificationConfig(), nil
+		}
+		return self.VerificationConfig{}, fmt.Errorf("failed to get config from Postgres: %w", err)
+	}
+
+	var config self.VerificationConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return self.VerificationConfig{}, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return config, nil
+}
+
+func (p *PostgresConfigStore) GetDisclosureConfig(ctx context.Context, id string) (SelfAppDisclosureConfig, error) {
+	optionsJSON, err := p.lookup(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return DefaultDisclosureConfig(), nil
+		}
+		return SelfAppDisclosureConfig{}, fmt.Errorf("failed to get options from Postgres: %w", err)
+	}
+
+	var options SelfAppDisclosureConfig
+	if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+		return SelfAppDisclosureConfig{}, fmt.Errorf("failed to unmarshal options: %w", err)
+	}
+	return options, nil
+}
+
+// AppendAudit inserts entry and drops all but the newest max entries for the user
+func (p *PostgresConfigStore) AppendAudit(ctx context.Context, userID string, entry AuditEntry, max int) error {
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to append audit entry in Postgres: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO audit_log (user_id, entry) VALUES ($1, $2)`, userID, string(entryJSON)); err != nil {
+		return fmt.Errorf("failed to append audit entry in Postgres: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM audit_log
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM audit_log WHERE user_id = $1 ORDER BY id DESC LIMIT $2
+		)`, userID, max); err != nil {
+		return fmt.Errorf("failed to trim audit log in Postgres: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to append audit entry in Postgres: %w", err)
+	}
+	return nil
+}
+
+// ReadAudit returns the user's audit entries, newest first
+func (p *PostgresConfigStore) ReadAudit(ctx context.Context, userID string) ([]AuditEntry, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT entry FROM audit_log WHERE user_id = $1 ORDER BY id DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log from Postgres: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, fmt.Errorf("failed to read audit log from Postgres: %w", err)
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log from Postgres: %w", err)
+	}
+	return entries, nil
+}
+
+// ListIDs returns every live config key matching the Redis-style glob pattern (* and ?),
+// leaving out rows that hold something else, such as cached results, and per-attestation
+// configs; see ListAttestationKeys
+func (p *PostgresConfigStore) ListIDs(ctx context.Context, pattern string) ([]string, error) {
+	return p.keys(ctx, pattern, isUserConfigKey)
+}
+
+// ListAttestationKeys returns the keys of every live per-attestation config
+func (p *PostgresConfigStore) ListAttestationKeys(ctx context.Context) ([]string, error) {
+	return p.keys(ctx, attestationPrefix+"*", isAttestationKey)
+}
+
+// keys returns the live keys matching the glob pattern that keep accepts
+func (p *PostgresConfigStore) keys(ctx context.Context, pattern string, keep func(key string) bool) ([]string, error) {
+	like := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`, "*", "%", "?", "_").Replace(pattern)
+	rows, err := p.db.QueryContext(ctx, `SELECT user_id FROM configs WHERE user_id LIKE $1 AND `+liveRow+` ORDER BY user_id`, like)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys in Postgres: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to list keys in Postgres: %w", err)
+		}
+		if keep(id) {
+			ids = append(ids, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list keys in Postgres: %w", err)
+	}
+	return ids, nil
+}
+
+// Ping checks that Postgres is reachable
+func (p *PostgresConfigStore) Ping(ctx context.Context) error {
+	return p.db.PingContext(ctx)
+}
+
+// Close closes the connection pool
+func (p *PostgresConfigStore) Close() error {
+	return p.db.Close()
+}
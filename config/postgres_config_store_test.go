@@ -0,0 +1,357 @@
+//go:build integration
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+	"github.com/selfxyz/self/sdk/sdk-go/common"
+)
+
+// These tests need a Postgres database they are free to wipe. Run them with
+// DATABASE_URL=postgres://... go test -tags integration ./config -run Postgres
+
+// newTestPostgresStore connects to DATABASE_URL, applies the schema and empties both tables,
+// so every test starts from nothing the way newTestKVStore does
+func newTestPostgresStore(t *testing.T) *PostgresConfigStore {
+	t.Helper()
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	store, err := NewPostgresConfigStoreFromEnv()
+	if err != nil {
+		t.Fatalf("NewPostgresConfigStoreFromEnv: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	ctx := context.Background()
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if _, err := store.db.ExecContext(ctx, `TRUNCATE configs, audit_log`); err != nil {
+		t.Fatalf("failed to empty the tables: %v", err)
+	}
+	return store
+}
+
+// sameJSON reports whether a and b encode the same value; jsonb doesn't keep the text as written
+func sameJSON(t *testing.T, a, b string) bool {
+	t.Helper()
+	var va, vb interface{}
+	if err := json.Unmarshal([]byte(a), &va); err != nil {
+		t.Fatalf("%q is not JSON: %v", a, err)
+	}
+	if err := json.Unmarshal([]byte(b), &vb); err != nil {
+		t.Fatalf("%q is not JSON: %v", b, err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func TestPostgresConfigStoreMigrateTwice(t *testing.T) {
+	store := newTestPostgresStore(t)
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Errorf("second Migrate: %v", err)
+	}
+}
+
+func TestPostgresConfigStoreRoundTrip(t *testing.T) {
+	store := newTestPostgresStore(t)
+	ctx := context.Background()
+	want := self.VerificationConfig{
+		MinimumAge:        intPtr(21),
+		ExcludedCountries: []common.Country3LetterCode{common.RUS, common.IRN},
+		Ofac:              boolPtr(true),
+	}
+
+	created, err := store.SetConfig(ctx, "user-1", want)
+	if err != nil || !created {
+		t.Fatalf("first SetConfig = %v, %v, want created", created, err)
+	}
+	created, err = store.SetConfig(ctx, "user-1", want)
+	if err != nil || created {
+		t.Fatalf("second SetConfig = %v, %v, want updated", created, err)
+	}
+
+	got, err := store.GetConfig(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetConfig = %+v, want %+v", got, want)
+	}
+}
+
+func TestPostgresConfigStoreDefaultsOnMiss(t *testing.T) {
+	store := newTestPostgresStore(t)
+	ctx := context.Background()
+
+	got, err := store.GetConfig(ctx, "missing")
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	if want := DefaultVerificationConfig(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetConfig = %+v, want defaults %+v", got, want)
+	}
+
+	options, err := store.GetDisclosureConfig(ctx, "missing")
+	if err != nil {
+		t.Fatalf("GetDisclosureConfig: %v", err)
+	}
+	if want := DefaultDisclosureConfig(); !reflect.DeepEqual(options, want) {
+		t.Errorf("GetDisclosureConfig = %+v, want defaults %+v", options, want)
+	}
+
+	// sql.ErrNoRows is a miss, not an error
+	value, ok, err := store.GetValue(ctx, "missing")
+	if err != nil || ok || value != "" {
+		t.Errorf("GetValue = %q, %v, %v, want a clean miss", value, ok, err)
+	}
+}
+
+func TestPostgresConfigStoreExpiry(t *testing.T) {
+	store := newTestPostgresStore(t)
+	ctx := context.Background()
+
+	if err := store.SetWithExpiration(ctx, "user-1", `{"minimumAge":30}`, time.Second); err != nil {
+		t.Fatalf("SetWithExpiration: %v", err)
+	}
+	if got, _ := store.GetConfig(ctx, "user-1"); got.MinimumAge == nil || *got.MinimumAge != 30 {
+		t.Fatalf("GetConfig before expiry = %+v, want minimumAge 30", got)
+	}
+
+	// Expiry is checked against the database clock, so this can't be faked the way miniredis allows
+	time.Sleep(1500 * time.Millisecond)
+	if _, ok, err := store.GetValue(ctx, "user-1"); ok || err != nil {
+		t.Errorf("GetValue after expiry = %v, %v, want a miss", ok, err)
+	}
+	got, err := store.GetConfig(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("GetConfig after expiry: %v", err)
+	}
+	if want := DefaultVerificationConfig(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetConfig after expiry = %+v, want defaults", got)
+	}
+	if ids, err := store.ListIDs(ctx, "*"); err != nil || len(ids) != 0 {
+		t.Errorf("ListIDs after expiry = %v, %v, want none", ids, err)
+	}
+}
+
+func TestPostgresConfigStoreTakeValue(t *testing.T) {
+	store := newTestPostgresStore(t)
+	ctx := context.Background()
+
+	if err := store.SetWithExpiration(ctx, StreamTokenPrefix+"abc", `{"proof":{}}`, time.Minute); err != nil {
+		t.Fatalf("SetWithExpiration: %v", err)
+	}
+	value, ok, err := store.TakeValue(ctx, StreamTokenPrefix+"abc")
+	if err != nil || !ok || !sameJSON(t, value, `{"proof":{}}`) {
+		t.Fatalf("first TakeValue = %q, %v, %v, want the stored value", value, ok, err)
+	}
+	// A second take, as a replayed stream token would make, finds nothing
+	if value, ok, err := store.TakeValue(ctx, StreamTokenPrefix+"abc"); ok || err != nil {
+		t.Errorf("second TakeValue = %q, %v, %v, want a miss", value, ok, err)
+	}
+
+	// An expired value is deleted but not returned
+	if err := store.SetWithExpiration(ctx, StreamTokenPrefix+"old", `{"proof":{}}`, time.Millisecond); err != nil {
+		t.Fatalf("SetWithExpiration: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if value, ok, err := store.TakeValue(ctx, StreamTokenPrefix+"old"); ok || err != nil {
+		t.Errorf("TakeValue of an expired value = %q, %v, %v, want a miss", value, ok, err)
+	}
+}
+
+func TestPostgresConfigStoreSaveOptions(t *testing.T) {
+	store := newTestPostgresStore(t)
+	ctx := context.Background()
+	version := func() int64 {
+		t.Helper()
+		v, found, err := store.ConfigVersion(ctx, "user-1")
+		if err != nil || !found {
+			t.Fatalf("ConfigVersion = %d, %v, %v, want a stored version", v, found, err)
+		}
+		return v
+	}
+
+	for i, options := range []string{`{"minimumAge":18}`, `{"minimumAge":21}`} {
+		stored, err := store.SaveOptions(ctx, "user-1", options, time.Second, false)
+		if err != nil || !stored {
+			t.Fatalf("SaveOptions = %v, %v, want stored", stored, err)
+		}
+		if got := version(); got != int64(i+1) {
+			t.Errorf("version after save %d = %d, want %d", i+1, got, i+1)
+		}
+	}
+
+	// onlyIfAbsent leaves live options, and their version, alone
+	stored, err := store.SaveOptions(ctx, "user-1", `{"minimumAge":30}`, time.Second, true)
+	if err != nil || stored {
+		t.Fatalf("SaveOptions onlyIfAbsent over live options = %v, %v, want not stored", stored, err)
+	}
+	if got, _ := store.GetConfig(ctx, "user-1"); *got.MinimumAge != 21 || version() != 2 {
+		t.Errorf("options changed to %+v at version %d", got, version())
+	}
+
+	// Once they expire the version continues from where it was, so it never repeats
+	time.Sleep(1500 * time.Millisecond)
+	if stored, err := store.SaveOptions(ctx, "user-1", `{"minimumAge":30}`, time.Minute, true); err != nil || !stored {
+		t.Fatalf("SaveOptions onlyIfAbsent after expiry = %v, %v, want stored", stored, err)
+	}
+	if got := version(); got != 3 {
+		t.Errorf("version after expiry = %d, want 3", got)
+	}
+	options, err := store.GetDisclosureConfig(ctx, "user-1")
+	if err != nil || options.MinimumAge == nil || *options.MinimumAge != 30 {
+		t.Errorf("GetDisclosureConfig = %+v, %v, want minimumAge 30", options, err)
+	}
+}
+
+func TestPostgresConfigStoreVersions(t *testing.T) {
+	store := newTestPostgresStore(t)
+	ctx := context.Background()
+	older := self.VerificationConfig{MinimumAge: intPtr(18)}
+	newer := self.VerificationConfig{MinimumAge: intPtr(21)}
+
+	steps := []struct {
+		name   string
+		config self.VerificationConfig
+		want   SetConfigResult
+	}{
+		{"create", older, SetConfigResult{Created: true, Changed: true, Version: 1}},
+		{"identical retry", older, SetConfigResult{Version: 1}},
+		{"change", newer, SetConfigResult{Changed: true, Version: 2}},
+		{"change back", older, SetConfigResult{Changed: true, Version: 3}},
+	}
+	for _, step := range steps {
+		got, err := store.SetConfigWithResult(ctx, "user-1", step.config)
+		if err != nil {
+			t.Fatalf("%s: SetConfigWithResult: %v", step.name, err)
+		}
+		if got != step.want {
+			t.Errorf("%s: SetConfigWithResult = %+v, want %+v", step.name, got, step.want)
+		}
+	}
+
+	version, found, err := store.ConfigVersion(ctx, "user-1")
+	if err != nil || !found || version != 3 {
+		t.Errorf("ConfigVersion = %d, %v, %v, want 3, true", version, found, err)
+	}
+	version, found, err = store.ConfigVersion(ctx, "missing")
+	if err != nil || found || version != 0 {
+		t.Errorf("ConfigVersion(missing) = %d, %v, %v, want 0, false", version, found, err)
+	}
+}
+
+func TestPostgresConfigStoreListIDs(t *testing.T) {
+	store := newTestPostgresStore(t)
+	ctx := context.Background()
+	for _, id := range []string{"user-1", "user-2", "user_3"} {
+		if _, err := store.SetConfig(ctx, id, DefaultVerificationConfig()); err != nil {
+			t.Fatalf("SetConfig(%s): %v", id, err)
+		}
+	}
+	for _, key := range []string{
+		ResultCachePrefix + "abc",
+		"tenant:acme:" + StreamTokenPrefix + "def",
+		AttestationConfigKey("1", "user-1"),
+		AttestationConfigKey("1", AttestationDefaultID),
+	} {
+		if err := store.SetWithExpiration(ctx, key, "{}", time.Minute); err != nil {
+			t.Fatalf("SetWithExpiration(%s): %v", key, err)
+		}
+	}
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*", []string{"user-1", "user-2", "user_3"}},
+		{"user-?", []string{"user-1", "user-2"}},
+		// LIKE wildcards in the pattern are matched literally
+		{"user_*", []string{"user_3"}},
+		{"nobody*", nil},
+	}
+	for _, tt := range tests {
+		got, err := store.ListIDs(ctx, tt.pattern)
+		if err != nil {
+			t.Fatalf("ListIDs(%q): %v", tt.pattern, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ListIDs(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+
+	got, err := store.ListAttestationKeys(ctx)
+	if err != nil {
+		t.Fatalf("ListAttestationKeys: %v", err)
+	}
+	if want := []string{"attestation:1:default", "attestation:1:user-1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListAttestationKeys = %v, want %v", got, want)
+	}
+}
+
+func TestPostgresConfigStoreUpdateConfig(t *testing.T) {
+	store := newTestPostgresStore(t)
+	ctx := context.Background()
+	if _, err := store.SetConfig(ctx, "user-1", self.VerificationConfig{MinimumAge: intPtr(21), Ofac: boolPtr(true)}); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+
+	merged, err := store.UpdateConfig(ctx, "user-1", SelfAppDisclosureConfig{Name: boolPtr(true)})
+	if err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	if merged.Name == nil || !*merged.Name || merged.MinimumAge == nil || *merged.MinimumAge != 21 {
+		t.Errorf("UpdateConfig = %+v, want name added and minimumAge 21 kept", merged)
+	}
+	if merged.SavedAt == nil {
+		t.Error("UpdateConfig didn't stamp savedAt")
+	}
+	if version, _, _ := store.ConfigVersion(ctx, "user-1"); version != 2 {
+		t.Errorf("ConfigVersion after UpdateConfig = %d, want 2", version)
+	}
+
+	// Patching an ID with nothing stored starts from the defaults
+	created, err := store.UpdateConfig(ctx, "user-2", SelfAppDisclosureConfig{Gender: boolPtr(true)})
+	if err != nil {
+		t.Fatalf("UpdateConfig on a missing ID: %v", err)
+	}
+	defaults := DefaultVerificationConfig()
+	if !reflect.DeepEqual(created.MinimumAge, defaults.MinimumAge) || created.Gender == nil || !*created.Gender {
+		t.Errorf("UpdateConfig on a missing ID = %+v, want the defaults with gender added", created)
+	}
+}
+
+func TestPostgresConfigStoreAudit(t *testing.T) {
+	store := newTestPostgresStore(t)
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		entry := AuditEntry{UserID: "user-1", AttestationID: "1", Result: i%2 == 0, RequestID: string(rune('a' + i))}
+		if err := store.AppendAudit(ctx, "user-1", entry, 3); err != nil {
+			t.Fatalf("AppendAudit: %v", err)
+		}
+	}
+	if err := store.AppendAudit(ctx, "user-2", AuditEntry{UserID: "user-2", RequestID: "z"}, 3); err != nil {
+		t.Fatalf("AppendAudit: %v", err)
+	}
+
+	entries, err := store.ReadAudit(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("ReadAudit: %v", err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.RequestID)
+	}
+	// Newest first, trimmed to max, and only the user's own entries
+	if want := []string{"e", "d", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadAudit request IDs = %v, want %v", got, want)
+	}
+}
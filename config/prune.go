@@ -0,0 +1,159 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PruneResult counts what one Prune call removed
+type PruneResult struct {
+	// Options is the number of expired options removed; Redis expires them itself and reports 0
+	Options int `json:"options"`
+	// AuditEntries is the number of audit entries older than the retention window removed
+	AuditEntries int `json:"auditEntries"`
+}
+
+// Pruner is implemented by stores that can drop stale per-user data. Stored configs have no
+// expiry and are never pruned; only options saved with an expiration and audit entries are
+type Pruner interface {
+	Prune(ctx context.Context, retention time.Duration) (PruneResult, error)
+}
+
+// auditScanPattern matches every audit list, tenant-scoped ones included
+const auditScanPattern = "audit:*"
+
+// Prune trims audit entries older than retention from every audit list, deleting lists that
+// end up empty. Each remaining list gets a TTL of its newest entry plus retention, so an idle
+// list expires on its own even if pruning stops. Saved options already carry a TTL
+func (kv *KVConfigStore) Prune(ctx context.Context, retention time.Duration) (PruneResult, error) {
+	var result PruneResult
+	cutoff := time.Now().Add(-retention)
+	client := kv.redisClient()
+	iter := client.Scan(ctx, 0, auditScanPattern, 100).Iterator()
+	for iter.Next(ctx) {
+		removed, err := kv.pruneAuditList(ctx, client, iter.Val(), cutoff, retention)
+		if err != nil {
+			return result, err
+		}
+		result.AuditEntries += removed
+	}
+	if err := iter.Err(); err != nil {
+		kv.noteConnError(ctx, client, err)
+		return result, fmt.Errorf("failed to scan audit logs in Redis: %w", err)
+	}
+	return result, nil
+}
+
+// pruneAuditList trims one list under WATCH, so an entry appended meanwhile is never cut
+func (kv *KVConfigStore) pruneAuditList(ctx context.Context, client *redis.Client, key string, cutoff time.Time, retention time.Duration) (int, error) {
+	var removed int
+	prune := func(tx *redis.Tx) error {
+		raw, err := tx.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+		// Entries are newest first, so everything from the first stale entry on goes
+		keep := len(raw)
+		var newest time.Time
+		for i, item := range raw {
+			var entry AuditEntry
+			if err := json.Unmarshal([]byte(item), &entry); err != nil {
+				return fmt.Errorf("failed to unmarshal audit entry in %s: %w", key, err)
+			}
+			if i == 0 {
+				newest = entry.Timestamp
+			}
+			if entry.Timestamp.Before(cutoff) {
+				keep = i
+				break
+			}
+		}
+		removed = len(raw) - keep
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if keep == 0 {
+				pipe.Del(ctx, key)
+				return nil
+			}
+			if removed > 0 {
+				pipe.LTrim(ctx, key, 0, int64(keep-1))
+			}
+			pipe.ExpireAt(ctx, key, newest.Add(retention))
+			return nil
+		})
+		return err
+	}
+
+	for attempt := 0; attempt < updateAttempts; attempt++ {
+		err := client.Watch(ctx, prune, key)
+		if err == redis.TxFailedErr {
+			continue
+		}
+		if err != nil {
+			kv.noteConnError(ctx, client, err)
+			return 0, fmt.Errorf("failed to prune audit log %s in Redis: %w", key, err)
+		}
+		return removed, nil
+	}
+	// A busy list is simply left for the next run
+	return 0, nil
+}
+
+// Prune drops expired values and audit entries older than retention
+func (m *MemoryConfigStore) Prune(ctx context.Context, retention time.Duration) (PruneResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result PruneResult
+	now := time.Now()
+	for key, v := range m.values {
+		if !v.expiresAt.IsZero() && now.After(v.expiresAt) {
+			delete(m.values, key)
+			result.Options++
+		}
+	}
+	cutoff := now.Add(-retention)
+	for userID, entries := range m.audit {
+		keep := len(entries)
+		for i, entry := range entries {
+			if entry.Timestamp.Before(cutoff) {
+				keep = i
+				break
+			}
+		}
+		result.AuditEntries += len(entries) - keep
+		if keep == 0 {
+			delete(m.audit, userID)
+		} else {
+			m.audit[userID] = entries[:keep]
+		}
+	}
+	return result, nil
+}
+
+// Prune deletes expired rows, which liveRow already hides from every read, and audit
+// entries older than retention
+func (p *PostgresConfigStore) Prune(ctx context.Context, retention time.Duration) (PruneResult, error) {
+	var result PruneResult
+	res, err := p.db.ExecContext(ctx, `DELETE FROM configs WHERE NOT `+liveRow)
+	if err != nil {
+		return result, fmt.Errorf("failed to prune expired options in Postgres: %w", err)
+	}
+	options, _ := res.RowsAffected()
+	result.Options = int(options)
+
+	res, err = p.db.ExecContext(ctx, `DELETE FROM audit_log WHERE created_at < $1`, time.Now().Add(-retention))
+	if err != nil {
+		return result, fmt.Errorf("failed to prune audit log in Postgres: %w", err)
+	}
+	entries, _ := res.RowsAffected()
+	result.AuditEntries = int(entries)
+	return result, nil
+}
+
+// String summarizes r for logs
+func (r PruneResult) String() string {
+	return fmt.Sprintf("options=%d auditEntries=%d", r.Options, r.AuditEntries)
+}
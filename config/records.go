@@ -0,0 +1,55 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Record is one line of the newline-delimited JSON used to export and import configs
+type Record struct {
+	ID string `json:"id"`
+	// Config is the value stored under ID, copied as-is so saved disclosure options and
+	// savedAt survive a round trip. A plain verification config is accepted as well
+	Config json.RawMessage `json:"config"`
+}
+
+// DecodeRecord strictly decodes a single NDJSON line and checks it is safe to store
+// The returned Config is compacted, matching how stores serialize values
+func DecodeRecord(line []byte) (Record, error) {
+	var record Record
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&record); err != nil {
+		return Record{}, err
+	}
+
+	if record.ID == "" {
+		return Record{}, fmt.Errorf("id is required")
+	}
+	if isInternalKey(record.ID) {
+		return Record{}, fmt.Errorf("id %q is not a config key", record.ID)
+	}
+	trimmed := bytes.TrimSpace(record.Config)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return Record{}, fmt.Errorf("config is required")
+	}
+	if trimmed[0] != '{' {
+		return Record{}, fmt.Errorf("config must be an object")
+	}
+	// Saved options may carry fields added by clients, so unknown fields are kept rather than rejected
+	var options SelfAppDisclosureConfig
+	if err := json.Unmarshal(trimmed, &options); err != nil {
+		return Record{}, err
+	}
+	if errs := ValidateDisclosureConfig(options); len(errs) > 0 {
+		return Record{}, &ValidationError{Errors: errs}
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, trimmed); err != nil {
+		return Record{}, err
+	}
+	record.Config = compact.Bytes()
+	return record, nil
+}
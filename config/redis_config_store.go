@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -25,12 +26,36 @@ type SelfAppDisclosureConfig struct {
 	Ofac              *bool                       `json:"ofac,omitempty"`
 	ExcludedCountries []common.Country3LetterCode `json:"excludedCountries,omitempty"`
 	MinimumAge        *int                        `json:"minimumAge,omitempty"`
+	// NationalityFormat selects how a disclosed nationality is returned: "name", "iso3",
+	// or empty for the value as disclosed
+	NationalityFormat string `json:"nationality_format,omitempty"`
+	// ExcludedCountriesFormat selects how excluded countries are returned: "code" (default),
+	// "name", or "both" for {code, name} objects
+	ExcludedCountriesFormat string `json:"excludedCountriesFormat,omitempty"`
+	// DateOfBirthFormat selects how a disclosed date of birth is returned: "date" (default),
+	// "age" for the age in years only, or "both"
+	DateOfBirthFormat string `json:"date_of_birth_format,omitempty"`
+	// AgeGatedDisclosure withholds every field when MinimumAge is set and the age check fails
+	AgeGatedDisclosure *bool `json:"age_gated_disclosure,omitempty"`
+	// OmitUndisclosed leaves withheld fields out of credentialSubject instead of
+	// setting them to "Not disclosed"
+	OmitUndisclosed *bool `json:"omit_undisclosed,omitempty"`
+	// SavedAt is when saveOptions or UpdateConfig last wrote these options; the server sets
+	// it, and options saved before it was recorded have none
+	SavedAt *time.Time `json:"savedAt,omitempty"`
 }
 
 // KVConfigStore implements a Redis-based configuration store for Self verification
 // This is the Go equivalent of the TypeScript KVConfigStore class
+// A client whose connection drops is replaced on the next operation, see withReconnect
 type KVConfigStore struct {
-	redis *redis.Client
+	mu                sync.RWMutex
+	client            *redis.Client
+	options           *redis.Options
+	reconnectAttempts int
+	actionIds         ActionIdStrategy
+	// cipher encrypts values at rest when CONFIG_ENCRYPTION_KEY is set; nil stores plaintext
+	cipher *valueCipher
 }
 
 // NewKVConfigStore creates a new Redis-based config store
@@ -48,17 +73,32 @@ func NewKVConfigStore(redisURL, redisToken string) (*KVConfigStore, error) {
 		opt.Password = redisToken
 	}
 
+	applyRedisTimeouts(opt)
+	valueCipher, err := cipherFromEnv()
+	if err != nil {
+		return nil, err
+	}
 	client := redis.NewClient(opt)
 
-	// Test the connection
-	ctx := context.Background()
-	_, err = client.Ping(ctx).Result()
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	if lazyRedisConnect() {
+		// go-redis dials on first use anyway; warming the pool in the background lets a
+		// cold start overlap the dial with request parsing instead of waiting on it
+		go warmRedis(client)
+	} else {
+		// Test the connection; the dial and read timeouts bound how long this can take
+		ctx := context.Background()
+		_, err = client.Ping(ctx).Result()
+		if err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+		}
 	}
 
 	return &KVConfigStore{
-		redis: client,
+		client:            client,
+		options:           opt,
+		reconnectAttempts: redisReconnectAttempts(),
+		cipher:            valueCipher,
 	}, nil
 }
 
@@ -79,43 +119,204 @@ func NewKVConfigStoreFromEnv() (*KVConfigStore, error) {
 }
 
 func (kv *KVConfigStore) GetActionId(ctx context.Context, userIdentifier string, userDefinedData string) (string, error) {
-	return userIdentifier, nil
+	return actionIdOrIdentity(kv.actionIds).ActionId(userIdentifier, userDefinedData), nil
 }
 
+// SetConfig stores config under id and reports whether it was newly created
 func (kv *KVConfigStore) SetConfig(ctx context.Context, id string, config self.VerificationConfig) (bool, error) {
+	result, err := kv.SetConfigWithResult(ctx, id, config)
+	return result.Created, err
+}
+
+// checkVersionCounter fails unless the counter at key is missing or an integer. The config
+// and its version are written in one MULTI/EXEC, but Redis doesn't roll back the SET when
+// the INCR after it fails, so the one command that can fail once queued is ruled out under
+// WATCH before the transaction starts
+func checkVersionCounter(ctx context.Context, tx *redis.Tx, key string) error {
+	if _, err := tx.Get(ctx, key).Int64(); err != nil && err != redis.Nil {
+		return fmt.Errorf("version counter %s is unusable, nothing was written: %w", key, err)
+	}
+	return nil
+}
+
+// SetConfigWithResult writes config and bumps its version unless the stored JSON is
+// already identical, so a retried request doesn't report a second change. The content
+// is compared against what is actually stored, which UpdateConfig and saveOptions also write
+func (kv *KVConfigStore) SetConfigWithResult(ctx context.Context, id string, config self.VerificationConfig) (SetConfigResult, error) {
 	// Serialize the config to JSON, just like the TypeScript version: JSON.stringify(config)
-	configJSON, err := json.Marshal(config)
+	configJSON, err := encodeConfig(config)
 	if err != nil {
-		return false, fmt.Errorf("failed to marshal config: %w", err)
+		return SetConfigResult{}, err
 	}
+	return kv.SetRawConfig(ctx, id, configJSON)
+}
 
-	err = kv.redis.Set(ctx, id, string(configJSON), 0).Err()
-	if err != nil {
-		return false, fmt.Errorf("failed to set config in Redis: %w", err)
+// SetRawConfig writes configJSON as SetConfigWithResult would, bumping the version unless
+// the same JSON is already stored
+func (kv *KVConfigStore) SetRawConfig(ctx context.Context, id string, configJSON string) (SetConfigResult, error) {
+	versionKey := configVersionKey(id)
+	var result SetConfigResult
+	set := func(tx *redis.Tx) error {
+		stored, err := tx.Get(ctx, id).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		result = SetConfigResult{Created: err == redis.Nil}
+		// Compare plaintext: sealing uses a fresh nonce, so ciphertexts never match
+		if !result.Created {
+			if stored, err = kv.cipher.open(id, stored); err != nil {
+				return err
+			}
+		}
+		if !result.Created && stored == configJSON {
+			result.Version, err = tx.Get(ctx, versionKey).Int64()
+			if err == redis.Nil {
+				err = nil
+			}
+			return err
+		}
+
+		sealed, err := kv.cipher.seal(id, configJSON)
+		if err != nil {
+			return err
+		}
+		if err := checkVersionCounter(ctx, tx, versionKey); err != nil {
+			return err
+		}
+		var version *redis.IntCmd
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, id, sealed, 0)
+			version = pipe.Incr(ctx, versionKey)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		result.Changed = true
+		result.Version = version.Val()
+		return nil
 	}
 
-	return true, nil
+	client := kv.redisClient()
+	for attempt := 0; attempt < updateAttempts; attempt++ {
+		err := client.Watch(ctx, set, id, versionKey)
+		if err == redis.TxFailedErr {
+			continue
+		}
+		if err != nil {
+			kv.noteConnError(ctx, client, err)
+			return SetConfigResult{}, fmt.Errorf("failed to set config in Redis: %w", err)
+		}
+		return result, nil
+	}
+	return SetConfigResult{}, ErrConflict
 }
 
 // SetWithExpiration stores a key-value pair with expiration, matching TypeScript kv.set(key, value, { ex: seconds })
 func (kv *KVConfigStore) SetWithExpiration(ctx context.Context, key string, value string, expiration time.Duration) error {
-	err := kv.redis.Set(ctx, key, value, expiration).Err()
+	sealed, err := kv.cipher.seal(key, value)
+	if err != nil {
+		return err
+	}
+	err = kv.withReconnect(ctx, func(client *redis.Client) error {
+		return client.Set(ctx, key, sealed, expiration).Err()
+	})
 	if err != nil {
 		return fmt.Errorf("failed to set key with expiration in Redis: %w", err)
 	}
 	return nil
 }
 
+// SaveOptions writes the options and bumps their version in one MULTI/EXEC, watching both
+// keys so onlyIfAbsent's check and the write can't be split by another writer. A dropped
+// connection isn't retried, since the first attempt may already have been applied
+func (kv *KVConfigStore) SaveOptions(ctx context.Context, id string, optionsJSON string, expiration time.Duration, onlyIfAbsent bool) (bool, error) {
+	sealed, err := kv.cipher.seal(id, optionsJSON)
+	if err != nil {
+		return false, err
+	}
+	versionKey := configVersionKey(id)
+	var stored bool
+	save := func(tx *redis.Tx) error {
+		stored = false
+		if onlyIfAbsent {
+			n, err := tx.Exists(ctx, id).Result()
+			if err != nil || n > 0 {
+				return err
+			}
+		}
+		if err := checkVersionCounter(ctx, tx, versionKey); err != nil {
+			return err
+		}
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, id, sealed, expiration)
+			pipe.Incr(ctx, versionKey)
+			return nil
+		})
+		stored = err == nil
+		return err
+	}
+
+	client := kv.redisClient()
+	for attempt := 0; attempt < updateAttempts; attempt++ {
+		err := client.Watch(ctx, save, id, versionKey)
+		if err == redis.TxFailedErr {
+			continue
+		}
+		if err != nil {
+			kv.noteConnError(ctx, client, err)
+			return false, fmt.Errorf("failed to save options to Redis: %w", err)
+		}
+		return stored, nil
+	}
+	return false, ErrConflict
+}
+
+// GetValue reads a raw value from Redis
+func (kv *KVConfigStore) GetValue(ctx context.Context, key string) (string, bool, error) {
+	var value string
+	err := kv.withReconnect(ctx, func(client *redis.Client) (err error) {
+		value, err = client.Get(ctx, key).Result()
+		return err
+	})
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get key from Redis: %w", err)
+	}
+	if value, err = kv.cipher.open(key, value); err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// TakeValue reads and deletes a raw value with GETDEL. A dropped connection isn't retried,
+// since the first attempt may already have consumed the value
+func (kv *KVConfigStore) TakeValue(ctx context.Context, key string) (string, bool, error) {
+	client := kv.redisClient()
+	value, err := client.GetDel(ctx, key).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		kv.noteConnError(ctx, client, err)
+		return "", false, fmt.Errorf("failed to take key from Redis: %w", err)
+	}
+	if value, err = kv.cipher.open(key, value); err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
 func (kv *KVConfigStore) GetConfig(ctx context.Context, id string) (self.VerificationConfig, error) {
-	// Get from Redis - this matches: await this.redis.get(id)
-	configJSON, err := kv.redis.Get(ctx, id).Result()
+	// Get from Redis - this matches: await this.redis.get(id), plus the per-attestation
+	// precedence described on configKeys when the context carries an attestation
+	configJSON, err := kv.lookup(ctx, id)
 	if err != nil {
 		if err == redis.Nil {
 			// Key doesn't exist - return default config
-			return self.VerificationConfig{
-				MinimumAge: &[]int{18}[0],
-				Ofac:       &[]bool{true}[0],
-			}, nil
+			return DefaultVerificationConfig(), nil
 		}
 		return self.VerificationConfig{}, fmt.Errorf("failed to get config from Redis: %w", err)
 	}
@@ -129,7 +330,114 @@ func (kv *KVConfigStore) GetConfig(ctx context.Context, id string) (self.Verific
 	return config, nil
 }
 
+// UpdateConfig merges patch into the config stored under id and returns the result
+// The read-modify-write runs under WATCH, so a concurrent write makes it retry instead
+// of being silently overwritten. An existing expiry on the key is kept
+func (kv *KVConfigStore) UpdateConfig(ctx context.Context, id string, patch SelfAppDisclosureConfig) (SelfAppDisclosureConfig, error) {
+	var merged SelfAppDisclosureConfig
+	update := func(tx *redis.Tx) error {
+		stored, err := tx.Get(ctx, id).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if stored, err = kv.cipher.open(id, stored); err != nil {
+			return err
+		}
+		merged, err = mergeStoredConfig(stored, patch)
+		if err != nil {
+			return err
+		}
+		mergedJSON, err := json.Marshal(merged)
+		if err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+		sealed, err := kv.cipher.seal(id, string(mergedJSON))
+		if err != nil {
+			return err
+		}
+		if err := checkVersionCounter(ctx, tx, configVersionKey(id)); err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetArgs(ctx, id, sealed, redis.SetArgs{KeepTTL: true})
+			pipe.Incr(ctx, configVersionKey(id))
+			return nil
+		})
+		return err
+	}
+
+	client := kv.redisClient()
+	for attempt := 0; attempt < updateAttempts; attempt++ {
+		err := client.Watch(ctx, update, id, configVersionKey(id))
+		if err == redis.TxFailedErr {
+			continue
+		}
+		if err != nil {
+			kv.noteConnError(ctx, client, err)
+			return SelfAppDisclosureConfig{}, fmt.Errorf("failed to update config in Redis: %w", err)
+		}
+		return merged, nil
+	}
+	return SelfAppDisclosureConfig{}, ErrConflict
+}
+
+// GetDisclosureConfig reads the options saved for id, including the disclosure flags
+// GetConfig drops; a missing key yields the default config with nothing disclosed
+func (kv *KVConfigStore) GetDisclosureConfig(ctx context.Context, id string) (SelfAppDisclosureConfig, error) {
+	optionsJSON, err := kv.lookup(ctx, id)
+	if err != nil {
+		if err == redis.Nil {
+			return DefaultDisclosureConfig(), nil
+		}
+		return SelfAppDisclosureConfig{}, fmt.Errorf("failed to get options from Redis: %w", err)
+	}
+
+	var options SelfAppDisclosureConfig
+	if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+		return SelfAppDisclosureConfig{}, fmt.Errorf("failed to unmarshal options: %w", err)
+	}
+	return options, nil
+}
+
+// ListIDs returns every config key matching pattern, using SCAN so large keyspaces don't block Redis.
+// Keys that hold something else, such as version counters, audit logs and cached results,
+// are left out, as are per-attestation configs; see ListAttestationKeys
+func (kv *KVConfigStore) ListIDs(ctx context.Context, pattern string) ([]string, error) {
+	return kv.scanKeys(ctx, pattern, isUserConfigKey)
+}
+
+// ListAttestationKeys returns the keys of every per-attestation config
+func (kv *KVConfigStore) ListAttestationKeys(ctx context.Context) ([]string, error) {
+	return kv.scanKeys(ctx, attestationPrefix+"*", isAttestationKey)
+}
+
+// scanKeys returns the keys matching pattern that keep accepts
+func (kv *KVConfigStore) scanKeys(ctx context.Context, pattern string, keep func(key string) bool) ([]string, error) {
+	var ids []string
+	err := kv.withReconnect(ctx, func(client *redis.Client) error {
+		ids = nil
+		iter := client.Scan(ctx, 0, pattern, 100).Iterator()
+		for iter.Next(ctx) {
+			if keep(iter.Val()) {
+				ids = append(ids, iter.Val())
+			}
+		}
+		return iter.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan keys in Redis: %w", err)
+	}
+	return ids, nil
+}
+
+// Ping checks that Redis is reachable
+func (kv *KVConfigStore) Ping(ctx context.Context) error {
+	return kv.withReconnect(ctx, func(client *redis.Client) error {
+		return client.Ping(ctx).Err()
+	})
+}
+
 // Close closes the Redis connection
 func (kv *KVConfigStore) Close() error {
-	return kv.redis.Close()
+	return kv.redisClient().Close()
 }
@@ -0,0 +1,392 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	self "github.com/selfxyz/self/sdk/sdk-go"
+	"github.com/selfxyz/self/sdk/sdk-go/common"
+)
+
+// newTestKVStore points a KVConfigStore at a fresh in-memory Redis. Both are closed when
+// the test ends; the *miniredis.Miniredis is returned so tests can inspect raw keys or
+// move its clock
+func newTestKVStore(t *testing.T) (*KVConfigStore, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	store, err := NewKVConfigStore("redis://"+mr.Addr(), "")
+	if err != nil {
+		t.Fatalf("NewKVConfigStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, mr
+}
+
+func intPtr(v int) *int    { return &v }
+func boolPtr(v bool) *bool { return &v }
+
+func TestKVConfigStoreRoundTrip(t *testing.T) {
+	store, _ := newTestKVStore(t)
+	ctx := context.Background()
+	want := self.VerificationConfig{
+		MinimumAge:        intPtr(21),
+		ExcludedCountries: []common.Country3LetterCode{common.RUS, common.IRN},
+		Ofac:              boolPtr(true),
+	}
+
+	created, err := store.SetConfig(ctx, "user-1", want)
+	if err != nil || !created {
+		t.Fatalf("first SetConfig = %v, %v, want created", created, err)
+	}
+	created, err = store.SetConfig(ctx, "user-1", want)
+	if err != nil || created {
+		t.Fatalf("second SetConfig = %v, %v, want updated", created, err)
+	}
+
+	got, err := store.GetConfig(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetConfig = %+v, want %+v", got, want)
+	}
+}
+
+func TestKVConfigStoreDefaultsOnMiss(t *testing.T) {
+	store, _ := newTestKVStore(t)
+	ctx := context.Background()
+
+	got, err := store.GetConfig(ctx, "missing")
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	if want := DefaultVerificationConfig(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetConfig = %+v, want defaults %+v", got, want)
+	}
+
+	options, err := store.GetDisclosureConfig(ctx, "missing")
+	if err != nil {
+		t.Fatalf("GetDisclosureConfig: %v", err)
+	}
+	if want := DefaultDisclosureConfig(); !reflect.DeepEqual(options, want) {
+		t.Errorf("GetDisclosureConfig = %+v, want defaults %+v", options, want)
+	}
+
+	// redis.Nil is a miss, not an error
+	value, ok, err := store.GetValue(ctx, "missing")
+	if err != nil || ok || value != "" {
+		t.Errorf("GetValue = %q, %v, %v, want a clean miss", value, ok, err)
+	}
+}
+
+func TestKVConfigStoreExpiry(t *testing.T) {
+	store, mr := newTestKVStore(t)
+	ctx := context.Background()
+
+	if err := store.SetWithExpiration(ctx, "user-1", `{"minimumAge":30}`, 30*time.Minute); err != nil {
+		t.Fatalf("SetWithExpiration: %v", err)
+	}
+	mr.FastForward(29 * time.Minute)
+	if got, _ := store.GetConfig(ctx, "user-1"); got.MinimumAge == nil || *got.MinimumAge != 30 {
+		t.Fatalf("GetConfig before expiry = %+v, want minimumAge 30", got)
+	}
+
+	mr.FastForward(2 * time.Minute)
+	if _, ok, err := store.GetValue(ctx, "user-1"); ok || err != nil {
+		t.Errorf("GetValue after expiry = %v, %v, want a miss", ok, err)
+	}
+	got, err := store.GetConfig(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("GetConfig after expiry: %v", err)
+	}
+	if want := DefaultVerificationConfig(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetConfig after expiry = %+v, want defaults", got)
+	}
+}
+
+func TestKVConfigStoreTakeValue(t *testing.T) {
+	store, mr := newTestKVStore(t)
+	ctx := context.Background()
+
+	if err := store.SetWithExpiration(ctx, StreamTokenPrefix+"abc", `{"proof":{}}`, time.Minute); err != nil {
+		t.Fatalf("SetWithExpiration: %v", err)
+	}
+	value, ok, err := store.TakeValue(ctx, StreamTokenPrefix+"abc")
+	if err != nil || !ok || value != `{"proof":{}}` {
+		t.Fatalf("first TakeValue = %q, %v, %v, want the stored value", value, ok, err)
+	}
+	if mr.Exists(StreamTokenPrefix + "abc") {
+		t.Error("TakeValue left the key in place")
+	}
+	// A second take, as a replayed stream token would make, finds nothing
+	if value, ok, err := store.TakeValue(ctx, StreamTokenPrefix+"abc"); ok || err != nil {
+		t.Errorf("second TakeValue = %q, %v, %v, want a miss", value, ok, err)
+	}
+}
+
+func TestKVConfigStoreSaveOptions(t *testing.T) {
+	store, mr := newTestKVStore(t)
+	ctx := context.Background()
+	version := func() int64 {
+		t.Helper()
+		v, found, err := store.ConfigVersion(ctx, "user-1")
+		if err != nil || !found {
+			t.Fatalf("ConfigVersion = %d, %v, %v, want a stored version", v, found, err)
+		}
+		return v
+	}
+
+	for i, options := range []string{`{"minimumAge":18}`, `{"minimumAge":21}`} {
+		stored, err := store.SaveOptions(ctx, "user-1", options, 30*time.Minute, false)
+		if err != nil || !stored {
+			t.Fatalf("SaveOptions = %v, %v, want stored", stored, err)
+		}
+		if got := version(); got != int64(i+1) {
+			t.Errorf("version after save %d = %d, want %d", i+1, got, i+1)
+		}
+	}
+
+	// onlyIfAbsent leaves live options, and their version, alone
+	stored, err := store.SaveOptions(ctx, "user-1", `{"minimumAge":30}`, 30*time.Minute, true)
+	if err != nil || stored {
+		t.Fatalf("SaveOptions onlyIfAbsent over live options = %v, %v, want not stored", stored, err)
+	}
+	if got, _ := store.GetConfig(ctx, "user-1"); *got.MinimumAge != 21 || version() != 2 {
+		t.Errorf("options changed to %+v at version %d", got, version())
+	}
+
+	// Once they expire the version continues from where it was, so it never repeats
+	mr.FastForward(31 * time.Minute)
+	if stored, err := store.SaveOptions(ctx, "user-1", `{"minimumAge":30}`, 30*time.Minute, true); err != nil || !stored {
+		t.Fatalf("SaveOptions onlyIfAbsent after expiry = %v, %v, want stored", stored, err)
+	}
+	if got := version(); got != 3 {
+		t.Errorf("version after expiry = %d, want 3", got)
+	}
+	if ttl := mr.TTL("user-1"); ttl != 30*time.Minute {
+		t.Errorf("options TTL = %s, want 30m", ttl)
+	}
+}
+
+func TestKVConfigStoreVersions(t *testing.T) {
+	store, _ := newTestKVStore(t)
+	ctx := context.Background()
+	older := self.VerificationConfig{MinimumAge: intPtr(18)}
+	newer := self.VerificationConfig{MinimumAge: intPtr(21)}
+
+	steps := []struct {
+		name   string
+		config self.VerificationConfig
+		want   SetConfigResult
+	}{
+		{"create", older, SetConfigResult{Created: true, Changed: true, Version: 1}},
+		{"identical retry", older, SetConfigResult{Version: 1}},
+		{"change", newer, SetConfigResult{Changed: true, Version: 2}},
+		{"change back", older, SetConfigResult{Changed: true, Version: 3}},
+	}
+	for _, step := range steps {
+		got, err := store.SetConfigWithResult(ctx, "user-1", step.config)
+		if err != nil {
+			t.Fatalf("%s: SetConfigWithResult: %v", step.name, err)
+		}
+		if got != step.want {
+			t.Errorf("%s: SetConfigWithResult = %+v, want %+v", step.name, got, step.want)
+		}
+	}
+
+	version, found, err := store.ConfigVersion(ctx, "user-1")
+	if err != nil || !found || version != 3 {
+		t.Errorf("ConfigVersion = %d, %v, %v, want 3, true", version, found, err)
+	}
+	version, found, err = store.ConfigVersion(ctx, "missing")
+	if err != nil || found || version != 0 {
+		t.Errorf("ConfigVersion(missing) = %d, %v, %v, want 0, false", version, found, err)
+	}
+}
+
+func TestKVConfigStoreListIDs(t *testing.T) {
+	store, mr := newTestKVStore(t)
+	ctx := context.Background()
+	for _, id := range []string{"user-1", "user-2"} {
+		if _, err := store.SetConfig(ctx, id, DefaultVerificationConfig()); err != nil {
+			t.Fatalf("SetConfig(%s): %v", id, err)
+		}
+	}
+	mr.SAdd(userListPrefix+"blocked", "user-3")
+	mr.Set(uniqueUsersPrefix+"2026-01-01T00", "x")
+	mr.Lpush(auditKey("user-1"), "{}")
+	mr.Set(ResultCachePrefix+"abc", "{}")
+	mr.Set("tenant:acme:"+StreamTokenPrefix+"def", "{}")
+	mr.Set(AttestationConfigKey("1", "user-1"), "{}")
+	mr.Set(AttestationConfigKey("1", AttestationDefaultID), "{}")
+
+	got, err := store.ListIDs(ctx, "*")
+	if err != nil {
+		t.Fatalf("ListIDs: %v", err)
+	}
+	sort.Strings(got)
+	if want := []string{"user-1", "user-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListIDs = %v, want %v", got, want)
+	}
+
+	got, err = store.ListAttestationKeys(ctx)
+	if err != nil {
+		t.Fatalf("ListAttestationKeys: %v", err)
+	}
+	sort.Strings(got)
+	if want := []string{"attestation:1:default", "attestation:1:user-1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListAttestationKeys = %v, want %v", got, want)
+	}
+}
+
+func TestKVConfigStoreUpdateConfig(t *testing.T) {
+	store, _ := newTestKVStore(t)
+	ctx := context.Background()
+	if _, err := store.SetConfig(ctx, "user-1", self.VerificationConfig{MinimumAge: intPtr(21), Ofac: boolPtr(true)}); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+
+	merged, err := store.UpdateConfig(ctx, "user-1", SelfAppDisclosureConfig{Name: boolPtr(true)})
+	if err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	if merged.Name == nil || !*merged.Name || merged.MinimumAge == nil || *merged.MinimumAge != 21 {
+		t.Errorf("UpdateConfig = %+v, want name added and minimumAge 21 kept", merged)
+	}
+	if merged.SavedAt == nil {
+		t.Error("UpdateConfig didn't stamp savedAt")
+	}
+	if version, _, _ := store.ConfigVersion(ctx, "user-1"); version != 2 {
+		t.Errorf("ConfigVersion after UpdateConfig = %d, want 2", version)
+	}
+}
+
+// TestKVConfigStoreConcurrentUpdates runs patches to different fields at once. WATCH makes
+// a loser retry or give up with ErrConflict, so every patch that succeeded is in the
+// stored config and the version counts exactly those
+func TestKVConfigStoreConcurrentUpdates(t *testing.T) {
+	store, _ := newTestKVStore(t)
+	ctx := context.Background()
+	patches := map[string]SelfAppDisclosureConfig{
+		"name":        {Name: boolPtr(true)},
+		"nationality": {Nationality: boolPtr(true)},
+		"gender":      {Gender: boolPtr(true)},
+		"dateOfBirth": {DateOfBirth: boolPtr(true)},
+		"expiryDate":  {ExpiryDate: boolPtr(true)},
+	}
+
+	var mu sync.Mutex
+	applied := map[string]bool{}
+	var wg sync.WaitGroup
+	for field, patch := range patches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.UpdateConfig(ctx, "user-1", patch)
+			if err != nil && !errors.Is(err, ErrConflict) {
+				t.Errorf("UpdateConfig(%s): %v", field, err)
+				return
+			}
+			mu.Lock()
+			applied[field] = err == nil
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	stored, err := store.GetDisclosureConfig(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("GetDisclosureConfig: %v", err)
+	}
+	flags := map[string]*bool{
+		"name": stored.Name, "nationality": stored.Nationality, "gender": stored.Gender,
+		"dateOfBirth": stored.DateOfBirth, "expiryDate": stored.ExpiryDate,
+	}
+	successes := 0
+	for field, ok := range applied {
+		if !ok {
+			continue
+		}
+		successes++
+		if flags[field] == nil || !*flags[field] {
+			t.Errorf("patch to %s succeeded but isn't stored", field)
+		}
+	}
+	version, _, _ := store.ConfigVersion(ctx, "user-1")
+	if version != int64(successes) {
+		t.Errorf("ConfigVersion = %d, want one per successful patch (%d)", version, successes)
+	}
+}
+
+func TestKVConfigStoreEncryption(t *testing.T) {
+	t.Setenv("CONFIG_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	store, mr := newTestKVStore(t)
+	ctx := context.Background()
+
+	if _, err := store.SetConfig(ctx, "user-1", self.VerificationConfig{MinimumAge: intPtr(25)}); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+	raw, err := mr.Get("user-1")
+	if err != nil {
+		t.Fatalf("raw Get: %v", err)
+	}
+	if !strings.HasPrefix(raw, encryptedPrefix) || strings.Contains(raw, "minimumAge") {
+		t.Errorf("stored value %q isn't sealed", raw)
+	}
+	got, err := store.GetConfig(ctx, "user-1")
+	if err != nil || got.MinimumAge == nil || *got.MinimumAge != 25 {
+		t.Errorf("GetConfig = %+v, %v, want minimumAge 25", got, err)
+	}
+}
+
+// TestKVConfigStoreNoPartialWrites breaks the second command of each two-key write, the
+// INCR of the version counter, and checks that neither the config nor its counter changed
+func TestKVConfigStoreNoPartialWrites(t *testing.T) {
+	writes := []struct {
+		name  string
+		write func(ctx context.Context, store *KVConfigStore) error
+	}{
+		{"SetConfigWithResult", func(ctx context.Context, store *KVConfigStore) error {
+			_, err := store.SetConfigWithResult(ctx, "user-1", self.VerificationConfig{MinimumAge: intPtr(30)})
+			return err
+		}},
+		{"UpdateConfig", func(ctx context.Context, store *KVConfigStore) error {
+			_, err := store.UpdateConfig(ctx, "user-1", SelfAppDisclosureConfig{MinimumAge: intPtr(30)})
+			return err
+		}},
+		{"SaveOptions", func(ctx context.Context, store *KVConfigStore) error {
+			_, err := store.SaveOptions(ctx, "user-1", `{"minimumAge":30}`, 30*time.Minute, false)
+			return err
+		}},
+	}
+	for _, tt := range writes {
+		t.Run(tt.name, func(t *testing.T) {
+			store, mr := newTestKVStore(t)
+			ctx := context.Background()
+			if _, err := store.SetConfig(ctx, "user-1", self.VerificationConfig{MinimumAge: intPtr(18)}); err != nil {
+				t.Fatalf("SetConfig: %v", err)
+			}
+			before, _ := mr.Get("user-1")
+			// INCR fails on a non-integer once queued, after the SET before it has applied
+			mr.Set(configVersionKey("user-1"), "not-a-number")
+
+			if err := tt.write(ctx, store); err == nil {
+				t.Fatal("write succeeded with a broken version counter")
+			}
+			if after, _ := mr.Get("user-1"); after != before {
+				t.Errorf("config changed to %s, want %s", after, before)
+			}
+			if counter, _ := mr.Get(configVersionKey("user-1")); counter != "not-a-number" {
+				t.Errorf("version counter changed to %q", counter)
+			}
+		})
+	}
+}
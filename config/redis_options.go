@@ -0,0 +1,88 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Serverless-friendly client defaults: fail fast during a Redis hiccup instead of
+// holding the function open until the platform kills it
+const (
+	defaultRedisDialTimeout  = 2 * time.Second
+	defaultRedisReadTimeout  = time.Second
+	defaultRedisWriteTimeout = time.Second
+	defaultRedisPoolTimeout  = 2 * time.Second
+	defaultRedisMaxRetries   = 1
+)
+
+// applyRedisTimeouts sets the client timeouts from REDIS_DIAL_TIMEOUT, REDIS_READ_TIMEOUT,
+// REDIS_WRITE_TIMEOUT, REDIS_POOL_TIMEOUT (Go durations) and REDIS_MAX_RETRIES.
+// These bound each individual command; a request context deadline still applies on top,
+// and whichever expires first wins, so a short request deadline can cut retries short
+func applyRedisTimeouts(opt *redis.Options) {
+	opt.DialTimeout = redisDurationFromEnv("REDIS_DIAL_TIMEOUT", defaultRedisDialTimeout)
+	opt.ReadTimeout = redisDurationFromEnv("REDIS_READ_TIMEOUT", defaultRedisReadTimeout)
+	opt.WriteTimeout = redisDurationFromEnv("REDIS_WRITE_TIMEOUT", defaultRedisWriteTimeout)
+	opt.PoolTimeout = redisDurationFromEnv("REDIS_POOL_TIMEOUT", defaultRedisPoolTimeout)
+
+	opt.MaxRetries = defaultRedisMaxRetries
+	if raw := os.Getenv("REDIS_MAX_RETRIES"); raw != "" {
+		retries, err := strconv.Atoi(raw)
+		if err != nil || retries < 0 {
+			log.Printf("Ignoring invalid REDIS_MAX_RETRIES %q, using %d", raw, defaultRedisMaxRetries)
+		} else {
+			opt.MaxRetries = retries
+		}
+	}
+	// go-redis treats 0 as "use the default of 3"; -1 is how it spells "no retries"
+	if opt.MaxRetries == 0 {
+		opt.MaxRetries = -1
+	}
+}
+
+// lazyRedisConnect reports whether REDIS_CONNECT=lazy. The default, eager, pings Redis in
+// NewKVConfigStore so a bad URL or outage fails construction. Lazy skips that round trip,
+// which saves a cold start its latency, but connection errors then only surface on the first
+// real command, e.g. as a 500 from the verify handler rather than at initialization
+func lazyRedisConnect() bool {
+	switch raw := os.Getenv("REDIS_CONNECT"); raw {
+	case "", "eager":
+		return false
+	case "lazy":
+		return true
+	default:
+		log.Printf("Ignoring invalid REDIS_CONNECT %q, using eager", raw)
+		return false
+	}
+}
+
+// redisWarmTimeout bounds the background ping of a lazily connected client
+const redisWarmTimeout = 2 * time.Second
+
+// warmRedis opens the first pooled connection; failures are only logged, the first real
+// command will report them to its caller
+func warmRedis(client *redis.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisWarmTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil && err != redis.ErrClosed {
+		log.Printf("Background Redis connect failed, will retry on first use: %v", err)
+	}
+}
+
+func redisDurationFromEnv(name string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		log.Printf("Ignoring invalid %s %q, using %s", name, raw, fallback)
+		return fallback
+	}
+	return d
+}
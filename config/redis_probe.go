@@ -0,0 +1,64 @@
+package config
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// probeKeyTTL bounds how long a probe key outlives a probe that failed before deleting it
+const probeKeyTTL = time.Minute
+
+// ProbeStep is the outcome of one step of KVConfigStore.Probe
+type ProbeStep struct {
+	Step      string `json:"step"`
+	OK        bool   `json:"ok"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Probe exercises the Redis connection with a ping and a set, get and delete of a
+// throwaway key, timing each step. It stops at the first failure, except that a key it
+// managed to set is always deleted. Steps run on the current client without reconnecting,
+// so a dropped connection shows up as a failure instead of being papered over
+func (kv *KVConfigStore) Probe(ctx context.Context) (steps []ProbeStep, ok bool) {
+	client := kv.redisClient()
+	run := func(name string, op func() error) bool {
+		started := time.Now()
+		err := op()
+		step := ProbeStep{Step: name, OK: err == nil, LatencyMs: time.Since(started).Milliseconds()}
+		if err != nil {
+			step.Error = err.Error()
+		}
+		steps = append(steps, step)
+		return err == nil
+	}
+
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	key := "debug:probe:" + hex.EncodeToString(buf)
+	value := hex.EncodeToString(buf)
+
+	if !run("ping", func() error { return client.Ping(ctx).Err() }) {
+		return steps, false
+	}
+	if !run("set", func() error { return client.Set(ctx, key, value, probeKeyTTL).Err() }) {
+		return steps, false
+	}
+	ok = run("get", func() error {
+		got, err := client.Get(ctx, key).Result()
+		if err == redis.Nil {
+			return fmt.Errorf("key written by set was not found")
+		}
+		if err == nil && got != value {
+			return fmt.Errorf("read back a different value than was written")
+		}
+		return err
+	})
+	ok = run("delete", func() error { return client.Del(ctx, key).Err() }) && ok
+	return steps, ok
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultRedisReconnectAttempts bounds how many fresh clients one operation may dial
+const defaultRedisReconnectAttempts = 2
+
+// redisReconnects counts clients replaced after a dropped connection
+var redisReconnects atomic.Int64
+
+// RedisReconnects returns how many times a Redis client has been replaced since startup
+func RedisReconnects() int64 {
+	return redisReconnects.Load()
+}
+
+// redisReconnectAttempts reads REDIS_RECONNECT_ATTEMPTS; 0 turns reconnecting off
+func redisReconnectAttempts() int {
+	raw := os.Getenv("REDIS_RECONNECT_ATTEMPTS")
+	if raw == "" {
+		return defaultRedisReconnectAttempts
+	}
+	attempts, err := strconv.Atoi(raw)
+	if err != nil || attempts < 0 {
+		log.Printf("Ignoring invalid REDIS_RECONNECT_ATTEMPTS %q, using %d", raw, defaultRedisReconnectAttempts)
+		return defaultRedisReconnectAttempts
+	}
+	return attempts
+}
+
+// isRedisConnError reports whether err means the connection itself is gone. Replies from
+// Redis, including WRONGPASS and NOAUTH, timeouts, cancelled contexts and a closed client
+// are not connection errors, so they fail the operation instead of triggering a reconnect
+func isRedisConnError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, redis.ErrClosed) {
+		return false
+	}
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && !netErr.Timeout()
+}
+
+// redisClient returns the client currently in use
+func (kv *KVConfigStore) redisClient() *redis.Client {
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+	return kv.client
+}
+
+// withReconnect runs op and, while it fails with a connection error, replaces the client
+// and runs it again, up to the configured number of attempts. Only idempotent operations
+// are replayed; writes go through noteConnError instead
+func (kv *KVConfigStore) withReconnect(ctx context.Context, op func(client *redis.Client) error) error {
+	client := kv.redisClient()
+	err := op(client)
+	for attempt := 0; attempt < kv.reconnectAttempts && isRedisConnError(err); attempt++ {
+		var dialErr error
+		if client, dialErr = kv.reconnect(ctx, client, err); dialErr != nil {
+			if !isRedisConnError(dialErr) {
+				// e.g. the credentials were rotated; redialling again won't help
+				return dialErr
+			}
+			err = dialErr
+			continue
+		}
+		err = op(client)
+	}
+	return err
+}
+
+// noteConnError replaces the client after a write failed on a dropped connection, so the
+// next operation starts on a fresh one. The write itself isn't replayed, as it may have applied
+func (kv *KVConfigStore) noteConnError(ctx context.Context, client *redis.Client, err error) {
+	if kv.reconnectAttempts > 0 && isRedisConnError(err) {
+		kv.reconnect(ctx, client, err)
+	}
+}
+
+// reconnect dials a new client from the stored options and swaps it in for stale. If another
+// caller already replaced stale, its client is returned without dialling again
+func (kv *KVConfigStore) reconnect(ctx context.Context, stale *redis.Client, cause error) (*redis.Client, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if kv.client != stale {
+		return kv.client, nil
+	}
+
+	fresh := redis.NewClient(kv.options)
+	if err := fresh.Ping(ctx).Err(); err != nil {
+		fresh.Close()
+		log.Printf("Redis reconnect after %v failed: %v", cause, err)
+		return nil, err
+	}
+	kv.client = fresh
+	stale.Close()
+	log.Printf("Reconnected to Redis after %v (%d reconnects)", cause, redisReconnects.Add(1))
+	return fresh, nil
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+	"github.com/selfxyz/self/sdk/sdk-go/common"
+)
+
+// VerificationConfigStore is the storage contract the Self SDK verifier consumes
+type VerificationConfigStore interface {
+	GetConfig(ctx context.Context, id string) (self.VerificationConfig, error)
+	SetConfig(ctx context.Context, id string, config self.VerificationConfig) (bool, error)
+	GetActionId(ctx context.Context, userIdentifier string, userDefinedData string) (string, error)
+}
+
+// GlobalExcludedCountriesFromEnv parses GLOBAL_EXCLUDED_COUNTRIES, a comma-separated list of
+// 3-letter country codes excluded for every user on top of their own config. Unknown codes
+// are an error, since a typo would leave the intended country allowed
+func GlobalExcludedCountriesFromEnv() ([]common.Country3LetterCode, error) {
+	var codes []common.Country3LetterCode
+	var unknown []string
+	for _, raw := range strings.Split(os.Getenv("GLOBAL_EXCLUDED_COUNTRIES"), ",") {
+		code := common.Country3LetterCode(strings.ToUpper(strings.TrimSpace(raw)))
+		if code == "" {
+			continue
+		}
+		if _, ok := CountryName(code); !ok {
+			unknown = append(unknown, strconv.Quote(string(code)))
+			continue
+		}
+		codes = append(codes, code)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("GLOBAL_EXCLUDED_COUNTRIES has unknown 3-letter country codes %s", strings.Join(unknown, ", "))
+	}
+	return DedupeCountries(codes), nil
+}
+
+// MergeExcludedCountries returns the union of both lists without duplicates, keeping first-seen order
+// The result is nil when both lists are empty so JSON output stays unchanged
+func MergeExcludedCountries(base, extra []common.Country3LetterCode) []common.Country3LetterCode {
+	var merged []common.Country3LetterCode
+	seen := make(map[common.Country3LetterCode]bool, len(base)+len(extra))
+	for _, list := range [][]common.Country3LetterCode{base, extra} {
+		for _, code := range list {
+			if !seen[code] {
+				seen[code] = true
+				merged = append(merged, code)
+			}
+		}
+	}
+	return merged
+}
+
+// RequiredDisclosuresFromEnv parses REQUIRED_DISCLOSURES, a comma-separated list of disclosure
+// flags such as nationality,date_of_birth that are disclosed whatever the saved options say.
+// It is a compliance setting, so unknown flags are an error rather than silently not enforced
+func RequiredDisclosuresFromEnv() ([]string, error) {
+	var flags, unknown []string
+	for _, flag := range strings.Split(os.Getenv("REQUIRED_DISCLOSURES"), ",") {
+		if flag = strings.ToLower(strings.TrimSpace(flag)); flag == "" {
+			continue
+		}
+		if disclosureFlag(&SelfAppDisclosureConfig{}, flag) == nil {
+			unknown = append(unknown, strconv.Quote(flag))
+			continue
+		}
+		flags = append(flags, flag)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("REQUIRED_DISCLOSURES has unknown fields %s", strings.Join(unknown, ", "))
+	}
+	return flags, nil
+}
+
+// disclosureFlag returns the field of options holding the named flag, nil if there is none
+func disclosureFlag(options *SelfAppDisclosureConfig, flag string) **bool {
+	switch flag {
+	case "issuing_state":
+		return &options.IssuingState
+	case "name":
+		return &options.Name
+	case "passport_number":
+		return &options.PassportNumber
+	case "nationality":
+		return &options.Nationality
+	case "date_of_birth":
+		return &options.DateOfBirth
+	case "gender":
+		return &options.Gender
+	case "expiry_date":
+		return &options.ExpiryDate
+	default:
+		return nil
+	}
+}
+
+// Policy is the deployment-wide policy applied on top of stored configs and saved options
+type Policy struct {
+	// ExcludedCountries are excluded for every user, see GlobalExcludedCountriesFromEnv
+	ExcludedCountries []common.Country3LetterCode
+	// RequiredDisclosures are the disclosure flags forced on, see RequiredDisclosuresFromEnv
+	RequiredDisclosures []string
+}
+
+// ResolveVerificationConfig applies the policy on top of a stored config
+func (p Policy) ResolveVerificationConfig(cfg self.VerificationConfig) self.VerificationConfig {
+	cfg.ExcludedCountries = MergeExcludedCountries(p.ExcludedCountries, cfg.ExcludedCountries)
+	return cfg
+}
+
+// ResolveDisclosureConfig applies the same policy as ResolveVerificationConfig to saved options,
+// and forces the required disclosure flags on
+func (p Policy) ResolveDisclosureConfig(options SelfAppDisclosureConfig) SelfAppDisclosureConfig {
+	options.ExcludedCountries = MergeExcludedCountries(p.ExcludedCountries, options.ExcludedCountries)
+	for _, flag := range p.RequiredDisclosures {
+		required := true
+		*disclosureFlag(&options, flag) = &required
+	}
+	return options
+}
+
+type configOverrideContextKey struct{}
+
+// WithConfigOverride makes config lookups under the returned context use cfg instead of
+// the store, for one-off verifications. Deployment-wide policy is still applied on top
+func WithConfigOverride(ctx context.Context, cfg self.VerificationConfig) context.Context {
+	return context.WithValue(ctx, configOverrideContextKey{}, cfg)
+}
+
+// ConfigOverrideFromContext returns the config recorded by WithConfigOverride
+func ConfigOverrideFromContext(ctx context.Context) (self.VerificationConfig, bool) {
+	cfg, ok := ctx.Value(configOverrideContextKey{}).(self.VerificationConfig)
+	return cfg, ok
+}
+
+// ApplyConfigOverride replaces the verification rules in saved options with those of an
+// override, leaving the disclosure flags and formats as saved
+func ApplyConfigOverride(options SelfAppDisclosureConfig, override self.VerificationConfig) SelfAppDisclosureConfig {
+	options.MinimumAge = override.MinimumAge
+	options.Ofac = override.Ofac
+	options.ExcludedCountries = override.ExcludedCountries
+	return options
+}
+
+// ResolvedConfigStore serves configs resolved under Policy, so the SDK enforces the same
+// rules the response reports
+type ResolvedConfigStore struct {
+	VerificationConfigStore
+	Policy Policy
+}
+
+func (s ResolvedConfigStore) GetConfig(ctx context.Context, id string) (self.VerificationConfig, error) {
+	if override, ok := ConfigOverrideFromContext(ctx); ok {
+		return s.Policy.ResolveVerificationConfig(override), nil
+	}
+	cfg, err := s.VerificationConfigStore.GetConfig(ctx, id)
+	if err != nil {
+		return self.VerificationConfig{}, err
+	}
+	return s.Policy.ResolveVerificationConfig(cfg), nil
+}
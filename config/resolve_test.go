@@ -0,0 +1,131 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+	"github.com/selfxyz/self/sdk/sdk-go/common"
+)
+
+func TestGlobalExcludedCountriesFromEnv(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    []common.Country3LetterCode
+		wantErr string
+	}{
+		{"", nil, ""},
+		{" , ,", nil, ""},
+		{"PRK,SYR", []common.Country3LetterCode{"PRK", "SYR"}, ""},
+		{" prk , irn ", []common.Country3LetterCode{"PRK", "IRN"}, ""},
+		{"PRK,PRK", []common.Country3LetterCode{"PRK"}, ""},
+		{"RU", nil, `"RU"`},
+		{"PRK,RUS1,IRN,XXX", nil, `"RUS1", "XXX"`},
+	}
+	for _, tt := range tests {
+		t.Setenv("GLOBAL_EXCLUDED_COUNTRIES", tt.raw)
+		got, err := GlobalExcludedCountriesFromEnv()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("GlobalExcludedCountriesFromEnv(%q) error = %v, want one naming %s", tt.raw, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GlobalExcludedCountriesFromEnv(%q) = %v, %v, want %v", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestGlobalExcludedCountriesMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		global []common.Country3LetterCode
+		saved  []common.Country3LetterCode
+		want   []common.Country3LetterCode
+	}{
+		{"unset and nothing saved", nil, nil, nil},
+		{"unset", nil, []common.Country3LetterCode{"IRN"}, []common.Country3LetterCode{"IRN"}},
+		{"nothing saved", []common.Country3LetterCode{"PRK", "SYR"}, nil, []common.Country3LetterCode{"PRK", "SYR"}},
+		{"disjoint", []common.Country3LetterCode{"PRK"}, []common.Country3LetterCode{"IRN"}, []common.Country3LetterCode{"PRK", "IRN"}},
+		{"overlap", []common.Country3LetterCode{"PRK", "IRN"}, []common.Country3LetterCode{"IRN", "CUB"}, []common.Country3LetterCode{"PRK", "IRN", "CUB"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := Policy{ExcludedCountries: tt.global}
+			got := policy.ResolveVerificationConfig(self.VerificationConfig{ExcludedCountries: tt.saved})
+			if !reflect.DeepEqual(got.ExcludedCountries, tt.want) {
+				t.Errorf("ResolveVerificationConfig excluded %v, want %v", got.ExcludedCountries, tt.want)
+			}
+			options := policy.ResolveDisclosureConfig(SelfAppDisclosureConfig{ExcludedCountries: tt.saved})
+			if !reflect.DeepEqual(options.ExcludedCountries, tt.want) {
+				t.Errorf("ResolveDisclosureConfig excluded %v, want %v", options.ExcludedCountries, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequiredDisclosuresFromEnv(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    []string
+		wantErr string
+	}{
+		{"", nil, ""},
+		{"nationality", []string{"nationality"}, ""},
+		{" Nationality , DATE_OF_BIRTH ", []string{"nationality", "date_of_birth"}, ""},
+		{"nationality,,name", []string{"nationality", "name"}, ""},
+		{"nationalty", nil, `"nationalty"`},
+		{"nationality,shoe_size,name,eye_colour", nil, `"shoe_size", "eye_colour"`},
+	}
+	for _, tt := range tests {
+		t.Setenv("REQUIRED_DISCLOSURES", tt.raw)
+		got, err := RequiredDisclosuresFromEnv()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("RequiredDisclosuresFromEnv(%q) error = %v, want one naming %s", tt.raw, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("RequiredDisclosuresFromEnv(%q) = %v, %v, want %v", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestResolveDisclosureConfigRequiredFlags(t *testing.T) {
+	off, on := false, true
+	tests := []struct {
+		name     string
+		required []string
+		saved    SelfAppDisclosureConfig
+		// want lists the flags that must be on after resolving; every other flag must be as saved
+		want []string
+	}{
+		{"nothing required", nil, SelfAppDisclosureConfig{Name: &on}, []string{"name"}},
+		{"required flag unset", []string{"nationality"}, SelfAppDisclosureConfig{}, []string{"nationality"}},
+		{"required flag off", []string{"nationality", "gender"}, SelfAppDisclosureConfig{Nationality: &off, Name: &on}, []string{"nationality", "gender", "name"}},
+		{"required flag already on", []string{"name"}, SelfAppDisclosureConfig{Name: &on}, []string{"name"}},
+	}
+	flags := []string{"issuing_state", "name", "passport_number", "nationality", "date_of_birth", "gender", "expiry_date"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved := Policy{RequiredDisclosures: tt.required}.ResolveDisclosureConfig(tt.saved)
+			for _, flag := range flags {
+				wantOn := false
+				for _, w := range tt.want {
+					wantOn = wantOn || w == flag
+				}
+				got := *disclosureFlag(&resolved, flag)
+				if gotOn := got != nil && *got; gotOn != wantOn {
+					t.Errorf("%s = %v, want %v", flag, gotOn, wantOn)
+				}
+			}
+		})
+	}
+	// Forcing a flag on replaces the pointer rather than writing through the saved one
+	if off {
+		t.Error("ResolveDisclosureConfig wrote through the saved options' flags")
+	}
+}
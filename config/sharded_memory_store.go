@@ -0,0 +1,81 @@
+package config
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+)
+
+// DefaultShardCount is the shard count used when NewShardedMemoryConfigStore is given n < 1
+const DefaultShardCount = 32
+
+// ShardedMemoryConfigStore is an in-memory VerificationConfigStore for local load testing
+// Keys are spread over shards that each have their own lock, so concurrent requests for
+// different users rarely contend the way they do on a single map and mutex.
+// A key's shard is its fnv hash modulo the shard count. That is plain modulo hashing, not
+// consistent hashing, and deliberately so: the count is fixed when the store is created
+// and never changes at runtime, so no key ever has to move between shards.
+// It implements only VerificationConfigStore, the contract the SDK verifier exercises in
+// a load test, not the full ConfigStore the API handlers need.
+// Nothing is persisted; don't use it outside local or test runs
+type ShardedMemoryConfigStore struct {
+	shards []*memoryShard
+}
+
+type memoryShard struct {
+	mu      sync.RWMutex
+	configs map[string]self.VerificationConfig
+}
+
+// NewShardedMemoryConfigStore creates a store with n shards
+func NewShardedMemoryConfigStore(n int) *ShardedMemoryConfigStore {
+	if n < 1 {
+		n = DefaultShardCount
+	}
+	shards := make([]*memoryShard, n)
+	for i := range shards {
+		shards[i] = &memoryShard{configs: make(map[string]self.VerificationConfig)}
+	}
+	return &ShardedMemoryConfigStore{shards: shards}
+}
+
+// shard picks the shard owning id by fnv hash modulo the fixed shard count, so a given ID
+// always maps to the same shard
+func (s *ShardedMemoryConfigStore) shard(id string) *memoryShard {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+func (s *ShardedMemoryConfigStore) GetActionId(ctx context.Context, userIdentifier string, userDefinedData string) (string, error) {
+	return userIdentifier, nil
+}
+
+func (s *ShardedMemoryConfigStore) SetConfig(ctx context.Context, id string, config self.VerificationConfig) (bool, error) {
+	config.ExcludedCountries = DedupeCountries(config.ExcludedCountries)
+	if errs := ValidateVerificationConfig(config); len(errs) > 0 {
+		return false, &ValidationError{Errors: errs}
+	}
+
+	sh := s.shard(id)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	_, existed := sh.configs[id]
+	sh.configs[id] = config
+	return !existed, nil
+}
+
+func (s *ShardedMemoryConfigStore) GetConfig(ctx context.Context, id string) (self.VerificationConfig, error) {
+	sh := s.shard(id)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	stored, ok := sh.configs[id]
+	if !ok {
+		return DefaultVerificationConfig(), nil
+	}
+	return stored, nil
+}
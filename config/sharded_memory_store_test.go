@@ -0,0 +1,59 @@
+package config
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// BenchmarkShardedMemoryConfigStore compares parallel lookups and saves across many users on
+// the sharded store against a single shard, which is the single-mutex map it replaced, and
+// against MemoryConfigStore. Run it with
+// go test ./config -run '^$' -bench ShardedMemoryConfigStore -cpu 1,4,16
+func BenchmarkShardedMemoryConfigStore(b *testing.B) {
+	const users = 1024
+	ids := make([]string, users)
+	for i := range ids {
+		ids[i] = "user-" + strconv.Itoa(i)
+	}
+	cfg := DefaultVerificationConfig()
+
+	stores := []struct {
+		name  string
+		store VerificationConfigStore
+	}{
+		{"single mutex", NewShardedMemoryConfigStore(1)},
+		{"sharded", NewShardedMemoryConfigStore(DefaultShardCount)},
+		{"MemoryConfigStore", NewMemoryConfigStore()},
+	}
+	for _, s := range stores {
+		for _, id := range ids {
+			if _, err := s.store.SetConfig(context.Background(), id, cfg); err != nil {
+				b.Fatalf("SetConfig: %v", err)
+			}
+		}
+		b.Run(s.name, func(b *testing.B) {
+			var next atomic.Uint64
+			b.RunParallel(func(pb *testing.PB) {
+				ctx := context.Background()
+				for pb.Next() {
+					n := next.Add(1)
+					id := ids[n%users]
+					// One save for every nine lookups, roughly what verify traffic looks like
+					if n%10 == 0 {
+						if _, err := s.store.SetConfig(ctx, id, cfg); err != nil {
+							b.Error(err)
+							return
+						}
+						continue
+					}
+					if _, err := s.store.GetConfig(ctx, id); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+)
+
+// ConfigStore is everything the API handlers need from a config backend
+type ConfigStore interface {
+	VerificationConfigStore
+	// SetConfigWithResult stores config like SetConfig, but leaves an identical config
+	// untouched and reports whether anything changed along with the new version
+	SetConfigWithResult(ctx context.Context, id string, config self.VerificationConfig) (SetConfigResult, error)
+	// SetRawConfig is SetConfigWithResult for an already validated value as GetValue returns
+	// it, such as saved options with disclosure flags, which a VerificationConfig can't carry
+	SetRawConfig(ctx context.Context, id string, configJSON string) (SetConfigResult, error)
+	// ConfigVersion returns the version of the stored config GetConfig resolves id to under ctx;
+	// found is false when nothing is stored and the defaults apply
+	ConfigVersion(ctx context.Context, id string) (version int64, found bool, err error)
+	GetDisclosureConfig(ctx context.Context, id string) (SelfAppDisclosureConfig, error)
+	// UpdateConfig merges the non-nil fields of patch into the config stored under id
+	UpdateConfig(ctx context.Context, id string, patch SelfAppDisclosureConfig) (SelfAppDisclosureConfig, error)
+	SetWithExpiration(ctx context.Context, key string, value string, expiration time.Duration) error
+	// SaveOptions writes saved options under id until expiration passes and bumps id's config
+	// version in the same transaction. With onlyIfAbsent nothing is written while id holds a
+	// live value; stored is false when that happened
+	SaveOptions(ctx context.Context, id string, optionsJSON string, expiration time.Duration, onlyIfAbsent bool) (stored bool, err error)
+	// GetValue reads a raw value written by SetWithExpiration; ok is false when it is missing
+	GetValue(ctx context.Context, key string) (value string, ok bool, err error)
+	// TakeValue reads and deletes a raw value in one step, so only one caller can ever get it
+	TakeValue(ctx context.Context, key string) (value string, ok bool, err error)
+	AppendAudit(ctx context.Context, userID string, entry AuditEntry, max int) error
+	ReadAudit(ctx context.Context, userID string) ([]AuditEntry, error)
+	// ListIDs returns the keys of the configs matching pattern, other than per-attestation ones
+	ListIDs(ctx context.Context, pattern string) ([]string, error)
+	// ListAttestationKeys returns the keys of the per-attestation configs, see AttestationConfigKey
+	ListAttestationKeys(ctx context.Context) ([]string, error)
+	// Ping reports whether the backend is reachable
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// encodeConfig dedupes and validates config, then serializes it the way every backend stores it
+func encodeConfig(config self.VerificationConfig) (string, error) {
+	config.ExcludedCountries = DedupeCountries(config.ExcludedCountries)
+	if errs := ValidateVerificationConfig(config); len(errs) > 0 {
+		return "", &ValidationError{Errors: errs}
+	}
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return string(configJSON), nil
+}
+
+// NewConfigStoreFromEnv creates the backend named by CONFIG_STORE_BACKEND:
+// "redis" (the default), "memory" or "postgres". New backends are added as a case here.
+// Every backend derives action IDs with the strategy from ActionIdStrategyFromEnv
+func NewConfigStoreFromEnv() (ConfigStore, error) {
+	actionIds, err := ActionIdStrategyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	switch backend := os.Getenv("CONFIG_STORE_BACKEND"); backend {
+	case "", "redis":
+		store, err := NewKVConfigStoreFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		store.actionIds = actionIds
+		return store, nil
+	case "memory":
+		store := NewMemoryConfigStore()
+		store.actionIds = actionIds
+		return store, nil
+	case "postgres":
+		store, err := NewPostgresConfigStoreFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		store.actionIds = actionIds
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown CONFIG_STORE_BACKEND %q", backend)
+	}
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+)
+
+// TenantConfigStore scopes a ConfigStore to a single tenant by prefixing every key
+// with the tenant ID, so the same user ID in two tenants maps to independent configs
+type TenantConfigStore struct {
+	ConfigStore
+	tenantID string
+}
+
+// ConfiguredTenants returns the tenant IDs listed in the TENANT_IDS environment variable
+// An empty result means multi-tenancy is disabled and requests must not carry a tenant ID
+func ConfiguredTenants() map[string]bool {
+	tenants := make(map[string]bool)
+	for _, id := range strings.Split(os.Getenv("TENANT_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			tenants[id] = true
+		}
+	}
+	return tenants
+}
+
+// NewTenantConfigStore wraps store for tenantID, rejecting tenants that aren't configured
+func NewTenantConfigStore(store ConfigStore, tenantID string) (*TenantConfigStore, error) {
+	tenants := ConfiguredTenants()
+	if len(tenants) == 0 {
+		if tenantID != "" {
+			return nil, fmt.Errorf("tenant %q is not configured", tenantID)
+		}
+		return &TenantConfigStore{ConfigStore: store}, nil
+	}
+	if tenantID == "" {
+		return nil, fmt.Errorf("tenant ID is required")
+	}
+	if !tenants[tenantID] {
+		return nil, fmt.Errorf("tenant %q is not configured", tenantID)
+	}
+	return &TenantConfigStore{ConfigStore: store, tenantID: tenantID}, nil
+}
+
+// tenantPrefix namespaces tenant-scoped keys, see tenantScope
+const tenantPrefix = "tenant:"
+
+// key namespaces id under the tenant; the untenanted store keeps the original keys.
+// Per-attestation keys keep their attestation prefix outermost, attestation:<code>:tenant:<tenant>:<id>,
+// which is where configKeys looks for a tenant's per-attestation and default configs
+func (t *TenantConfigStore) key(id string) string {
+	if t.tenantID == "" {
+		return id
+	}
+	if rest, ok := strings.CutPrefix(id, attestationPrefix); ok {
+		if code, id, ok := strings.Cut(rest, ":"); ok {
+			return AttestationConfigKey(code, t.key(id))
+		}
+	}
+	return tenantPrefix + t.tenantID + ":" + id
+}
+
+func (t *TenantConfigStore) SetConfig(ctx context.Context, id string, config self.VerificationConfig) (bool, error) {
+	return t.ConfigStore.SetConfig(ctx, t.key(id), config)
+}
+
+func (t *TenantConfigStore) SetConfigWithResult(ctx context.Context, id string, config self.VerificationConfig) (SetConfigResult, error) {
+	return t.ConfigStore.SetConfigWithResult(ctx, t.key(id), config)
+}
+
+func (t *TenantConfigStore) SetRawConfig(ctx context.Context, id string, configJSON string) (SetConfigResult, error) {
+	return t.ConfigStore.SetRawConfig(ctx, t.key(id), configJSON)
+}
+
+func (t *TenantConfigStore) ConfigVersion(ctx context.Context, id string) (int64, bool, error) {
+	return t.ConfigStore.ConfigVersion(ctx, t.key(id))
+}
+
+func (t *TenantConfigStore) GetConfig(ctx context.Context, id string) (self.VerificationConfig, error) {
+	return t.ConfigStore.GetConfig(ctx, t.key(id))
+}
+
+func (t *TenantConfigStore) GetDisclosureConfig(ctx context.Context, id string) (SelfAppDisclosureConfig, error) {
+	return t.ConfigStore.GetDisclosureConfig(ctx, t.key(id))
+}
+
+func (t *TenantConfigStore) UpdateConfig(ctx context.Context, id string, patch SelfAppDisclosureConfig) (SelfAppDisclosureConfig, error) {
+	return t.ConfigStore.UpdateConfig(ctx, t.key(id), patch)
+}
+
+// SetWithExpiration stores a tenant-scoped key-value pair with expiration
+func (t *TenantConfigStore) SetWithExpiration(ctx context.Context, key string, value string, expiration time.Duration) error {
+	return t.ConfigStore.SetWithExpiration(ctx, t.key(key), value, expiration)
+}
+
+// SaveOptions stores tenant-scoped saved options and bumps their version
+func (t *TenantConfigStore) SaveOptions(ctx context.Context, id string, optionsJSON string, expiration time.Duration, onlyIfAbsent bool) (bool, error) {
+	return t.ConfigStore.SaveOptions(ctx, t.key(id), optionsJSON, expiration, onlyIfAbsent)
+}
+
+// GetValue reads a tenant-scoped raw value
+func (t *TenantConfigStore) GetValue(ctx context.Context, key string) (string, bool, error) {
+	return t.ConfigStore.GetValue(ctx, t.key(key))
+}
+
+// TakeValue reads and deletes a tenant-scoped raw value
+func (t *TenantConfigStore) TakeValue(ctx context.Context, key string) (string, bool, error) {
+	return t.ConfigStore.TakeValue(ctx, t.key(key))
+}
+
+// AppendAudit appends to the tenant-scoped audit log of userID
+func (t *TenantConfigStore) AppendAudit(ctx context.Context, userID string, entry AuditEntry, max int) error {
+	return t.ConfigStore.AppendAudit(ctx, t.key(userID), entry, max)
+}
+
+// ReadAudit reads the tenant-scoped audit log of userID
+func (t *TenantConfigStore) ReadAudit(ctx context.Context, userID string) ([]AuditEntry, error) {
+	return t.ConfigStore.ReadAudit(ctx, t.key(userID))
+}
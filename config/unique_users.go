@@ -0,0 +1,89 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// uniqueUsersPrefix namespaces the hourly HyperLogLogs, which ListIDs leaves out
+	uniqueUsersPrefix = "stats:unique-users:"
+	// UniqueUsersBucket is the granularity of the unique user windows
+	UniqueUsersBucket = time.Hour
+	// defaultUniqueUsersRetention is how long hourly buckets are kept by default
+	defaultUniqueUsersRetention = 30 * 24 * time.Hour
+)
+
+// UniqueUserCounter is implemented by stores that can count distinct verified users
+type UniqueUserCounter interface {
+	// RecordUniqueUser adds userID to the bucket covering at
+	RecordUniqueUser(ctx context.Context, userID string, at time.Time) error
+	// CountUniqueUsers estimates the distinct users recorded in the window ending at now
+	CountUniqueUsers(ctx context.Context, window time.Duration, now time.Time) (int64, error)
+}
+
+// UniqueUsersRetention reads UNIQUE_USERS_RETENTION, how long hourly buckets live and so
+// the longest window that can be counted. 0 turns recording off
+func UniqueUsersRetention() time.Duration {
+	raw := os.Getenv("UNIQUE_USERS_RETENTION")
+	if raw == "" {
+		return defaultUniqueUsersRetention
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		log.Printf("Ignoring invalid UNIQUE_USERS_RETENTION %q, using %s", raw, defaultUniqueUsersRetention)
+		return defaultUniqueUsersRetention
+	}
+	return d
+}
+
+func uniqueUsersKey(bucket time.Time) string {
+	return uniqueUsersPrefix + bucket.UTC().Format("2006010215")
+}
+
+// RecordUniqueUser adds userID to the hour's HyperLogLog, which expires once it falls out
+// of the longest countable window. A retried PFADD is harmless, so it may be replayed
+func (kv *KVConfigStore) RecordUniqueUser(ctx context.Context, userID string, at time.Time) error {
+	retention := UniqueUsersRetention()
+	if retention == 0 {
+		return nil
+	}
+	bucket := at.UTC().Truncate(UniqueUsersBucket)
+	key := uniqueUsersKey(bucket)
+	err := kv.withReconnect(ctx, func(client *redis.Client) error {
+		_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.PFAdd(ctx, key, userID)
+			pipe.ExpireAt(ctx, key, bucket.Add(UniqueUsersBucket+retention))
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record unique user in Redis: %w", err)
+	}
+	return nil
+}
+
+// CountUniqueUsers merges the hourly buckets overlapping the window with PFCOUNT, so the
+// window is rounded out to whole hours and users seen in several hours count once
+func (kv *KVConfigStore) CountUniqueUsers(ctx context.Context, window time.Duration, now time.Time) (int64, error) {
+	var keys []string
+	last := now.UTC().Truncate(UniqueUsersBucket)
+	for bucket := now.Add(-window).UTC().Truncate(UniqueUsersBucket); !bucket.After(last); bucket = bucket.Add(UniqueUsersBucket) {
+		keys = append(keys, uniqueUsersKey(bucket))
+	}
+	var count int64
+	err := kv.withReconnect(ctx, func(client *redis.Client) (err error) {
+		count, err = client.PFCount(ctx, keys...).Result()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unique users in Redis: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+)
+
+var (
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexPattern  = regexp.MustCompile(`^(0x)?[0-9a-fA-F]+$`)
+)
+
+// UserIDTypeFromEnv reads USER_ID_TYPE ("uuid" or "hex"), defaulting to UUID when unset
+func UserIDTypeFromEnv() (self.UserIDType, error) {
+	switch strings.ToLower(os.Getenv("USER_ID_TYPE")) {
+	case "", "uuid":
+		return self.UserIDTypeUUID, nil
+	case "hex":
+		return self.UserIDTypeHex, nil
+	default:
+		return self.UserIDTypeUUID, fmt.Errorf("USER_ID_TYPE must be \"uuid\" or \"hex\", got %q", os.Getenv("USER_ID_TYPE"))
+	}
+}
+
+// ValidateUserID checks that id is formatted the way the verifier expects for idType
+func ValidateUserID(id string, idType self.UserIDType) error {
+	switch idType {
+	case self.UserIDTypeHex:
+		if !hexPattern.MatchString(id) {
+			return fmt.Errorf("userId %q is not a hex identifier", id)
+		}
+	default:
+		if !uuidPattern.MatchString(id) {
+			return fmt.Errorf("userId %q is not a UUID", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// UserAccess is the outcome of checking a user ID against the block and allow lists
+type UserAccess int
+
+const (
+	UserAllowed UserAccess = iota
+	// UserBlocked means the ID is on a block list
+	UserBlocked
+	// UserNotAllowed means an allow list is active and the ID isn't on it
+	UserNotAllowed
+)
+
+// userListPrefix namespaces the Redis sets of user IDs, which ListIDs leaves out
+const userListPrefix = "userlist:"
+
+// Redis sets checked alongside BLOCKED_USER_IDS and ALLOWED_USER_IDS. Changes made with
+// SADD and SREM apply from the next request, without a restart
+const (
+	BlockedUsersKey = userListPrefix + "blocked"
+	AllowedUsersKey = userListPrefix + "allowed"
+)
+
+// UserListStore is implemented by backends that keep reloadable user ID lists
+type UserListStore interface {
+	// UserListed reports whether id is in the list stored under key, and whether that
+	// list has any entries at all
+	UserListed(ctx context.Context, key, id string) (member, active bool, err error)
+}
+
+// envUserLists reads BLOCKED_USER_IDS and ALLOWED_USER_IDS, comma-separated user IDs
+var envUserLists = sync.OnceValues(func() (blocked, allowed map[string]bool) {
+	return parseUserList(os.Getenv("BLOCKED_USER_IDS")), parseUserList(os.Getenv("ALLOWED_USER_IDS"))
+})
+
+func parseUserList(raw string) map[string]bool {
+	ids := make(map[string]bool)
+	for _, id := range strings.Split(raw, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// CheckUserAccess checks id against the environment lists and, when store keeps its own,
+// the stored ones. Blocking wins over allowing, and an allow list is active as soon as
+// either source has entries
+func CheckUserAccess(ctx context.Context, store ConfigStore, id string) (UserAccess, error) {
+	blocked, allowed := envUserLists()
+	if blocked[id] {
+		return UserBlocked, nil
+	}
+	allowListActive, onAllowList := len(allowed) > 0, allowed[id]
+
+	if lists, ok := store.(UserListStore); ok {
+		member, _, err := lists.UserListed(ctx, BlockedUsersKey, id)
+		if err != nil {
+			return UserAllowed, err
+		}
+		if member {
+			return UserBlocked, nil
+		}
+		member, active, err := lists.UserListed(ctx, AllowedUsersKey, id)
+		if err != nil {
+			return UserAllowed, err
+		}
+		allowListActive = allowListActive || active
+		onAllowList = onAllowList || member
+	}
+
+	if allowListActive && !onAllowList {
+		return UserNotAllowed, nil
+	}
+	return UserAllowed, nil
+}
+
+// UserListed checks membership of the Redis set under key in one round trip
+func (kv *KVConfigStore) UserListed(ctx context.Context, key, id string) (bool, bool, error) {
+	var member *redis.BoolCmd
+	var size *redis.IntCmd
+	err := kv.withReconnect(ctx, func(client *redis.Client) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			member = pipe.SIsMember(ctx, key, id)
+			size = pipe.SCard(ctx, key)
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return false, false, fmt.Errorf("failed to read user list %s from Redis: %w", key, err)
+	}
+	return member.Val(), size.Val() > 0, nil
+}
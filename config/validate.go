@@ -0,0 +1,107 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+	"github.com/selfxyz/self/sdk/sdk-go/common"
+)
+
+// FieldError describes one invalid field of a config in terms safe to show to users
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned by SetConfig when a config breaks an invariant.
+// Its field errors only describe the submitted values, so handlers can show them as-is
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		parts[i] = fe.Field + ": " + fe.Message
+	}
+	return "invalid config: " + strings.Join(parts, "; ")
+}
+
+// ValidateVerificationConfig checks the invariants every stored config must satisfy
+// It is the single validator behind SetConfig and the validate endpoint
+func ValidateVerificationConfig(cfg self.VerificationConfig) []FieldError {
+	var errs []FieldError
+	if age := cfg.MinimumAge; age != nil && (*age < minimumAgeLowerBound || *age > minimumAgeUpperBound) {
+		errs = append(errs, FieldError{
+			Field:   "minimumAge",
+			Message: fmt.Sprintf("must be between %d and %d", minimumAgeLowerBound, minimumAgeUpperBound),
+		})
+	}
+	if limit := maxExcludedCountries(); len(DedupeCountries(cfg.ExcludedCountries)) > limit {
+		errs = append(errs, FieldError{
+			Field:   "excludedCountries",
+			Message: fmt.Sprintf("must not list more than %d countries", limit),
+		})
+	}
+	for i, code := range cfg.ExcludedCountries {
+		if _, ok := CountryName(code); !ok {
+			errs = append(errs, FieldError{
+				Field:   fmt.Sprintf("excludedCountries[%d]", i),
+				Message: fmt.Sprintf("%q is not a known 3-letter country code", code),
+			})
+		}
+	}
+	return errs
+}
+
+// DedupeCountries drops repeated country codes, keeping the first occurrence's position.
+// Stores apply it before writing, so lists are kept as short as they can be
+func DedupeCountries(codes []common.Country3LetterCode) []common.Country3LetterCode {
+	if codes == nil {
+		return nil
+	}
+	seen := make(map[common.Country3LetterCode]bool, len(codes))
+	deduped := make([]common.Country3LetterCode, 0, len(codes))
+	for _, code := range codes {
+		if !seen[code] {
+			seen[code] = true
+			deduped = append(deduped, code)
+		}
+	}
+	return deduped
+}
+
+// ValidateDisclosureConfig applies ValidateVerificationConfig to the verification part of
+// saved options, so options can't smuggle in values SetConfig would reject
+func ValidateDisclosureConfig(cfg SelfAppDisclosureConfig) []FieldError {
+	return ValidateVerificationConfig(self.VerificationConfig{
+		MinimumAge:        cfg.MinimumAge,
+		Ofac:              cfg.Ofac,
+		ExcludedCountries: cfg.ExcludedCountries,
+	})
+}
+
+// DecodeVerificationConfig strictly decodes a config, reporting unknown fields and
+// type mismatches as field errors; any other decode failure is returned as an error
+func DecodeVerificationConfig(data []byte) (self.VerificationConfig, []FieldError, error) {
+	var cfg self.VerificationConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&cfg)
+	if err == nil {
+		return cfg, ValidateVerificationConfig(cfg), nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return cfg, []FieldError{{Field: typeErr.Field, Message: "expected " + typeErr.Type.String() + ", got " + typeErr.Value}}, nil
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return cfg, []FieldError{{Field: strings.Trim(field, `"`), Message: "unknown field"}}, nil
+	}
+	return cfg, nil, err
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+	"github.com/selfxyz/self/sdk/sdk-go/common"
+)
+
+// knownCountries returns n distinct valid country codes in a stable order
+func knownCountries(t *testing.T, n int) []common.Country3LetterCode {
+	t.Helper()
+	codes := make([]common.Country3LetterCode, 0, len(countryNames))
+	for code := range countryNames {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	if n > len(codes) {
+		t.Fatalf("only %d known countries, need %d", len(codes), n)
+	}
+	return codes[:n]
+}
+
+// useMaxExcludedCountries sets the excluded-country limit until t ends
+func useMaxExcludedCountries(t *testing.T, limit int) {
+	previous := maxExcludedCountries
+	maxExcludedCountries = func() int { return limit }
+	t.Cleanup(func() { maxExcludedCountries = previous })
+}
+
+func TestMaxExcludedCountriesFromEnv(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    int
+		wantErr bool
+	}{
+		{"", defaultMaxExcludedCountries, false},
+		{"5", 5, false},
+		{"1", 1, false},
+		{"0", 0, true},
+		{"-3", 0, true},
+		{"many", 0, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("MAX_EXCLUDED_COUNTRIES", tt.raw)
+		got, err := MaxExcludedCountriesFromEnv()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("MaxExcludedCountriesFromEnv with %q = %d, want an error", tt.raw, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("MaxExcludedCountriesFromEnv with %q = %d, %v, want %d", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestExcludedCountriesLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		// distinct countries listed, then how many of them are listed a second time
+		distinct, repeated int
+		wantErr            bool
+	}{
+		{"default at the limit", defaultMaxExcludedCountries, defaultMaxExcludedCountries, 0, false},
+		{"default over the limit", defaultMaxExcludedCountries, defaultMaxExcludedCountries + 1, 0, true},
+		{"at the limit", 5, 5, 0, false},
+		{"one over the limit", 5, 6, 0, true},
+		{"over the limit only with duplicates", 5, 5, 5, false},
+		{"limit of one", 1, 1, 1, false},
+		{"none", 1, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMaxExcludedCountries(t, tt.limit)
+			countries := knownCountries(t, tt.distinct)
+			countries = append(countries, countries[:tt.repeated]...)
+
+			errs := ValidateVerificationConfig(self.VerificationConfig{ExcludedCountries: countries})
+			if gotErr := len(errs) > 0; gotErr != tt.wantErr {
+				t.Fatalf("ValidateVerificationConfig with %d countries = %v, want error %v", len(countries), errs, tt.wantErr)
+			}
+			if tt.wantErr && errs[0].Field != "excludedCountries" {
+				t.Errorf("error field = %q, want excludedCountries", errs[0].Field)
+			}
+		})
+	}
+}
+
+func TestDedupeCountries(t *testing.T) {
+	tests := []struct {
+		in, want []common.Country3LetterCode
+	}{
+		{nil, nil},
+		{[]common.Country3LetterCode{}, []common.Country3LetterCode{}},
+		{[]common.Country3LetterCode{"IRN", "PRK"}, []common.Country3LetterCode{"IRN", "PRK"}},
+		{[]common.Country3LetterCode{"IRN", "PRK", "IRN", "CUB", "PRK"}, []common.Country3LetterCode{"IRN", "PRK", "CUB"}},
+	}
+	for _, tt := range tests {
+		if got := DedupeCountries(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DedupeCountries(%v) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestStoreDedupesCountries checks that a list over the limit only through repeats is stored deduped
+func TestStoreDedupesCountries(t *testing.T) {
+	useMaxExcludedCountries(t, 2)
+	store, _ := newTestKVStore(t)
+	ctx := context.Background()
+	listed := []common.Country3LetterCode{"IRN", "PRK", "IRN", "PRK"}
+	if _, err := store.SetConfig(ctx, "user-1", self.VerificationConfig{ExcludedCountries: listed}); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+	got, err := store.GetConfig(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	if want := []common.Country3LetterCode{"IRN", "PRK"}; !reflect.DeepEqual(got.ExcludedCountries, want) {
+		t.Errorf("stored countries = %v, want %v", got.ExcludedCountries, want)
+	}
+}
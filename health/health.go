@@ -0,0 +1,170 @@
+// Package health implements the liveness and readiness probes
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"playground/config"
+	"playground/web"
+)
+
+const (
+	// readyCacheTTL is how long a readiness result is reused, so frequent probes don't hammer the store
+	readyCacheTTL = 5 * time.Second
+	// pingTimeout bounds a single readiness check of the config store
+	pingTimeout = 2 * time.Second
+	// probeCacheControl lets proxies reuse a probe answer as long as the checker would
+	probeCacheControl = "public, max-age=5"
+)
+
+// Overall and per-dependency statuses reported by the readiness probe
+const (
+	StatusHealthy   = "healthy"
+	StatusDegraded  = "degraded"
+	StatusUnhealthy = "unhealthy"
+)
+
+// Dependency is one thing readiness depends on. A failing critical dependency makes the
+// instance unhealthy; a failing non-critical one only degrades it
+type Dependency struct {
+	Name     string
+	Check    func(ctx context.Context) error
+	Timeout  time.Duration
+	Critical bool
+}
+
+// DependencyResult is the outcome of checking one dependency
+type DependencyResult struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+	Critical  bool   `json:"critical"`
+}
+
+// Report is the aggregate readiness result; Status is the worst of the dependencies'
+type Report struct {
+	Status       string             `json:"status"`
+	Dependencies []DependencyResult `json:"dependencies"`
+}
+
+// checkDependency runs fn with its own deadline of timeout and reports how it went
+func checkDependency(ctx context.Context, name string, fn func(ctx context.Context) error, timeout time.Duration) DependencyResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	started := time.Now()
+	err := fn(ctx)
+	result := DependencyResult{Name: name, Status: StatusHealthy, LatencyMs: time.Since(started).Milliseconds()}
+	if err != nil {
+		result.Status = StatusUnhealthy
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Checker checks a set of dependencies concurrently and caches the report for a short while
+type Checker struct {
+	deps []Dependency
+	ttl  time.Duration
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	last      Report
+}
+
+// NewChecker creates a Checker that reuses each report on deps for ttl
+func NewChecker(deps []Dependency, ttl time.Duration) *Checker {
+	return &Checker{deps: deps, ttl: ttl}
+}
+
+// Check returns the cached report, checking again once it is older than the TTL
+// Concurrent probes wait for one run instead of each hitting the dependencies
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < c.ttl {
+		return c.last
+	}
+
+	results := make([]DependencyResult, len(c.deps))
+	var wg sync.WaitGroup
+	for i, dep := range c.deps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = checkDependency(ctx, dep.Name, dep.Check, dep.Timeout)
+			results[i].Critical = dep.Critical
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusHealthy, Dependencies: results}
+	for i := range results {
+		if results[i].Status == StatusHealthy {
+			continue
+		}
+		if results[i].Critical {
+			report.Status = StatusUnhealthy
+		} else {
+			results[i].Status = StatusDegraded
+			if report.Status == StatusHealthy {
+				report.Status = StatusDegraded
+			}
+		}
+	}
+	c.last = report
+	c.checkedAt = time.Now()
+	return report
+}
+
+// ConfigStoreCheck returns a check that pings the configured config store
+// The store is created on first use and again after a failed creation, so a store
+// that was down at startup is picked up once it comes back. It is not safe for concurrent
+// use on its own; wrap it in a Checker, which runs one check at a time
+func ConfigStoreCheck() func(ctx context.Context) error {
+	var store config.ConfigStore
+	return func(ctx context.Context) error {
+		if store == nil {
+			s, err := config.NewConfigStoreFromEnv()
+			if err != nil {
+				return err
+			}
+			store = s
+		}
+		return store.Ping(ctx)
+	}
+}
+
+// DefaultChecker checks the config store from the environment
+var DefaultChecker = NewChecker([]Dependency{
+	{Name: "configStore", Check: ConfigStoreCheck(), Timeout: pingTimeout, Critical: true},
+}, readyCacheTTL)
+
+// Liveness reports that the process is up; it never checks dependencies
+func Liveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", probeCacheControl)
+	web.WriteJSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readiness reports c's dependencies, with 503 when a critical one is down. A degraded
+// instance still answers 200, so it keeps receiving traffic
+func Readiness(c *Checker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Check(r.Context())
+		status := http.StatusOK
+		if report.Status == StatusUnhealthy {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Cache-Control", probeCacheControl)
+		// redisReconnects counts dropped Redis connections this instance has recovered from;
+		// inFlight is the number of requests it is handling, this probe included
+		web.WriteJSON(w, r, status, struct {
+			Report
+			RedisReconnects int64 `json:"redisReconnects"`
+			InFlight        int64 `json:"inFlight"`
+		}{report, config.RedisReconnects(), web.InFlight()})
+	})
+}
@@ -0,0 +1,218 @@
+// Package jwt signs verification results as JWTs and publishes the matching JWKS
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"playground/settings"
+)
+
+// TokenTTL is how long issued verification tokens stay valid
+const TokenTTL = 5 * time.Minute
+
+// Signer signs JWTs with one RSA or EC private key
+type Signer struct {
+	key crypto.Signer
+	alg string
+	kid string
+}
+
+// JWK is the public half of a signing key as published in a JWKS
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewSigner parses a PEM private key (PKCS#8, PKCS#1 RSA or SEC 1 EC). RSA keys sign
+// with RS256; P-256 and P-384 keys with ES256 and ES384. Literal "\n" sequences, as
+// multi-line env vars often end up, are treated as newlines
+func NewSigner(pemKey string) (*Signer, error) {
+	block, _ := pem.Decode([]byte(strings.ReplaceAll(pemKey, `\n`, "\n")))
+	if block == nil {
+		return nil, errors.New("signing key is not PEM encoded")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %w", err)
+	}
+
+	s := &Signer{}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA signing key must be at least 2048 bits, got %d", k.N.BitLen())
+		}
+		s.key, s.alg = k, "RS256"
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			s.key, s.alg = k, "ES256"
+		case elliptic.P384():
+			s.key, s.alg = k, "ES384"
+		default:
+			return nil, fmt.Errorf("unsupported EC curve %s", k.Curve.Params().Name)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", key)
+	}
+
+	thumbprint, err := s.thumbprint()
+	if err != nil {
+		return nil, err
+	}
+	s.kid = thumbprint
+	return s, nil
+}
+
+var (
+	loadOnce  sync.Once
+	loaded    *Signer
+	loadError error
+)
+
+// Load returns the signer configured by ISSUE_JWT and JWT_SIGNING_KEY, parsed on first use.
+// It returns a nil signer when token issuance is disabled
+func Load() (*Signer, error) {
+	loadOnce.Do(func() {
+		cfg, err := settings.Load()
+		if err != nil {
+			loadError = err
+			return
+		}
+		if !cfg.IssueJWT {
+			return
+		}
+		loaded, loadError = NewSigner(cfg.JWTSigningKey)
+		if loadError != nil {
+			loadError = fmt.Errorf("invalid JWT_SIGNING_KEY: %w", loadError)
+		}
+	})
+	return loaded, loadError
+}
+
+// Sign returns the compact JWS of claims
+func (s *Signer) Sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": s.alg, "typ": "JWT", "kid": s.kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal claims: %w", err)
+	}
+	signingInput := b64(header) + "." + b64(payload)
+
+	var sig []byte
+	switch k := s.key.(type) {
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signingInput))
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		sig, err = signECDSA(k, s.alg, []byte(signingInput))
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signingInput + "." + b64(sig), nil
+}
+
+// JWKS returns the key set receivers use to verify tokens from this signer
+func (s *Signer) JWKS() JWKS {
+	jwk := s.publicJWK()
+	jwk.Kid = s.kid
+	jwk.Use = "sig"
+	jwk.Alg = s.alg
+	return JWKS{Keys: []JWK{jwk}}
+}
+
+func (s *Signer) publicJWK() JWK {
+	switch k := s.key.(type) {
+	case *rsa.PrivateKey:
+		return JWK{Kty: "RSA", N: b64(k.N.Bytes()), E: b64(big.NewInt(int64(k.E)).Bytes())}
+	case *ecdsa.PrivateKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return JWK{Kty: "EC", Crv: k.Curve.Params().Name, X: b64(k.X.FillBytes(make([]byte, size))), Y: b64(k.Y.FillBytes(make([]byte, size)))}
+	}
+	return JWK{}
+}
+
+// thumbprint is the RFC 7638 SHA-256 thumbprint of the public key, used as the kid
+func (s *Signer) thumbprint() (string, error) {
+	jwk := s.publicJWK()
+	var members string
+	switch jwk.Kty {
+	case "RSA":
+		members = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	case "EC":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, jwk.Crv, jwk.X, jwk.Y)
+	default:
+		return "", errors.New("cannot compute thumbprint of unknown key type")
+	}
+	sum := sha256.Sum256([]byte(members))
+	return b64(sum[:]), nil
+}
+
+// signECDSA produces the fixed-width r||s signature JWS uses instead of ASN.1
+func signECDSA(k *ecdsa.PrivateKey, alg string, input []byte) ([]byte, error) {
+	var digest []byte
+	if alg == "ES384" {
+		sum := sha512.Sum384(input)
+		digest = sum[:]
+	} else {
+		sum := sha256.Sum256(input)
+		digest = sum[:]
+	}
+	r, sVal, err := ecdsa.Sign(rand.Reader, k, digest)
+	if err != nil {
+		return nil, err
+	}
+	size := (k.Curve.Params().BitSize + 7) / 8
+	sig := make([]byte, 2*size)
+	r.FillBytes(sig[:size])
+	sVal.FillBytes(sig[size:])
+	return sig, nil
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -7,6 +7,8 @@ import (
 	"log"
 	"sync"
 
+	"playground/config"
+
 	self "github.com/selfxyz/self/sdk/sdk-go"
 	"github.com/selfxyz/self/sdk/sdk-go/common"
 )
@@ -29,15 +31,12 @@ func (c *CustomConfigStore) GetConfig(ctx context.Context, id string) (self.Veri
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
-	config, exists := c.configs[id]
+	stored, exists := c.configs[id]
 	if !exists {
 		// Return default config for unknown IDs
-		return self.VerificationConfig{
-			MinimumAge: &[]int{18}[0],
-			Ofac:       &[]bool{true}[0],
-		}, nil
+		return config.DefaultVerificationConfig(), nil
 	}
-	return config, nil
+	return stored, nil
 }
 
 // SetConfig stores a configuration with the given ID
@@ -57,11 +56,9 @@ func (c *CustomConfigStore) GetActionId(ctx context.Context, userIdentifier stri
 	// - Generate IDs based on user data
 	// - Apply business logic
 
-	// For this example, we'll create a simple mapping
-	if len(userDefinedData) > 10 {
-		return "premium-user-config", nil
-	}
-	return "standard-user-config", nil
+	// For this example, we'll create a simple mapping (the same one ACTION_ID_STRATEGY=length uses)
+	strategy := config.LengthThresholdStrategy{Threshold: 10, Short: "standard-user-config", Long: "premium-user-config"}
+	return strategy.ActionId(userIdentifier, userDefinedData), nil
 }
 
 // demonstrateVerification shows how to perform a verification (mock example)
@@ -160,6 +157,11 @@ func main() {
 		self.EUCard:   true,
 	}
 
+	userIDType, err := config.UserIDTypeFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Invalid user ID type: %v", err)
+	}
+
 	// Initialize the verifier
 	verifier, err := self.NewBackendVerifier(
 		"custom-config-app",          // App name
@@ -167,7 +169,7 @@ func main() {
 		true,                         // Use testnet
 		allowedIds,
 		configStore,
-		self.UserIDTypeUUID, // Use UUID format for user IDs
+		userIDType, // UUID unless USER_ID_TYPE=hex
 	)
 	if err != nil {
 		log.Fatalf("❌ Failed to create verifier: %v", err)
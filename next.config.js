@@ -4,6 +4,15 @@ const nextConfig = {
   compiler: {
     styledComponents: true,
   },
+  // Serve the Go health probes and the JWKS at their conventional root paths as well as under /api
+  async rewrites() {
+    return [
+      { source: "/healthz", destination: "/api/healthz" },
+      { source: "/readyz", destination: "/api/readyz" },
+      { source: "/health", destination: "/api/health" },
+      { source: "/.well-known/jwks.json", destination: "/api/jwks" },
+    ];
+  },
   webpack: (config, { isServer }) => {
     // Fix for packages that use 'document' in server context
     if (isServer) {
@@ -0,0 +1,379 @@
+// Package settings loads the service's environment toggles once, with validation
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"playground/config"
+	"playground/verification"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+	"github.com/selfxyz/self/sdk/sdk-go/common"
+)
+
+// Values of STALE_OPTIONS_POLICY, what verify does with options older than OPTIONS_MAX_AGE
+const (
+	StaleOptionsFallback = "fallback"
+	StaleOptionsReject   = "reject"
+)
+
+// DefaultProblemTypeBase prefixes error codes to form problem type URIs unless PROBLEM_TYPE_BASE is set
+const DefaultProblemTypeBase = "https://playground.self.xyz/problems/"
+
+// MinRequestTimeout is the floor for request deadlines, configured or client-requested;
+// anything shorter couldn't even reach the config store
+const MinRequestTimeout = 100 * time.Millisecond
+
+const (
+	defaultRequestTimeout     = 30 * time.Second
+	defaultMaxRequestTimeout  = 60 * time.Second
+	defaultAdmissionWait      = 250 * time.Millisecond
+	defaultSlowRequestMs      = 2000
+	defaultAuditLogMaxEntries = 100
+	defaultMaxClockSkew       = 5 * time.Minute
+	defaultPruneInterval      = time.Hour
+	defaultPruneRetention     = 30 * 24 * time.Hour
+)
+
+// Config is the typed view of the environment toggles the handlers read
+type Config struct {
+	// UserIDType is the identifier format verifiers expect (USER_ID_TYPE: uuid or hex)
+	UserIDType self.UserIDType
+	// VerifySink names the verification event sink (VERIFY_SINK: none or stdout)
+	VerifySink string
+	// MaxConcurrentVerifications caps verifications per instance (MAX_CONCURRENT_VERIFICATIONS)
+	MaxConcurrentVerifications int
+	// AuditLogMaxEntries is the number of audit entries kept per user (AUDIT_LOG_MAX_ENTRIES)
+	AuditLogMaxEntries int
+	// ExposeRawDisclosure adds unfiltered disclosures to verify responses (EXPOSE_RAW_DISCLOSURE)
+	ExposeRawDisclosure bool
+	// SkipSaveOptionsSignature disables the saveOptions HMAC check (SAVE_OPTIONS_SKIP_SIGNATURE)
+	SkipSaveOptionsSignature bool
+	// MaxClockSkew is how far a user context timestamp may be from server time (MAX_CLOCK_SKEW)
+	MaxClockSkew time.Duration
+	// VerifyResultCacheTTL is how long verification results are cached; zero disables the cache (VERIFY_RESULT_CACHE_TTL)
+	VerifyResultCacheTTL time.Duration
+	// SaveOptionsSecret is the saveOptions HMAC key (SAVE_OPTIONS_SECRET)
+	SaveOptionsSecret string
+	// AdminAPIToken is the bearer token for admin endpoints (ADMIN_API_TOKEN)
+	AdminAPIToken string
+	// ConfigOverrideSecret is the HMAC key verify requests carrying configOverride must be signed with;
+	// unset rejects every override (CONFIG_OVERRIDE_SECRET)
+	ConfigOverrideSecret string
+	// IssueJWT adds a signed token of the verification result to verify responses (ISSUE_JWT)
+	IssueJWT bool
+	// JWTSigningKey is the PEM RSA or EC private key tokens are signed with (JWT_SIGNING_KEY)
+	JWTSigningKey string
+	// UndisclosedPlaceholder replaces withheld fields in credentialSubject; "__null__" emits
+	// JSON null. Set but empty means an empty string (UNDISCLOSED_PLACEHOLDER)
+	UndisclosedPlaceholder string
+	// RequireNonce rejects verify requests whose JSON object user context has no nonce;
+	// hex-encoded contexts have no nonce field and are unaffected (REQUIRE_NONCE)
+	RequireNonce bool
+	// EnableDebugEndpoints serves the /api/debug endpoints, which 404 otherwise (ENABLE_DEBUG_ENDPOINTS)
+	EnableDebugEndpoints bool
+	// OptionsMaxAge is how old saved options may be when a verification reads them; zero
+	// disables the check (OPTIONS_MAX_AGE)
+	OptionsMaxAge time.Duration
+	// StaleOptionsPolicy is "fallback" to verify with the defaults instead of options older
+	// than OptionsMaxAge, or "reject" to fail the verification (STALE_OPTIONS_POLICY)
+	StaleOptionsPolicy string
+	// RequiredDisclosures are the disclosure flags forced on for every verification; an
+	// unknown flag fails startup (REQUIRED_DISCLOSURES)
+	RequiredDisclosures []string
+	// GlobalExcludedCountries are excluded for every user on top of their own config; an
+	// unknown code fails startup (GLOBAL_EXCLUDED_COUNTRIES)
+	GlobalExcludedCountries []common.Country3LetterCode
+	// StrictJSON rejects request bodies with unknown fields, naming them (STRICT_JSON)
+	StrictJSON bool
+	// MaxPublicSignals caps the publicSignals array, with per-attestation overrides
+	// (MAX_PUBLIC_SIGNALS, MAX_PUBLIC_SIGNALS_<code>)
+	MaxPublicSignals verification.PublicSignalLimits
+	// MaxExcludedCountries caps the distinct countries a stored config may exclude
+	// (MAX_EXCLUDED_COUNTRIES)
+	MaxExcludedCountries int
+	// DefaultMinimumAge and DefaultOfac are the checks applied to IDs with no stored config
+	// (DEFAULT_MIN_AGE, DEFAULT_OFAC)
+	DefaultMinimumAge int
+	DefaultOfac       bool
+	// PruneEnabled runs the background pruner in cmd/server (PRUNE_ENABLED)
+	PruneEnabled bool
+	// PruneInterval is how often the pruner runs (PRUNE_INTERVAL)
+	PruneInterval time.Duration
+	// PruneRetention is how long audit entries are kept before the pruner removes them (PRUNE_RETENTION)
+	PruneRetention time.Duration
+	// ProblemJSON makes RFC 7807 problem+json the error format for every client, not only
+	// those asking for it in Accept (PROBLEM_JSON)
+	ProblemJSON bool
+	// ProblemTypeBase is the URI prefix error codes are mapped under in problem types (PROBLEM_TYPE_BASE)
+	ProblemTypeBase string
+	// RequestTimeout is the deadline of requests that don't ask for one (REQUEST_TIMEOUT)
+	RequestTimeout time.Duration
+	// RequestTimeoutMax caps the deadline a client may ask for in X-Request-Timeout (REQUEST_TIMEOUT_MAX)
+	RequestTimeoutMax time.Duration
+	// MaxInFlight caps the requests the server handles at once; 0 means no cap (MAX_IN_FLIGHT)
+	MaxInFlight int
+	// AdmissionQueueTimeout is how long a request waits for one of the MaxInFlight slots
+	// before it gets 503 (ADMISSION_QUEUE_TIMEOUT)
+	AdmissionQueueTimeout time.Duration
+	// SlowRequest is how long a request may take before it is logged as slow; 0 turns the
+	// log off (SLOW_REQUEST_MS, in milliseconds)
+	SlowRequest time.Duration
+}
+
+// FromEnv parses and validates the environment, reporting every bad value at once
+func FromEnv() (*Config, error) {
+	var errs []error
+	cfg := &Config{
+		VerifySink:        os.Getenv("VERIFY_SINK"),
+		SaveOptionsSecret: os.Getenv("SAVE_OPTIONS_SECRET"),
+		AdminAPIToken:     os.Getenv("ADMIN_API_TOKEN"),
+		JWTSigningKey:     os.Getenv("JWT_SIGNING_KEY"),
+
+		ConfigOverrideSecret: os.Getenv("CONFIG_OVERRIDE_SECRET"),
+		StaleOptionsPolicy:   os.Getenv("STALE_OPTIONS_POLICY"),
+		ProblemTypeBase:      os.Getenv("PROBLEM_TYPE_BASE"),
+	}
+
+	userIDType, err := config.UserIDTypeFromEnv()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	cfg.UserIDType = userIDType
+
+	if cfg.RequiredDisclosures, err = config.RequiredDisclosuresFromEnv(); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.GlobalExcludedCountries, err = config.GlobalExcludedCountriesFromEnv(); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.MaxPublicSignals, err = verification.PublicSignalLimitsFromEnv(); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.MaxExcludedCountries, err = config.MaxExcludedCountriesFromEnv(); err != nil {
+		errs = append(errs, err)
+	}
+	defaults, err := config.DefaultVerificationConfigFromEnv()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	cfg.DefaultMinimumAge, cfg.DefaultOfac = *defaults.MinimumAge, *defaults.Ofac
+
+	switch cfg.VerifySink {
+	case "":
+		cfg.VerifySink = "none"
+	case "none", "stdout":
+	default:
+		errs = append(errs, fmt.Errorf("VERIFY_SINK must be \"none\" or \"stdout\", got %q", cfg.VerifySink))
+	}
+
+	if cfg.ProblemTypeBase == "" {
+		cfg.ProblemTypeBase = DefaultProblemTypeBase
+	}
+
+	switch cfg.StaleOptionsPolicy {
+	case "":
+		cfg.StaleOptionsPolicy = StaleOptionsFallback
+	case StaleOptionsFallback, StaleOptionsReject:
+	default:
+		errs = append(errs, fmt.Errorf("STALE_OPTIONS_POLICY must be %q or %q, got %q", StaleOptionsFallback, StaleOptionsReject, cfg.StaleOptionsPolicy))
+	}
+
+	cfg.MaxConcurrentVerifications = positiveInt("MAX_CONCURRENT_VERIFICATIONS", runtime.NumCPU(), &errs)
+	cfg.AuditLogMaxEntries = positiveInt("AUDIT_LOG_MAX_ENTRIES", defaultAuditLogMaxEntries, &errs)
+	cfg.MaxClockSkew = positiveDuration("MAX_CLOCK_SKEW", defaultMaxClockSkew, &errs)
+	cfg.VerifyResultCacheTTL = nonNegativeDuration("VERIFY_RESULT_CACHE_TTL", &errs)
+	cfg.OptionsMaxAge = nonNegativeDuration("OPTIONS_MAX_AGE", &errs)
+	cfg.RequestTimeout = requestTimeout("REQUEST_TIMEOUT", defaultRequestTimeout, &errs)
+	cfg.RequestTimeoutMax = requestTimeout("REQUEST_TIMEOUT_MAX", defaultMaxRequestTimeout, &errs)
+	cfg.MaxInFlight = nonNegativeInt("MAX_IN_FLIGHT", 0, &errs)
+	cfg.SlowRequest = time.Duration(nonNegativeInt("SLOW_REQUEST_MS", defaultSlowRequestMs, &errs)) * time.Millisecond
+	cfg.AdmissionQueueTimeout = positiveDuration("ADMISSION_QUEUE_TIMEOUT", defaultAdmissionWait, &errs)
+	cfg.ExposeRawDisclosure = boolean("EXPOSE_RAW_DISCLOSURE", &errs)
+	cfg.SkipSaveOptionsSignature = boolean("SAVE_OPTIONS_SKIP_SIGNATURE", &errs)
+	cfg.IssueJWT = boolean("ISSUE_JWT", &errs)
+	cfg.RequireNonce = boolean("REQUIRE_NONCE", &errs)
+	cfg.EnableDebugEndpoints = boolean("ENABLE_DEBUG_ENDPOINTS", &errs)
+	cfg.StrictJSON = boolean("STRICT_JSON", &errs)
+	cfg.PruneEnabled = boolean("PRUNE_ENABLED", &errs)
+	cfg.ProblemJSON = boolean("PROBLEM_JSON", &errs)
+	cfg.PruneInterval = positiveDuration("PRUNE_INTERVAL", defaultPruneInterval, &errs)
+	cfg.PruneRetention = positiveDuration("PRUNE_RETENTION", defaultPruneRetention, &errs)
+	cfg.UndisclosedPlaceholder = verification.NotDisclosed
+	if raw, ok := os.LookupEnv("UNDISCLOSED_PLACEHOLDER"); ok {
+		cfg.UndisclosedPlaceholder = raw
+	}
+	if cfg.IssueJWT && cfg.JWTSigningKey == "" {
+		errs = append(errs, errors.New("ISSUE_JWT requires JWT_SIGNING_KEY"))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid settings: %w", err)
+	}
+	return cfg, nil
+}
+
+var (
+	loadOnce  sync.Once
+	loaded    *Config
+	loadError error
+)
+
+// Load returns the settings parsed on first use; every later call gets the same result
+func Load() (*Config, error) {
+	loadOnce.Do(func() {
+		loaded, loadError = FromEnv()
+		if loadError == nil {
+			log.Printf("Settings: %s", loaded)
+		}
+	})
+	return loaded, loadError
+}
+
+// String describes the effective settings with secrets redacted, for startup logs
+func (c *Config) String() string {
+	userIDType := "uuid"
+	if c.UserIDType == self.UserIDTypeHex {
+		userIDType = "hex"
+	}
+	fields := []string{
+		"userIdType=" + userIDType,
+		"verifySink=" + c.VerifySink,
+		"maxConcurrentVerifications=" + strconv.Itoa(c.MaxConcurrentVerifications),
+		"auditLogMaxEntries=" + strconv.Itoa(c.AuditLogMaxEntries),
+		"maxClockSkew=" + c.MaxClockSkew.String(),
+		"verifyResultCacheTTL=" + c.VerifyResultCacheTTL.String(),
+		"exposeRawDisclosure=" + strconv.FormatBool(c.ExposeRawDisclosure),
+		"skipSaveOptionsSignature=" + strconv.FormatBool(c.SkipSaveOptionsSignature),
+		"saveOptionsSecret=" + redact(c.SaveOptionsSecret),
+		"adminApiToken=" + redact(c.AdminAPIToken),
+		"configOverrideSecret=" + redact(c.ConfigOverrideSecret),
+		"issueJwt=" + strconv.FormatBool(c.IssueJWT),
+		"jwtSigningKey=" + redact(c.JWTSigningKey),
+		"undisclosedPlaceholder=" + strconv.Quote(c.UndisclosedPlaceholder),
+		"requireNonce=" + strconv.FormatBool(c.RequireNonce),
+		"enableDebugEndpoints=" + strconv.FormatBool(c.EnableDebugEndpoints),
+		"optionsMaxAge=" + c.OptionsMaxAge.String(),
+		"staleOptionsPolicy=" + c.StaleOptionsPolicy,
+		"requiredDisclosures=" + strings.Join(c.RequiredDisclosures, ","),
+		"globalExcludedCountries=" + fmt.Sprint(c.GlobalExcludedCountries),
+		"strictJson=" + strconv.FormatBool(c.StrictJSON),
+		"maxPublicSignals=" + strconv.Itoa(c.MaxPublicSignals.Default),
+		"maxExcludedCountries=" + strconv.Itoa(c.MaxExcludedCountries),
+		"defaultMinAge=" + strconv.Itoa(c.DefaultMinimumAge),
+		"defaultOfac=" + strconv.FormatBool(c.DefaultOfac),
+		"pruneEnabled=" + strconv.FormatBool(c.PruneEnabled),
+		"pruneInterval=" + c.PruneInterval.String(),
+		"pruneRetention=" + c.PruneRetention.String(),
+		"problemJson=" + strconv.FormatBool(c.ProblemJSON),
+		"problemTypeBase=" + c.ProblemTypeBase,
+		"requestTimeout=" + c.RequestTimeout.String(),
+		"requestTimeoutMax=" + c.RequestTimeoutMax.String(),
+		"maxInFlight=" + strconv.Itoa(c.MaxInFlight),
+		"admissionQueueTimeout=" + c.AdmissionQueueTimeout.String(),
+		"slowRequest=" + c.SlowRequest.String(),
+	}
+	return strings.Join(fields, " ")
+}
+
+// Policy is the deployment-wide policy the config package applies to saved options
+func (c *Config) Policy() config.Policy {
+	return config.Policy{
+		ExcludedCountries:   c.GlobalExcludedCountries,
+		RequiredDisclosures: c.RequiredDisclosures,
+	}
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return "<unset>"
+	}
+	return "<redacted>"
+}
+
+func positiveInt(name string, fallback int, errs *[]error) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		*errs = append(*errs, fmt.Errorf("%s must be a positive integer, got %q", name, raw))
+		return fallback
+	}
+	return n
+}
+
+func nonNegativeInt(name string, fallback int, errs *[]error) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		*errs = append(*errs, fmt.Errorf("%s must be a non-negative integer, got %q", name, raw))
+		return fallback
+	}
+	return n
+}
+
+func positiveDuration(name string, fallback time.Duration, errs *[]error) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		*errs = append(*errs, fmt.Errorf("%s must be a positive duration such as 5m, got %q", name, raw))
+		return fallback
+	}
+	return d
+}
+
+// requestTimeout reads a request deadline, which must be at least MinRequestTimeout
+func requestTimeout(name string, fallback time.Duration, errs *[]error) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < MinRequestTimeout {
+		*errs = append(*errs, fmt.Errorf("%s must be a duration of at least %s, got %q", name, MinRequestTimeout, raw))
+		return fallback
+	}
+	return d
+}
+
+func nonNegativeDuration(name string, errs *[]error) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		*errs = append(*errs, fmt.Errorf("%s must be a duration such as 30s, got %q", name, raw))
+		return 0
+	}
+	return d
+}
+
+func boolean(name string, errs *[]error) bool {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return false
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("%s must be true or false, got %q", name, raw))
+		return false
+	}
+	return v
+}
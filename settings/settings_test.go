@@ -0,0 +1,77 @@
+package settings
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"playground/verification"
+)
+
+func TestUndisclosedPlaceholder(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  *string
+		want string
+	}{
+		{"unset", nil, verification.NotDisclosed},
+		{"set but empty", strPtr(""), ""},
+		{"null", strPtr(verification.NullPlaceholder), verification.NullPlaceholder},
+		{"custom", strPtr("REDACTED"), "REDACTED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("UNDISCLOSED_PLACEHOLDER", "")
+			if tt.raw == nil {
+				os.Unsetenv("UNDISCLOSED_PLACEHOLDER")
+			} else {
+				os.Setenv("UNDISCLOSED_PLACEHOLDER", *tt.raw)
+			}
+			cfg, err := FromEnv()
+			if err != nil {
+				t.Fatalf("FromEnv: %v", err)
+			}
+			if cfg.UndisclosedPlaceholder != tt.want {
+				t.Errorf("UndisclosedPlaceholder = %q, want %q", cfg.UndisclosedPlaceholder, tt.want)
+			}
+		})
+	}
+}
+
+// TestFromEnvRejects checks that bad values fail startup rather than being ignored per request
+func TestFromEnvRejects(t *testing.T) {
+	tests := []struct {
+		env   string
+		value string
+		// want is part of the error naming the bad value
+		want string
+	}{
+		{"REQUIRED_DISCLOSURES", "nationality,nationalty", `"nationalty"`},
+		{"GLOBAL_EXCLUDED_COUNTRIES", "PRK,RU", `"RU"`},
+		{"STRICT_JSON", "yes", "STRICT_JSON"},
+		{"MAX_PUBLIC_SIGNALS", "0", "MAX_PUBLIC_SIGNALS"},
+		{"MAX_PUBLIC_SIGNALS_1", "many", "MAX_PUBLIC_SIGNALS_1"},
+		{"MAX_EXCLUDED_COUNTRIES", "-3", "MAX_EXCLUDED_COUNTRIES"},
+		{"DEFAULT_MIN_AGE", "150", "DEFAULT_MIN_AGE"},
+		{"DEFAULT_OFAC", "sometimes", "DEFAULT_OFAC"},
+		{"PRUNE_ENABLED", "on", "PRUNE_ENABLED"},
+		{"PRUNE_INTERVAL", "hourly", "PRUNE_INTERVAL"},
+		{"PRUNE_RETENTION", "-1h", "PRUNE_RETENTION"},
+		{"PROBLEM_JSON", "always", "PROBLEM_JSON"},
+		{"REQUEST_TIMEOUT", "50ms", "REQUEST_TIMEOUT"},
+		{"REQUEST_TIMEOUT_MAX", "1 minute", "REQUEST_TIMEOUT_MAX"},
+		{"MAX_IN_FLIGHT", "-1", "MAX_IN_FLIGHT"},
+		{"ADMISSION_QUEUE_TIMEOUT", "0s", "ADMISSION_QUEUE_TIMEOUT"},
+		{"SLOW_REQUEST_MS", "2s", "SLOW_REQUEST_MS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv(tt.env, tt.value)
+			if _, err := FromEnv(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("FromEnv with %s=%q: error = %v, want one mentioning %s", tt.env, tt.value, err, tt.want)
+			}
+		})
+	}
+}
+
+func strPtr(s string) *string { return &s }
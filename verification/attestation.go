@@ -0,0 +1,97 @@
+package verification
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+)
+
+// Attestation describes a document type the verify endpoint understands
+type Attestation struct {
+	ID self.AttestationId
+	// Code is the attestationId value clients send for this document type
+	Code string
+	// Name is the human-readable document type
+	Name string
+	// Aliases are other names clients may send for Code, matched case-insensitively
+	Aliases []string
+	// PublicSignals is the number of public signals the disclose circuit emits
+	PublicSignals int
+	// Fields are the credential fields this document type can disclose. A type registered
+	// without them can't be filtered, so verifying it fails with ATTESTATION_UNSUPPORTED
+	Fields []DisclosureField
+}
+
+// Supported reports whether the disclosure filter knows a's fields
+func (a Attestation) Supported() bool {
+	return len(a.Fields) > 0
+}
+
+// attestations is the single registry of supported document types; adding a type, or
+// filter support for one, means adding or completing its entry here
+var attestations = []Attestation{
+	{ID: self.Passport, Code: "1", Name: "Passport", Aliases: []string{"passport"}, PublicSignals: 21, Fields: disclosureFields},
+	{ID: self.EUCard, Code: "2", Name: "EU ID card", Aliases: []string{"eu_card", "eucard", "eu-card"}, PublicSignals: 19, Fields: disclosureFields},
+}
+
+// LookupAttestation finds the attestation registered under code or one of its aliases
+func LookupAttestation(code string) (Attestation, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	for _, a := range attestations {
+		if a.Code == code {
+			return a, true
+		}
+		for _, alias := range a.Aliases {
+			if alias == code {
+				return a, true
+			}
+		}
+	}
+	return Attestation{}, false
+}
+
+// Attestations returns every registered attestation in registry order
+func Attestations() []Attestation {
+	return append([]Attestation(nil), attestations...)
+}
+
+// AcceptedAttestationIDs lists every code and alias LookupAttestation accepts
+func AcceptedAttestationIDs() []string {
+	var ids []string
+	for _, a := range attestations {
+		ids = append(ids, a.Code)
+		ids = append(ids, a.Aliases...)
+	}
+	return ids
+}
+
+// AllowedAttestations parses ALLOWED_ATTESTATIONS, a comma-separated list of attestation
+// codes or aliases, into the set of allowed codes. Unset allows every registered type.
+// A list that allows nothing is an error, since every verification would then fail
+func AllowedAttestations() (map[string]bool, error) {
+	raw := os.Getenv("ALLOWED_ATTESTATIONS")
+	allowed := make(map[string]bool)
+	if strings.TrimSpace(raw) == "" {
+		for _, a := range attestations {
+			allowed[a.Code] = true
+		}
+		return allowed, nil
+	}
+
+	for _, entry := range strings.Split(raw, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		a, ok := LookupAttestation(entry)
+		if !ok {
+			return nil, fmt.Errorf("ALLOWED_ATTESTATIONS: unknown attestation %q, expected one of: %s", entry, strings.Join(AcceptedAttestationIDs(), ", "))
+		}
+		allowed[a.Code] = true
+	}
+	if len(allowed) == 0 {
+		return nil, fmt.Errorf("ALLOWED_ATTESTATIONS %q allows no attestation types", raw)
+	}
+	return allowed, nil
+}
@@ -0,0 +1,59 @@
+package verification
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLookupAttestation(t *testing.T) {
+	tests := []struct {
+		code     string
+		wantCode string
+	}{
+		{"1", "1"},
+		{"2", "2"},
+		{" 1 ", "1"},
+		{"passport", "1"},
+		{"Passport", "1"},
+		{"PASSPORT", "1"},
+		{"eu_card", "2"},
+		{"EU_CARD", "2"},
+		{"EuCard", "2"},
+		{"eu-card", "2"},
+		{"\teu-card\n", "2"},
+		{"", ""},
+		{"0", ""},
+		{"3", ""},
+		{"passports", ""},
+		{"eu card", ""},
+	}
+	for _, tt := range tests {
+		a, ok := LookupAttestation(tt.code)
+		if ok != (tt.wantCode != "") || a.Code != tt.wantCode {
+			t.Errorf("LookupAttestation(%q) = %q, %v, want %q", tt.code, a.Code, ok, tt.wantCode)
+		}
+	}
+}
+
+func TestAllowedAttestations(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    map[string]bool
+		wantErr bool
+	}{
+		{"", map[string]bool{"1": true, "2": true}, false},
+		{"1", map[string]bool{"1": true}, false},
+		{"PASSPORT", map[string]bool{"1": true}, false},
+		{" passport , Eu-Card ", map[string]bool{"1": true, "2": true}, false},
+		{"1,passport", map[string]bool{"1": true}, false},
+		{"3", nil, true},
+		{" , ", nil, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("ALLOWED_ATTESTATIONS", tt.raw)
+		got, err := AllowedAttestations()
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("AllowedAttestations(%q) = %v, %v, want %v, error %v", tt.raw, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package verification
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"playground/config"
+)
+
+// NotDisclosed replaces the value of every field the user's options don't disclose
+const NotDisclosed = "Not disclosed"
+
+// DisclosureField is one credential field an attestation can disclose
+type DisclosureField struct {
+	// Flag is the option name that enables the field, as in SelfAppDisclosureConfig's JSON
+	Flag string `json:"flag"`
+	// Label is a human-readable name for consent screens
+	Label string `json:"label"`
+	// Output is the name of the field in the SDK's disclose output
+	Output string `json:"-"`
+	// Enabled reads the field's flag from saved options
+	Enabled func(config.SelfAppDisclosureConfig) *bool `json:"-"`
+	// Check validates a disclosed value's format; nil accepts anything
+	Check func(value string) error `json:"-"`
+}
+
+// disclosureFields is the single list of disclosable fields; the verify filter and the
+// attestations endpoint both read it, so they can't disagree
+var disclosureFields = []DisclosureField{
+	{Flag: "issuing_state", Label: "Issuing state", Output: "IssuingState", Enabled: func(c config.SelfAppDisclosureConfig) *bool { return c.IssuingState }},
+	{Flag: "name", Label: "Name", Output: "Name", Enabled: func(c config.SelfAppDisclosureConfig) *bool { return c.Name }},
+	{Flag: "nationality", Label: "Nationality", Output: "Nationality", Enabled: func(c config.SelfAppDisclosureConfig) *bool { return c.Nationality }},
+	{Flag: "date_of_birth", Label: "Date of birth", Output: "DateOfBirth", Enabled: func(c config.SelfAppDisclosureConfig) *bool { return c.DateOfBirth }, Check: checkDate},
+	{Flag: "passport_number", Label: "Document number", Output: "IdNumber", Enabled: func(c config.SelfAppDisclosureConfig) *bool { return c.PassportNumber }},
+	{Flag: "gender", Label: "Gender", Output: "Gender", Enabled: func(c config.SelfAppDisclosureConfig) *bool { return c.Gender }, Check: checkGender},
+	{Flag: "expiry_date", Label: "Expiry date", Output: "ExpiryDate", Enabled: func(c config.SelfAppDisclosureConfig) *bool { return c.ExpiryDate }, Check: checkDate},
+}
+
+// DisclosureEmpty reports whether output, the SDK's disclose output, is nil or has every
+// disclosable field blank
+func DisclosureEmpty(output interface{}) bool {
+	v := reflect.ValueOf(output)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return !v.IsValid() || v.IsZero()
+	}
+	for _, field := range disclosureFields {
+		if f := v.FieldByName(field.Output); f.IsValid() && !f.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// Disclosed reports whether output, the SDK's disclose output, carries a value for field
+func Disclosed(output interface{}, field DisclosureField) bool {
+	v := reflect.ValueOf(output)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+	f := v.FieldByName(field.Output)
+	return f.IsValid() && !f.IsZero()
+}
+
+// CheckDisclosed runs field's format check on its value in output, the SDK's disclose
+// output. Blank values and fields without a check pass
+func CheckDisclosed(output interface{}, field DisclosureField) error {
+	if field.Check == nil {
+		return nil
+	}
+	v := reflect.ValueOf(output)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	f := v.FieldByName(field.Output)
+	if !f.IsValid() || f.Kind() != reflect.String || f.String() == "" {
+		return nil
+	}
+	return field.Check(f.String())
+}
+
+// Withhold overwrites field in subject, a pointer to the SDK's disclose output, with NotDisclosed
+func Withhold(subject interface{}, field DisclosureField) {
+	v := reflect.ValueOf(subject)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	v = v.FieldByName(field.Output)
+	if v.IsValid() && v.Kind() == reflect.String && v.CanSet() {
+		v.SetString(NotDisclosed)
+	}
+}
+
+// NullPlaceholder is the UNDISCLOSED_PLACEHOLDER value that renders withheld fields as JSON null
+const NullPlaceholder = "__null__"
+
+// Placeholder is what a withheld field carries in the response
+type Placeholder struct {
+	Value string
+	Null  bool
+}
+
+// ParsePlaceholder reads an UNDISCLOSED_PLACEHOLDER value
+func ParsePlaceholder(raw string) Placeholder {
+	if raw == NullPlaceholder {
+		return Placeholder{Null: true}
+	}
+	return Placeholder{Value: raw}
+}
+
+// IsDefault reports whether p is the NotDisclosed sentinel Withhold already writes
+func (p Placeholder) IsDefault() bool {
+	return !p.Null && p.Value == NotDisclosed
+}
+
+// RenderWithheld returns subject, the SDK's disclose output, as a map keyed like its JSON.
+// Every field whose flag is false in disclosure is left out when omit is set, and set to
+// placeholder otherwise
+func RenderWithheld(subject interface{}, fields []DisclosureField, disclosure map[string]bool, placeholder Placeholder, omit bool) (map[string]interface{}, error) {
+	data, err := json.Marshal(subject)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeOf(subject)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return out, nil
+	}
+	for _, field := range fields {
+		if disclosure[field.Flag] {
+			continue
+		}
+		f, ok := t.FieldByName(field.Output)
+		if !ok {
+			continue
+		}
+		switch name := jsonFieldName(f); {
+		case omit:
+			delete(out, name)
+		case placeholder.Null:
+			out[name] = nil
+		default:
+			out[name] = placeholder.Value
+		}
+	}
+	return out, nil
+}
+
+// jsonFieldName is the key encoding/json uses for f
+func jsonFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
@@ -0,0 +1,42 @@
+package verification
+
+import (
+	"testing"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+)
+
+func TestCheckDisclosed(t *testing.T) {
+	fields := map[string]DisclosureField{}
+	for _, field := range disclosureFields {
+		fields[field.Flag] = field
+	}
+	tests := []struct {
+		flag    string
+		output  self.GenericDiscloseOutput
+		wantErr bool
+	}{
+		{"date_of_birth", self.GenericDiscloseOutput{DateOfBirth: "1990-01-02"}, false},
+		{"date_of_birth", self.GenericDiscloseOutput{DateOfBirth: "02-01-90"}, false},
+		{"date_of_birth", self.GenericDiscloseOutput{DateOfBirth: "900102"}, false},
+		{"date_of_birth", self.GenericDiscloseOutput{DateOfBirth: "02-01-1990"}, false},
+		{"date_of_birth", self.GenericDiscloseOutput{DateOfBirth: ""}, false},
+		{"date_of_birth", self.GenericDiscloseOutput{DateOfBirth: "1990-13-45"}, true},
+		{"date_of_birth", self.GenericDiscloseOutput{DateOfBirth: "\x00\x00"}, true},
+		{"expiry_date", self.GenericDiscloseOutput{ExpiryDate: "2030-01-01"}, false},
+		{"expiry_date", self.GenericDiscloseOutput{ExpiryDate: "someday"}, true},
+		{"gender", self.GenericDiscloseOutput{Gender: "M"}, false},
+		{"gender", self.GenericDiscloseOutput{Gender: "f"}, false},
+		{"gender", self.GenericDiscloseOutput{Gender: "<"}, false},
+		{"gender", self.GenericDiscloseOutput{Gender: "Female"}, false},
+		{"gender", self.GenericDiscloseOutput{Gender: "Q"}, true},
+		// Fields without a check accept anything
+		{"name", self.GenericDiscloseOutput{Name: "\x00"}, false},
+	}
+	for _, tt := range tests {
+		err := CheckDisclosed(tt.output, fields[tt.flag])
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckDisclosed(%s of %+v) = %v, want error %v", tt.flag, tt.output, err, tt.wantErr)
+		}
+	}
+}
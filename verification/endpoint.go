@@ -0,0 +1,68 @@
+package verification
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// EndpointHosts are the host patterns verifier endpoints may use. A pattern is a host name,
+// or "*." followed by a domain to match any subdomain of it. Empty allows every host
+type EndpointHosts []string
+
+// AllowedEndpointHosts parses ALLOWED_ENDPOINT_HOSTS, a comma-separated list of host patterns
+// such as "playground.self.xyz,*.vercel.app"
+func AllowedEndpointHosts() EndpointHosts {
+	var hosts EndpointHosts
+	for _, pattern := range strings.Split(os.Getenv("ALLOWED_ENDPOINT_HOSTS"), ",") {
+		if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+			hosts = append(hosts, pattern)
+		}
+	}
+	return hosts
+}
+
+// Allows reports whether the host of endpoint, an absolute URL, matches one of the patterns
+// The port is ignored, so patterns name hosts only
+func (h EndpointHosts) Allows(endpoint string) bool {
+	if len(h) == 0 {
+		return true
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range h {
+		if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// LogVerifierEndpoints logs the scope and endpoint each attestation type verifies with,
+// so a wrong endpoint shows up in the startup logs rather than as failing proofs
+func LogVerifierEndpoints(params map[string]VerifierParams, hosts EndpointHosts) {
+	codes := make([]string, 0, len(params))
+	for code := range params {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		endpoint := params[code].Endpoint
+		if endpoint == "" {
+			endpoint = "derived from each request's host"
+		}
+		log.Printf("Verifier for attestation %s: scope=%q endpoint=%s", code, params[code].Scope, endpoint)
+	}
+	if len(hosts) > 0 {
+		log.Printf("Verifier endpoints restricted to hosts: %s", strings.Join(hosts, ", "))
+	}
+}
@@ -0,0 +1,79 @@
+package verification
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAllowedEndpointHosts(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want EndpointHosts
+	}{
+		{"", nil},
+		{" , ", nil},
+		{"playground.self.xyz", EndpointHosts{"playground.self.xyz"}},
+		{" Playground.Self.xyz , *.Vercel.app ", EndpointHosts{"playground.self.xyz", "*.vercel.app"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("ALLOWED_ENDPOINT_HOSTS", tt.raw)
+		if got := AllowedEndpointHosts(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("AllowedEndpointHosts(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestEndpointHostsAllows(t *testing.T) {
+	hosts := EndpointHosts{"playground.self.xyz", "*.vercel.app"}
+	tests := []struct {
+		endpoint string
+		want     bool
+	}{
+		{"https://playground.self.xyz/api/go-verify", true},
+		{"https://PLAYGROUND.self.xyz/api/go-verify", true},
+		{"https://playground.self.xyz:8443/api/go-verify", true},
+		{"https://preview-123.vercel.app/api/go-verify", true},
+		{"https://a.b.vercel.app/api/go-verify", true},
+		// The wildcard only matches subdomains, not the domain itself
+		{"https://vercel.app/api/go-verify", false},
+		{"https://evilvercel.app/api/go-verify", false},
+		{"https://self.xyz/api/go-verify", false},
+		{"https://playground.self.xyz.evil.com/api/go-verify", false},
+		{"https://evil.com/playground.self.xyz", false},
+		{"https://user@evil.com/api/go-verify", false},
+		{"http://[::1/api/go-verify", false},
+	}
+	for _, tt := range tests {
+		if got := hosts.Allows(tt.endpoint); got != tt.want {
+			t.Errorf("Allows(%q) = %v, want %v", tt.endpoint, got, tt.want)
+		}
+	}
+
+	// No patterns allow every host
+	if !EndpointHosts(nil).Allows("https://anything.example/api/go-verify") {
+		t.Error("empty EndpointHosts rejected an endpoint")
+	}
+}
+
+func TestValidateVerifierParamsEndpointHosts(t *testing.T) {
+	hosts := EndpointHosts{"playground.self.xyz"}
+	allowed := map[string]bool{"1": true}
+	tests := []struct {
+		endpoint string
+		wantErr  bool
+	}{
+		{"", false},
+		{"https://playground.self.xyz/api/go-verify", false},
+		{"https://staging.self.xyz/api/go-verify", true},
+	}
+	for _, tt := range tests {
+		err := ValidateVerifierParams(map[string]VerifierParams{"1": {Scope: "a", Endpoint: tt.endpoint}}, allowed, hosts)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateVerifierParams with endpoint %q = %v, want error %v", tt.endpoint, err, tt.wantErr)
+		}
+		if err != nil && !strings.Contains(err.Error(), "not on ALLOWED_ENDPOINT_HOSTS") {
+			t.Errorf("ValidateVerifierParams = %v, want it to name ALLOWED_ENDPOINT_HOSTS", err)
+		}
+	}
+}
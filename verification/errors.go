@@ -0,0 +1,27 @@
+package verification
+
+// Error codes reported to clients in verify responses and recorded in audit entries
+const (
+	// ErrorCodeVerificationFailed means the verifier returned an error, e.g. an unreachable RPC node
+	ErrorCodeVerificationFailed = "VERIFICATION_FAILED"
+	// ErrorCodeInvalidProof means verification completed but the proof was rejected
+	ErrorCodeInvalidProof = "INVALID_PROOF"
+	// ErrorCodeAttestationNotAllowed means the attestation type is valid but disabled on this deployment
+	ErrorCodeAttestationNotAllowed = "ATTESTATION_NOT_ALLOWED"
+	// ErrorCodeAttestationUnsupported means the attestation type is allowed but has no disclosure fields registered
+	ErrorCodeAttestationUnsupported = "ATTESTATION_UNSUPPORTED"
+	// ErrorCodeStaleContext means the user context timestamp is outside MAX_CLOCK_SKEW
+	ErrorCodeStaleContext = "STALE_CONTEXT"
+	// ErrorCodeEmptyDisclosure means a valid proof came back without any disclosed data
+	ErrorCodeEmptyDisclosure = "EMPTY_DISCLOSURE"
+	// ErrorCodeRequiredDisclosureMissing means a field in REQUIRED_DISCLOSURES wasn't disclosed in the proof
+	ErrorCodeRequiredDisclosureMissing = "REQUIRED_DISCLOSURE_MISSING"
+	// ErrorCodeNonceRequired means REQUIRE_NONCE is set and the user context carried no nonce
+	ErrorCodeNonceRequired = "NONCE_REQUIRED"
+	// ErrorCodeOptionsExpired means the saved options are older than OPTIONS_MAX_AGE and STALE_OPTIONS_POLICY=reject
+	ErrorCodeOptionsExpired = "OPTIONS_EXPIRED"
+	// ErrorCodeUserBlocked means the user ID is on BLOCKED_USER_IDS or the stored block list
+	ErrorCodeUserBlocked = "USER_BLOCKED"
+	// ErrorCodeUserNotAllowed means an allow list is active and the user ID isn't on it
+	ErrorCodeUserNotAllowed = "USER_NOT_ALLOWED"
+)
@@ -0,0 +1,182 @@
+package verification
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"playground/config"
+
+	"github.com/selfxyz/self/sdk/sdk-go/common"
+)
+
+// Nationality formats accepted in the nationality_format option
+const (
+	NationalityFormatName = "name"
+	NationalityFormatISO3 = "iso3"
+)
+
+// Excluded country formats accepted in the excludedCountriesFormat option
+const (
+	ExcludedCountriesFormatCode = "code"
+	ExcludedCountriesFormatName = "name"
+	ExcludedCountriesFormatBoth = "both"
+)
+
+// Date of birth formats accepted in the date_of_birth_format option
+const (
+	DateOfBirthFormatDate = "date"
+	DateOfBirthFormatAge  = "age"
+	DateOfBirthFormatBoth = "both"
+)
+
+// dateOfBirthLayouts are the date of birth shapes the SDK and MRZ produce; two-digit
+// years are resolved to the most recent century that doesn't put the birth in the future
+var dateOfBirthLayouts = []struct {
+	layout       string
+	twoDigitYear bool
+}{
+	{"02-01-06", true},
+	{"060102", true},
+	{"2006-01-02", false},
+	{"02-01-2006", false},
+}
+
+// genders are the values the MRZ sex field can hold, "<" being unspecified
+var genders = map[string]bool{"M": true, "F": true, "X": true, "<": true, "MALE": true, "FEMALE": true}
+
+// checkDate accepts the date shapes in dateOfBirthLayouts
+func checkDate(value string) error {
+	for _, l := range dateOfBirthLayouts {
+		if _, err := time.Parse(l.layout, value); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not a recognized date", value)
+}
+
+// checkGender accepts the MRZ sex codes, case-insensitively
+func checkGender(value string) error {
+	if !genders[strings.ToUpper(strings.TrimSpace(value))] {
+		return fmt.Errorf("%q is not a recognized gender", value)
+	}
+	return nil
+}
+
+// CountryRef is an excluded country in the "both" format
+type CountryRef struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// FormatExcludedCountries renders excluded country codes for the verify response: codes
+// (the default), display names, or {code, name} objects. Codes without a known name
+// fall back to the code. Nil input stays nil so the response shape is unchanged
+func FormatExcludedCountries(codes []common.Country3LetterCode, format string) (interface{}, string) {
+	if codes == nil {
+		return nil, ""
+	}
+	nameOf := func(code common.Country3LetterCode) string {
+		if name, ok := config.CountryName(code); ok {
+			return name
+		}
+		return string(code)
+	}
+
+	var warning string
+	switch format {
+	case ExcludedCountriesFormatName:
+		names := make([]string, len(codes))
+		for i, code := range codes {
+			names[i] = nameOf(code)
+		}
+		return names, ""
+	case ExcludedCountriesFormatBoth:
+		refs := make([]CountryRef, len(codes))
+		for i, code := range codes {
+			refs[i] = CountryRef{Code: string(code), Name: nameOf(code)}
+		}
+		return refs, ""
+	case "", ExcludedCountriesFormatCode:
+	default:
+		warning = fmt.Sprintf("unknown excludedCountriesFormat %q, excluded countries returned as codes", format)
+	}
+	out := make([]string, len(codes))
+	for i, code := range codes {
+		out[i] = string(code)
+	}
+	return out, warning
+}
+
+// FormatNationality converts a disclosed nationality to the requested format
+// An empty format keeps the SDK value; values that can't be mapped are returned unchanged
+// together with a warning for the response
+func FormatNationality(value, format string) (string, string) {
+	switch format {
+	case "":
+		return value, ""
+	case NationalityFormatName:
+		if name, ok := config.CountryName(common.Country3LetterCode(value)); ok {
+			return name, ""
+		}
+		if _, ok := config.CountryCode(value); ok {
+			return value, ""
+		}
+	case NationalityFormatISO3:
+		if _, ok := config.CountryName(common.Country3LetterCode(value)); ok {
+			return value, ""
+		}
+		if code, ok := config.CountryCode(value); ok {
+			return string(code), ""
+		}
+	default:
+		return value, fmt.Sprintf("unknown nationality_format %q, nationality returned as disclosed", format)
+	}
+	return value, fmt.Sprintf("nationality %q could not be converted to %s", value, format)
+}
+
+// FormatDateOfBirth applies the date_of_birth_format option to a disclosed date of birth.
+// It returns the value to disclose and, for "age" and "both", the age in years at now.
+// With "age" the date itself is replaced by NotDisclosed; a date that can't be parsed
+// yields no age and a warning, and is withheld as well unless "both" asked for the date
+func FormatDateOfBirth(value, format string, now time.Time) (string, *int, string) {
+	switch format {
+	case "", DateOfBirthFormatDate:
+		return value, nil, ""
+	case DateOfBirthFormatAge, DateOfBirthFormatBoth:
+	default:
+		return value, nil, fmt.Sprintf("unknown date_of_birth_format %q, date of birth returned as disclosed", format)
+	}
+
+	disclosed := value
+	if format == DateOfBirthFormatAge {
+		disclosed = NotDisclosed
+	}
+	age, ok := ageAt(value, now)
+	if !ok {
+		return disclosed, nil, "date of birth could not be converted to an age"
+	}
+	return disclosed, &age, ""
+}
+
+// ageAt parses a date of birth and returns the completed years at now
+func ageAt(value string, now time.Time) (int, bool) {
+	for _, l := range dateOfBirthLayouts {
+		dob, err := time.Parse(l.layout, value)
+		if err != nil {
+			continue
+		}
+		if l.twoDigitYear && dob.After(now) {
+			dob = dob.AddDate(-100, 0, 0)
+		}
+		if dob.After(now) {
+			return 0, false
+		}
+		age := now.Year() - dob.Year()
+		if now.Month() < dob.Month() || (now.Month() == dob.Month() && now.Day() < dob.Day()) {
+			age--
+		}
+		return age, true
+	}
+	return 0, false
+}
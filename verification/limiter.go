@@ -0,0 +1,34 @@
+package verification
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// acquireWait is how long a request queues for a verification slot before it is turned away
+const acquireWait = 2 * time.Second
+
+// Limiter caps how many CPU-heavy verifications run at once on this instance
+type Limiter struct {
+	sem *semaphore.Weighted
+}
+
+// NewLimiter allows at most limit verifications at once
+func NewLimiter(limit int) *Limiter {
+	return &Limiter{sem: semaphore.NewWeighted(int64(limit))}
+}
+
+// Acquire waits briefly for a verification slot and reports whether one was obtained
+// Callers that get true must call Release when the verification finishes
+func (l *Limiter) Acquire(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, acquireWait)
+	defer cancel()
+	return l.sem.Acquire(ctx, 1) == nil
+}
+
+// Release frees a slot obtained with Acquire
+func (l *Limiter) Release() {
+	l.sem.Release(1)
+}
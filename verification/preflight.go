@@ -0,0 +1,64 @@
+package verification
+
+import (
+	"fmt"
+)
+
+// ValidateProofShape checks the raw proof has non-empty a/b/c components before it reaches the verifier
+// Both the SDK field names (a, b, c) and the snarkjs names (pi_a, pi_b, pi_c) are accepted
+func ValidateProofShape(proof interface{}) error {
+	fields, ok := proof.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("proof must be an object")
+	}
+
+	for _, name := range []string{"a", "b", "c"} {
+		component, ok := fields[name]
+		if !ok {
+			component, ok = fields["pi_"+name]
+		}
+		if !ok {
+			return fmt.Errorf("proof is missing component %s", name)
+		}
+
+		if name == "b" {
+			rows, ok := component.([]interface{})
+			if !ok || len(rows) < 2 {
+				return fmt.Errorf("proof component b must hold at least 2 coordinate pairs")
+			}
+			for i, row := range rows[:2] {
+				if err := validateCoordinates(row, 2); err != nil {
+					return fmt.Errorf("proof component b[%d]: %w", i, err)
+				}
+			}
+			continue
+		}
+		if err := validateCoordinates(component, 2); err != nil {
+			return fmt.Errorf("proof component %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validateCoordinates checks v is an array whose first n entries are non-empty strings
+func validateCoordinates(v interface{}, n int) error {
+	values, ok := v.([]interface{})
+	if !ok || len(values) < n {
+		return fmt.Errorf("expected at least %d values", n)
+	}
+	for i, value := range values[:n] {
+		if s, ok := value.(string); !ok || s == "" {
+			return fmt.Errorf("value %d must be a non-empty string", i)
+		}
+	}
+	return nil
+}
+
+// ValidatePublicSignals checks the signal count matches what the attestation's circuit emits
+func ValidatePublicSignals(attestation Attestation, publicSignals []string) error {
+	if len(publicSignals) != attestation.PublicSignals {
+		return fmt.Errorf("expected %d public signals for attestation %s, got %d",
+			attestation.PublicSignals, attestation.Code, len(publicSignals))
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package verification
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxPublicSignals caps the publicSignals array unless MAX_PUBLIC_SIGNALS says otherwise
+const defaultMaxPublicSignals = 64
+
+// PublicSignalLimits is how many public signals a request may carry, parsed once at startup
+// by PublicSignalLimitsFromEnv. The zero value applies the default cap to every attestation
+type PublicSignalLimits struct {
+	// Default applies to attestations without an override (MAX_PUBLIC_SIGNALS)
+	Default int
+	// PerAttestation overrides Default by attestation code (MAX_PUBLIC_SIGNALS_<code>)
+	PerAttestation map[string]int
+}
+
+// For returns the limit for attestation: its override, then Default, then 64
+func (l PublicSignalLimits) For(attestation Attestation) int {
+	if limit, ok := l.PerAttestation[attestation.Code]; ok {
+		return limit
+	}
+	if l.Default > 0 {
+		return l.Default
+	}
+	return defaultMaxPublicSignals
+}
+
+// PublicSignalLimitsFromEnv parses MAX_PUBLIC_SIGNALS and MAX_PUBLIC_SIGNALS_<code>, e.g.
+// MAX_PUBLIC_SIGNALS_1, for every registered attestation. Each set value must be a positive
+// integer; every bad one is reported
+func PublicSignalLimitsFromEnv() (PublicSignalLimits, error) {
+	limits := PublicSignalLimits{Default: defaultMaxPublicSignals, PerAttestation: make(map[string]int)}
+	var errs []error
+	parse := func(name string) (int, bool) {
+		raw := os.Getenv(name)
+		if raw == "" {
+			return 0, false
+		}
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			errs = append(errs, fmt.Errorf("%s must be a positive integer, got %q", name, raw))
+			return 0, false
+		}
+		return limit, true
+	}
+	if limit, ok := parse("MAX_PUBLIC_SIGNALS"); ok {
+		limits.Default = limit
+	}
+	for _, a := range attestations {
+		if limit, ok := parse("MAX_PUBLIC_SIGNALS_" + a.Code); ok {
+			limits.PerAttestation[a.Code] = limit
+		}
+	}
+	return limits, errors.Join(errs...)
+}
+
+// ParsePublicSignals decodes a publicSignals array of at most limit entries, stopping as
+// soon as the limit is passed. Entries may be decimal strings or JSON numbers, as some
+// clients send [1,2,3]; numbers are kept as json.Number and converted to their exact
+// decimal string, so 256-bit field elements don't pass through float64.
+// Any other entry type is rejected with its index
+func ParsePublicSignals(raw json.RawMessage, limit int) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, fmt.Errorf("publicSignals must be an array")
+	}
+
+	var signals []string
+	for i := 0; dec.More(); i++ {
+		if i == limit {
+			return nil, fmt.Errorf("at most %d public signals are accepted", limit)
+		}
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("publicSignals[%d]: %w", i, err)
+		}
+		switch v := value.(type) {
+		case string:
+			signals = append(signals, v)
+		case json.Number:
+			s, err := decimalString(v)
+			if err != nil {
+				return nil, fmt.Errorf("publicSignals[%d]: %w", i, err)
+			}
+			signals = append(signals, s)
+		default:
+			return nil, fmt.Errorf("publicSignals[%d] must be a string or number, got %s", i, jsonKind(value))
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("publicSignals must be an array")
+	}
+	return signals, nil
+}
+
+// maxSignalDigits bounds the decimal length of a numeric signal. Every public signal is a
+// BN254 field element, which is below 2^254 and so at most 77 digits long
+const maxSignalDigits = 78
+
+// maxSignal is the smallest value with more than maxSignalDigits digits
+var maxSignal = new(big.Float).SetPrec(1024).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(maxSignalDigits), nil))
+
+// decimalString writes n as a plain base-10 integer, accepting exponent forms such as 1e3
+// as long as they denote an integer. Values that can't be a field element are rejected
+// before being expanded, so 1e10000000 costs no more than 1e3
+func decimalString(n json.Number) (string, error) {
+	s := n.String()
+	if !strings.ContainsAny(s, ".eE") {
+		if len(strings.TrimPrefix(s, "-")) > maxSignalDigits {
+			return "", fmt.Errorf("%s has more than %d digits", s, maxSignalDigits)
+		}
+		if i, ok := new(big.Int).SetString(s, 10); ok {
+			return i.String(), nil
+		}
+	}
+	if len(s) > 2*maxSignalDigits {
+		return "", fmt.Errorf("%s is too long", s)
+	}
+	f, ok := new(big.Float).SetPrec(1024).SetString(s)
+	if !ok {
+		return "", fmt.Errorf("%s is not an integer", s)
+	}
+	if new(big.Float).Abs(f).Cmp(maxSignal) >= 0 {
+		return "", fmt.Errorf("%s has more than %d digits", s, maxSignalDigits)
+	}
+	// A non-zero mantissa that parsed as zero underflowed, e.g. 1e-999999999
+	mantissa, _, _ := strings.Cut(strings.ToLower(s), "e")
+	underflow := f.Sign() == 0 && strings.Trim(mantissa, "-0.") != ""
+	if underflow || !f.IsInt() {
+		return "", fmt.Errorf("%s is not an integer", s)
+	}
+	i, _ := f.Int(nil)
+	return i.String(), nil
+}
+
+// jsonKind names the JSON type of a decoded value for error messages
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
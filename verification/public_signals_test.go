@@ -0,0 +1,177 @@
+package verification
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePublicSignals(t *testing.T) {
+	const field = "21888242871839275222246405745257275088548364400416034343698204186575808495616"
+
+	tests := []struct {
+		name string
+		raw  string
+		want []string
+	}{
+		{"strings", `["1","2","3"]`, []string{"1", "2", "3"}},
+		{"small integers", `[1,2,3]`, []string{"1", "2", "3"}},
+		{"mixed", `["7",8]`, []string{"7", "8"}},
+		{"256-bit integer", `[` + field + `]`, []string{field}},
+		{"exponent", `[1e3]`, []string{"1000"}},
+		{"exponent with fraction", `[1.5e2]`, []string{"150"}},
+		{"largest exponent", `[1e77]`, []string{"1" + strings.Repeat("0", 77)}},
+		{"zero with huge exponent", `[0.0e-999999999]`, []string{"0"}},
+		{"empty", `[]`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePublicSignals(json.RawMessage(tt.raw), 64)
+			if err != nil {
+				t.Fatalf("ParsePublicSignals(%s): %v", tt.raw, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePublicSignals(%s) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePublicSignalsRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"not an array", `{"0":"1"}`, "must be an array"},
+		{"boolean", `["1",true]`, "publicSignals[1] must be a string or number, got boolean"},
+		{"null", `[null]`, "got null"},
+		{"object", `[{}]`, "got object"},
+		{"array", `[[1]]`, "got array"},
+		{"fraction", `[1.5]`, "is not an integer"},
+		{"huge exponent", `[1e10000000]`, "more than 78 digits"},
+		{"exponent overflow", `[1e99999999999999999999]`, "is not an integer"},
+		{"exponent underflow", `[1e-999999999]`, "is not an integer"},
+		{"just too many digits", `[1e78]`, "more than 78 digits"},
+		{"79-digit integer", `[` + strings.Repeat("9", 79) + `]`, "more than 78 digits"},
+		{"long mantissa", `[0.` + strings.Repeat("0", 200) + `1e201]`, "is too long"},
+		{"truncated", `["1",`, "publicSignals[1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePublicSignals(json.RawMessage(tt.raw), 64)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParsePublicSignals(%.40s) error = %v, want it to contain %q", tt.raw, err, tt.want)
+			}
+		})
+	}
+}
+
+// signalsArray is a JSON array of n small string signals
+func signalsArray(n int) json.RawMessage {
+	if n == 0 {
+		return json.RawMessage(`[]`)
+	}
+	return json.RawMessage(`[` + strings.TrimSuffix(strings.Repeat(`"1",`, n), ",") + `]`)
+}
+
+func TestParsePublicSignalsLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		count   int
+		limit   int
+		wantErr bool
+	}{
+		{"under the limit", 20, 21, false},
+		{"at the limit", 21, 21, false},
+		{"just over the limit", 22, 21, true},
+		{"at the default cap", defaultMaxPublicSignals, defaultMaxPublicSignals, false},
+		{"just over the default cap", defaultMaxPublicSignals + 1, defaultMaxPublicSignals, true},
+		{"limit of one", 1, 1, false},
+		{"two over a limit of one", 2, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePublicSignals(signalsArray(tt.count), tt.limit)
+			if tt.wantErr {
+				if want := fmt.Sprintf("at most %d public signals", tt.limit); err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("ParsePublicSignals(%d signals, %d) error = %v, want %q", tt.count, tt.limit, err, want)
+				}
+				return
+			}
+			if err != nil || len(got) != tt.count {
+				t.Errorf("ParsePublicSignals(%d signals, %d) = %d signals, %v", tt.count, tt.limit, len(got), err)
+			}
+		})
+	}
+}
+
+func TestPublicSignalLimits(t *testing.T) {
+	passport, _ := LookupAttestation("1")
+	card, _ := LookupAttestation("2")
+	tests := []struct {
+		name                 string
+		global, passport     string
+		wantPassport, wantEU int
+		wantErr              string
+	}{
+		{"unset", "", "", defaultMaxPublicSignals, defaultMaxPublicSignals, ""},
+		{"global", "30", "", 30, 30, ""},
+		{"per-type override", "30", "25", 25, 30, ""},
+		{"per-type only", "", "25", 25, defaultMaxPublicSignals, ""},
+		{"invalid per-type", "30", "many", 0, 0, "MAX_PUBLIC_SIGNALS_1"},
+		{"zero per-type", "30", "0", 0, 0, "MAX_PUBLIC_SIGNALS_1"},
+		{"invalid global", "-1", "", 0, 0, "MAX_PUBLIC_SIGNALS must"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAX_PUBLIC_SIGNALS", tt.global)
+			t.Setenv("MAX_PUBLIC_SIGNALS_1", tt.passport)
+			t.Setenv("MAX_PUBLIC_SIGNALS_2", "")
+			limits, err := PublicSignalLimitsFromEnv()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("PublicSignalLimitsFromEnv error = %v, want one naming %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PublicSignalLimitsFromEnv: %v", err)
+			}
+			if got := limits.For(passport); got != tt.wantPassport {
+				t.Errorf("limit for passport = %d, want %d", got, tt.wantPassport)
+			}
+			if got := limits.For(card); got != tt.wantEU {
+				t.Errorf("limit for EU card = %d, want %d", got, tt.wantEU)
+			}
+		})
+	}
+	if got := (PublicSignalLimits{}).For(passport); got != defaultMaxPublicSignals {
+		t.Errorf("zero limits give %d, want the default %d", got, defaultMaxPublicSignals)
+	}
+}
+
+// BenchmarkParsePublicSignals covers the circuit's 21 signals and an array at the default cap
+func BenchmarkParsePublicSignals(b *testing.B) {
+	const field = "21888242871839275222246405745257275088548364400416034343698204186575808495616"
+	for _, bc := range []struct {
+		name  string
+		count int
+	}{
+		{"21 signals", 21},
+		{"64 signals", defaultMaxPublicSignals},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			raw := json.RawMessage(`["` + strings.TrimSuffix(strings.Repeat(field+`",`+`"`, bc.count), `,"`) + `]`)
+			b.SetBytes(int64(len(raw)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ParsePublicSignals(raw, defaultMaxPublicSignals); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package verification
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+
+	"playground/config"
+)
+
+// ResultCacheStore is the storage a ResultCache needs; config.ConfigStore satisfies it
+type ResultCacheStore interface {
+	GetValue(ctx context.Context, key string) (string, bool, error)
+	SetWithExpiration(ctx context.Context, key string, value string, expiration time.Duration) error
+}
+
+// Fingerprint returns a version of the config that applies to userID, so cached
+// results are only reused while the config they were decided under is unchanged
+type Fingerprint func(ctx context.Context, userID string) (string, error)
+
+// ResultCache short-circuits repeat verifications of an identical proof
+// A nil *ResultCache is valid and never hits, which is how the cache is turned off.
+// Entries hold the full result including disclosed fields, so keep the TTL short
+type ResultCache struct {
+	store ResultCacheStore
+	ttl   time.Duration
+}
+
+type cachedResult struct {
+	UserID      string                   `json:"userId"`
+	Fingerprint string                   `json:"fingerprint"`
+	Result      *self.VerificationResult `json:"result"`
+}
+
+// NewResultCache caches results in store for ttl
+func NewResultCache(store ResultCacheStore, ttl time.Duration) *ResultCache {
+	return &ResultCache{store: store, ttl: ttl}
+}
+
+// ResultCacheKey hashes everything the verifier sees, so only an identical request can hit
+func ResultCacheKey(attestationCode string, proof self.VcAndDiscloseProof, publicSignals []string, userContextData string) (string, error) {
+	proofJSON, err := json.Marshal(proof)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, part := range []string{attestationCode, string(proofJSON), strings.Join(publicSignals, ","), userContextData} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return config.ResultCachePrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ConfigFingerprint hashes a resolved config for use as a Fingerprint result
+func ConfigFingerprint(cfg self.VerificationConfig) (string, error) {
+	cfgJSON, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(cfgJSON)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Get returns the cached result for key if the user's config still has the fingerprint
+// it was verified under. Cache errors count as misses
+func (c *ResultCache) Get(ctx context.Context, key string, fingerprint Fingerprint) (*self.VerificationResult, bool) {
+	if c == nil {
+		return nil, false
+	}
+	raw, ok, err := c.store.GetValue(ctx, key)
+	if err != nil {
+		log.Printf("Failed to read verification result cache: %v", err)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+
+	var entry cachedResult
+	if err := json.Unmarshal([]byte(raw), &entry); err != nil || entry.Result == nil {
+		return nil, false
+	}
+	current, err := fingerprint(ctx, entry.UserID)
+	if err != nil || current != entry.Fingerprint {
+		return nil, false
+	}
+	return entry.Result, true
+}
+
+// Put caches result under key along with the fingerprint of the user's current config
+func (c *ResultCache) Put(ctx context.Context, key string, result *self.VerificationResult, fingerprint Fingerprint) {
+	if c == nil || result == nil {
+		return
+	}
+	userID := result.UserData.UserIdentifier
+	current, err := fingerprint(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to fingerprint config for result cache: %v", err)
+		return
+	}
+	entryJSON, err := json.Marshal(cachedResult{UserID: userID, Fingerprint: current, Result: result})
+	if err != nil {
+		log.Printf("Failed to marshal verification result for cache: %v", err)
+		return
+	}
+	if err := c.store.SetWithExpiration(ctx, key, string(entryJSON), c.ttl); err != nil {
+		log.Printf("Failed to write verification result cache: %v", err)
+	}
+}
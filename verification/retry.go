@@ -0,0 +1,89 @@
+package verification
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// maxAttempts is the total number of Verify calls, including the first one
+	maxAttempts = 3
+	// baseBackoff is the wait before the first retry; it doubles on every attempt
+	baseBackoff = 200 * time.Millisecond
+)
+
+// transientMessages are fragments of RPC errors that indicate the node, not the proof, is at fault
+var transientMessages = []string{
+	"connection refused",
+	"connection reset",
+	"timeout",
+	"too many requests",
+	"service unavailable",
+	"bad gateway",
+}
+
+// IsTransient reports whether err looks like a temporary network or RPC failure worth retrying
+// Anything not positively classified (e.g. an invalid proof) is treated as permanent
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range transientMessages {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithRetry calls fn until it succeeds, fails with a non-transient error, or maxAttempts is reached
+// Backoff is exponential and never outlives the deadline of ctx
+func WithRetry[T any](ctx context.Context, requestID string, fn func(context.Context) (T, error)) (T, error) {
+	var result T
+	var err error
+
+	backoff := baseBackoff
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		result, err = fn(ctx)
+		if err == nil || !IsTransient(err) || attempt == maxAttempts {
+			return result, err
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < backoff {
+			return result, err
+		}
+
+		log.Printf("[%s] transient verification error (attempt %d/%d), retrying in %s: %v",
+			requestID, attempt, maxAttempts, backoff, err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+	return result, err
+}
@@ -0,0 +1,69 @@
+package verification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/selfxyz/self/sdk/sdk-go/common"
+)
+
+// VerificationEvent is the analytics record of one completed verification
+// It carries the outcome and the config that was applied, never disclosed fields
+type VerificationEvent struct {
+	Timestamp         time.Time                   `json:"timestamp"`
+	RequestID         string                      `json:"requestId"`
+	TenantID          string                      `json:"tenantId,omitempty"`
+	AttestationID     string                      `json:"attestationId"`
+	Result            bool                        `json:"result"`
+	ErrorCode         string                      `json:"errorCode,omitempty"`
+	MinimumAge        *int                        `json:"minimumAge,omitempty"`
+	Ofac              *bool                       `json:"ofac,omitempty"`
+	ExcludedCountries []common.Country3LetterCode `json:"excludedCountries,omitempty"`
+	DurationMs        int64                       `json:"durationMs"`
+}
+
+// VerificationSink receives an event for every completed verification
+type VerificationSink interface {
+	Record(ctx context.Context, event VerificationEvent) error
+}
+
+// NoopSink discards every event; it is the default
+type NoopSink struct{}
+
+func (NoopSink) Record(ctx context.Context, event VerificationEvent) error {
+	return nil
+}
+
+// JSONSink writes each event as one line of JSON
+type JSONSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONSink creates a sink writing newline-delimited JSON to w
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w)}
+}
+
+func (s *JSONSink) Record(ctx context.Context, event VerificationEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(event)
+}
+
+// NewSink creates the sink called name: "none" (or empty) or "stdout"
+func NewSink(name string) (VerificationSink, error) {
+	switch name {
+	case "", "none":
+		return NoopSink{}, nil
+	case "stdout":
+		return NewJSONSink(os.Stdout), nil
+	default:
+		return nil, fmt.Errorf("unknown verification sink %q", name)
+	}
+}
@@ -0,0 +1,61 @@
+package verification
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ErrStaleContext is returned when a user context timestamp falls outside the allowed skew
+var ErrStaleContext = errors.New("user context timestamp is outside the allowed clock skew")
+
+// CheckContextTimestamp validates the timestamp field of a decoded user context against
+// now, allowing skew in either direction. Contexts without a timestamp are accepted.
+// Timestamps may be RFC 3339 strings or Unix times in seconds or milliseconds
+func CheckContextTimestamp(userContext interface{}, now time.Time, skew time.Duration) error {
+	fields, ok := userContext.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	raw, ok := fields["timestamp"]
+	if !ok || raw == nil {
+		return nil
+	}
+
+	ts, err := parseContextTimestamp(raw)
+	if err != nil {
+		return err
+	}
+	if ts.Before(now.Add(-skew)) || ts.After(now.Add(skew)) {
+		return fmt.Errorf("%w: %s is more than %s from server time", ErrStaleContext, ts.UTC().Format(time.RFC3339), skew)
+	}
+	return nil
+}
+
+func parseContextTimestamp(raw interface{}) (time.Time, error) {
+	switch v := raw.(type) {
+	case string:
+		if ts, err := time.Parse(time.RFC3339, v); err == nil {
+			return ts, nil
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("timestamp %q is neither RFC 3339 nor a Unix time", v)
+		}
+		return unixTimestamp(n), nil
+	case float64:
+		return unixTimestamp(v), nil
+	default:
+		return time.Time{}, fmt.Errorf("timestamp must be a string or number")
+	}
+}
+
+// unixTimestamp reads n as milliseconds when it is too large to be a plausible time in seconds
+func unixTimestamp(n float64) time.Time {
+	if math.Abs(n) >= 1e11 {
+		return time.UnixMilli(int64(n))
+	}
+	return time.Unix(int64(n), 0)
+}
@@ -0,0 +1,88 @@
+package verification
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MaxNonceLength bounds the nonce a client may send, since it is echoed in responses
+const MaxNonceLength = 256
+
+// UserContext is the JSON object form of userContextData, as main.go's mock sends it.
+// Fields are declared in key order so marshalling it matches marshalling the decoded map,
+// keeping the bytes handed to the SDK the same as before the context was validated
+type UserContext struct {
+	Nonce string `json:"nonce,omitempty"`
+	// Timestamp is kept as sent, an RFC 3339 string or a Unix time, and checked by CheckContextTimestamp
+	Timestamp      interface{} `json:"timestamp"`
+	UserIdentifier string      `json:"userIdentifier"`
+}
+
+// ParseUserContext validates a decoded userContextData object and returns it typed.
+// Every required field must be present with the right type, the timestamp must parse,
+// and unknown fields are rejected; all problems are reported together. The nonce is
+// optional here, REQUIRE_NONCE decides whether a request must carry one
+func ParseUserContext(fields map[string]interface{}) (UserContext, error) {
+	var ctx UserContext
+	var problems []string
+	requireString := func(name string) string {
+		raw, ok := fields[name]
+		if !ok {
+			problems = append(problems, name+" is required")
+			return ""
+		}
+		s, ok := raw.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			problems = append(problems, name+" must be a non-empty string")
+		}
+		return s
+	}
+
+	ctx.UserIdentifier = requireString("userIdentifier")
+	if raw, ok := fields["nonce"]; ok {
+		s, ok := raw.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			problems = append(problems, "nonce must be a non-empty string")
+		}
+		ctx.Nonce = s
+	}
+	if len(ctx.Nonce) > MaxNonceLength {
+		problems = append(problems, fmt.Sprintf("nonce must be at most %d bytes", MaxNonceLength))
+	}
+	if raw, ok := fields["timestamp"]; !ok || raw == nil {
+		problems = append(problems, "timestamp is required")
+	} else if _, err := parseContextTimestamp(raw); err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		ctx.Timestamp = raw
+	}
+
+	var unknown []string
+	for name := range fields {
+		switch name {
+		case "userIdentifier", "nonce", "timestamp":
+		default:
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		problems = append(problems, fmt.Sprintf("unknown field %q", name))
+	}
+
+	if len(problems) > 0 {
+		return UserContext{}, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return ctx, nil
+}
+
+// Canonical is the serialized context passed to the verifier
+func (c UserContext) Canonical() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
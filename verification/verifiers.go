@@ -0,0 +1,152 @@
+package verification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	self "github.com/selfxyz/self/sdk/sdk-go"
+)
+
+// DefaultScope is the verifier scope used when an attestation has no configured scope
+const DefaultScope = "self-playground-go"
+
+// VerifierParams are the BackendVerifier settings for one attestation type
+// An empty Endpoint means the endpoint is derived from the incoming request
+type VerifierParams struct {
+	Scope    string `json:"scope"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// LoadVerifierParams reads ATTESTATION_VERIFIERS, a JSON object keyed by attestation code, e.g.
+// {"1": {"scope": "passport-scope", "endpoint": "https://example.com/api/go-verify"}}
+// When unset, every registered attestation uses DefaultScope and the request-derived endpoint
+func LoadVerifierParams() (map[string]VerifierParams, error) {
+	raw := os.Getenv("ATTESTATION_VERIFIERS")
+	if raw == "" {
+		params := make(map[string]VerifierParams, len(attestations))
+		for _, a := range attestations {
+			params[a.Code] = VerifierParams{Scope: DefaultScope}
+		}
+		return params, nil
+	}
+
+	var params map[string]VerifierParams
+	if err := json.Unmarshal([]byte(raw), &params); err != nil {
+		return nil, fmt.Errorf("failed to parse ATTESTATION_VERIFIERS: %w", err)
+	}
+	for code, p := range params {
+		if _, ok := LookupAttestation(code); !ok {
+			return nil, fmt.Errorf("ATTESTATION_VERIFIERS references unknown attestation %q", code)
+		}
+		if p.Scope == "" {
+			p.Scope = DefaultScope
+			params[code] = p
+		}
+	}
+	return params, nil
+}
+
+// Verifier is the part of *self.BackendVerifier the verify handler uses
+// Keeping the handler on this interface lets a fake stand in for the ZK verification,
+// so request-handling overhead can be measured on its own
+type Verifier interface {
+	Verify(ctx context.Context, attestationId string, proof self.VcAndDiscloseProof, pubSignals []string, userContextData string) (*self.VerificationResult, error)
+}
+
+// ValidateVerifierParams checks the verifier settings before any verifier is built, so a bad
+// value fails at startup with one clear error instead of deep inside the SDK on first use.
+// Every problem is reported, not just the first. Configured endpoints must match hosts
+func ValidateVerifierParams(params map[string]VerifierParams, allowed map[string]bool, hosts EndpointHosts) error {
+	var errs []error
+	if len(allowed) == 0 {
+		errs = append(errs, errors.New("no attestation types are allowed"))
+	}
+
+	codes := make([]string, 0, len(params))
+	for code := range params {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		p := params[code]
+		if strings.TrimSpace(p.Scope) == "" {
+			errs = append(errs, fmt.Errorf("attestation %s: scope (app name) is empty", code))
+		}
+		if p.Endpoint != "" {
+			if u, err := url.Parse(p.Endpoint); err != nil || !u.IsAbs() || u.Host == "" {
+				errs = append(errs, fmt.Errorf("attestation %s: endpoint %q is not an absolute URL", code, p.Endpoint))
+			} else if !hosts.Allows(p.Endpoint) {
+				errs = append(errs, fmt.Errorf("attestation %s: endpoint %q is not on ALLOWED_ENDPOINT_HOSTS", code, p.Endpoint))
+			}
+		}
+	}
+	allowedCodes := make([]string, 0, len(allowed))
+	for code := range allowed {
+		allowedCodes = append(allowedCodes, code)
+	}
+	sort.Strings(allowedCodes)
+	for _, code := range allowedCodes {
+		if _, ok := params[code]; !ok {
+			errs = append(errs, fmt.Errorf("attestation %s is allowed but has no verifier parameters", code))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid verifier parameters:\n%w", err)
+	}
+	return nil
+}
+
+// VerifierCache holds constructed verifiers so they are built once per distinct configuration
+type VerifierCache struct {
+	mu        sync.Mutex
+	verifiers map[string]Verifier
+	// generation counts invalidations, so logs can tell which set of verifiers served a request
+	generation uint64
+}
+
+// NewVerifierCache creates an empty verifier cache
+func NewVerifierCache() *VerifierCache {
+	return &VerifierCache{verifiers: make(map[string]Verifier)}
+}
+
+// Get returns the verifier cached under key, calling build to construct it on first use
+// Failed builds are not cached, so a later request can try again
+func (c *VerifierCache) Get(key string, build func() (Verifier, error)) (Verifier, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if verifier, ok := c.verifiers[key]; ok {
+		return verifier, nil
+	}
+	verifier, err := build()
+	if err != nil {
+		return nil, err
+	}
+	c.verifiers[key] = verifier
+	return verifier, nil
+}
+
+// Invalidate drops every cached verifier so the next Get builds fresh ones, and returns the
+// new generation. Requests already holding a verifier keep using it until they finish
+func (c *VerifierCache) Invalidate() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.verifiers = make(map[string]Verifier)
+	c.generation++
+	return c.generation
+}
+
+// Generation returns how many times the cache has been invalidated
+func (c *VerifierCache) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
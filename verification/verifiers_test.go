@@ -0,0 +1,126 @@
+package verification
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateVerifierParams(t *testing.T) {
+	both := map[string]bool{"1": true, "2": true}
+	tests := []struct {
+		name    string
+		params  map[string]VerifierParams
+		allowed map[string]bool
+		// wantErrs are substrings of the error, every one of which must be reported
+		wantErrs []string
+	}{
+		{
+			name:    "valid",
+			params:  map[string]VerifierParams{"1": {Scope: "a"}, "2": {Scope: "b", Endpoint: "https://example.com/api/go-verify"}},
+			allowed: both,
+		},
+		{
+			name:    "params for a type that isn't allowed",
+			params:  map[string]VerifierParams{"1": {Scope: "a"}, "2": {Scope: "b"}},
+			allowed: map[string]bool{"1": true},
+		},
+		{
+			name:     "no allowed types",
+			params:   map[string]VerifierParams{"1": {Scope: "a"}},
+			allowed:  map[string]bool{},
+			wantErrs: []string{"no attestation types are allowed"},
+		},
+		{
+			name:     "empty scope",
+			params:   map[string]VerifierParams{"1": {Scope: ""}, "2": {Scope: "b"}},
+			allowed:  both,
+			wantErrs: []string{"attestation 1: scope (app name) is empty"},
+		},
+		{
+			name:     "blank scope",
+			params:   map[string]VerifierParams{"1": {Scope: " \t"}, "2": {Scope: "b"}},
+			allowed:  both,
+			wantErrs: []string{"attestation 1: scope (app name) is empty"},
+		},
+		{
+			name:     "relative endpoint",
+			params:   map[string]VerifierParams{"1": {Scope: "a", Endpoint: "/api/go-verify"}, "2": {Scope: "b"}},
+			allowed:  both,
+			wantErrs: []string{`attestation 1: endpoint "/api/go-verify" is not an absolute URL`},
+		},
+		{
+			name:     "endpoint without a host",
+			params:   map[string]VerifierParams{"1": {Scope: "a"}, "2": {Scope: "b", Endpoint: "https://"}},
+			allowed:  both,
+			wantErrs: []string{`attestation 2: endpoint "https://" is not an absolute URL`},
+		},
+		{
+			name:     "allowed type without params",
+			params:   map[string]VerifierParams{"1": {Scope: "a"}},
+			allowed:  both,
+			wantErrs: []string{"attestation 2 is allowed but has no verifier parameters"},
+		},
+		{
+			name:    "every problem is reported",
+			params:  map[string]VerifierParams{"1": {Scope: "", Endpoint: "not a url"}},
+			allowed: both,
+			wantErrs: []string{
+				"attestation 1: scope (app name) is empty",
+				`attestation 1: endpoint "not a url" is not an absolute URL`,
+				"attestation 2 is allowed but has no verifier parameters",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateVerifierParams(tt.params, tt.allowed, nil)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("ValidateVerifierParams = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("ValidateVerifierParams = nil, want %q", tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("ValidateVerifierParams = %v, want it to report %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadVerifierParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    map[string]VerifierParams
+		wantErr bool
+	}{
+		{"unset", "", map[string]VerifierParams{"1": {Scope: DefaultScope}, "2": {Scope: DefaultScope}}, false},
+		{"missing scope gets the default", `{"1":{"endpoint":"https://example.com/api/go-verify"}}`,
+			map[string]VerifierParams{"1": {Scope: DefaultScope, Endpoint: "https://example.com/api/go-verify"}}, false},
+		{"configured", `{"2":{"scope":"card"}}`, map[string]VerifierParams{"2": {Scope: "card"}}, false},
+		{"unknown attestation", `{"3":{"scope":"x"}}`, nil, true},
+		{"not JSON", `{"1":`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ATTESTATION_VERIFIERS", tt.raw)
+			got, err := LoadVerifierParams()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadVerifierParams = %v, %v, want error %v", got, err, tt.wantErr)
+			}
+			if !tt.wantErr && len(got) != len(tt.want) {
+				t.Fatalf("LoadVerifierParams = %v, want %v", got, tt.want)
+			}
+			for code, want := range tt.want {
+				if got[code] != want {
+					t.Errorf("LoadVerifierParams[%s] = %+v, want %+v", code, got[code], want)
+				}
+			}
+		})
+	}
+}
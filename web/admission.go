@@ -0,0 +1,57 @@
+package web
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// inFlight counts requests currently admitted, including those exempt from the limit
+var inFlight atomic.Int64
+
+// InFlight returns how many requests this process is handling right now
+func InFlight() int64 {
+	return inFlight.Load()
+}
+
+// Admission caps the requests the process handles at once at limit, the MAX_IN_FLIGHT
+// setting. A request that can't get a slot within wait (ADMISSION_QUEUE_TIMEOUT) gets 503
+// with Retry-After. A limit of 0 disables the cap, and health probes are never held back,
+// so a saturated instance still answers them
+func Admission(next http.Handler, limit int, wait time.Duration) http.Handler {
+	var sem *semaphore.Weighted
+	if limit > 0 {
+		sem = semaphore.NewWeighted(int64(limit))
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight.Add(1)
+		defer inFlight.Add(-1)
+		if sem == nil || isHealthPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), wait)
+		err := sem.Acquire(ctx, 1)
+		cancel()
+		if err != nil {
+			log.Printf("Rejecting %s %s from %s, the server is at capacity", r.Method, r.URL.Path, ClientIP(r))
+			w.Header().Set("Retry-After", "1")
+			WriteJSON(w, r, http.StatusServiceUnavailable, map[string]string{"message": "Server is at capacity, please retry"})
+			return
+		}
+		defer sem.Release(1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isHealthPath reports whether path is one of the liveness or readiness probes
+func isHealthPath(path string) bool {
+	path = strings.TrimPrefix(path, "/api")
+	return path == "/healthz" || path == "/readyz" || path == "/health"
+}
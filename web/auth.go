@@ -0,0 +1,36 @@
+package web
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+
+	"playground/settings"
+)
+
+// RequireAdminToken guards admin endpoints with the bearer token in ADMIN_API_TOKEN
+// When the token isn't configured the endpoints stay closed rather than open
+func RequireAdminToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg, err := settings.Load()
+		if err != nil {
+			log.Printf("Failed to load settings: %v", err)
+			WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+			return
+		}
+		expected := cfg.AdminAPIToken
+		if expected == "" {
+			WriteJSON(w, r, http.StatusServiceUnavailable, map[string]string{"message": "Admin API is not configured"})
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			WriteJSON(w, r, http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,100 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// CasingHeader lets clients pick the key casing of JSON responses; ?casing= does the same
+const CasingHeader = "Accept-Casing"
+
+// Key casings a client can ask for; camel is what the handlers emit natively
+const (
+	CasingCamel = "camel"
+	CasingSnake = "snake"
+)
+
+type casingKey struct{}
+
+// Casing lets clients of next ask for snake_case JSON keys with Accept-Casing: snake or
+// ?casing=snake. WriteJSON does the renaming, so handlers keep their camelCase structs.
+// Unknown values keep the default camelCase
+func Casing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", CasingHeader)
+		casing := r.URL.Query().Get("casing")
+		if casing == "" {
+			casing = r.Header.Get(CasingHeader)
+		}
+		if strings.EqualFold(strings.TrimSpace(casing), CasingSnake) {
+			r = r.WithContext(context.WithValue(r.Context(), casingKey{}, CasingSnake))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// wantsSnakeCase reports whether Casing selected snake_case for r
+func wantsSnakeCase(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	casing, _ := r.Context().Value(casingKey{}).(string)
+	return casing == CasingSnake
+}
+
+// toSnakeCase re-keys v's JSON form, nested objects included, from camelCase to snake_case
+func toSnakeCase(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// Keep numbers as written rather than round-tripping them through float64
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return snakeKeys(generic), nil
+}
+
+func snakeKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			out[snakeCase(k)] = snakeKeys(val)
+		}
+		return out
+	case []interface{}:
+		for i := range v {
+			v[i] = snakeKeys(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// snakeCase converts one camelCase key; runs of capitals count as one word, so
+// "rawDiscloseOutput" becomes "raw_disclose_output" and "userID" becomes "user_id"
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			acronymEnd := i > 0 && unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || acronymEnd {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
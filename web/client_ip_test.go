@@ -0,0 +1,55 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Trusted proxies are read once per process, so they have to be set before any test runs
+	os.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.1, 2001:db8::/32")
+	os.Exit(m.Run())
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		realIP     string
+		want       string
+	}{
+		{"no proxy", "203.0.113.5:1234", nil, "", "203.0.113.5"},
+		{"peer without port", "203.0.113.5", nil, "", "203.0.113.5"},
+		{"untrusted peer can't claim an address", "203.0.113.5:1234", []string{"198.51.100.1"}, "198.51.100.2", "203.0.113.5"},
+		{"trusted peer", "10.0.0.1:1234", []string{"203.0.113.5"}, "", "203.0.113.5"},
+		{"trusted bare IP", "192.168.1.1:1234", []string{"203.0.113.5"}, "", "203.0.113.5"},
+		{"trusted hops are skipped", "10.0.0.1:1234", []string{"203.0.113.5, 10.1.2.3, 10.4.5.6"}, "", "203.0.113.5"},
+		{"spoofed leftmost hop is ignored", "10.0.0.1:1234", []string{"198.51.100.1, 203.0.113.5"}, "", "203.0.113.5"},
+		{"untrusted hop stops the walk", "10.0.0.1:1234", []string{"198.51.100.1, 203.0.113.5, 10.1.2.3"}, "", "203.0.113.5"},
+		{"every hop trusted", "10.0.0.1:1234", []string{"10.0.0.2, 10.0.0.3"}, "", "10.0.0.2"},
+		{"repeated headers", "10.0.0.1:1234", []string{"198.51.100.1", "203.0.113.5, 10.1.2.3"}, "", "203.0.113.5"},
+		{"malformed hop", "10.0.0.1:1234", []string{"203.0.113.5, not-an-ip"}, "", "10.0.0.1"},
+		{"malformed hop falls back to X-Real-IP", "10.0.0.1:1234", []string{"not-an-ip"}, "203.0.113.9", "203.0.113.9"},
+		{"X-Real-IP from trusted peer", "10.0.0.1:1234", nil, "203.0.113.9", "203.0.113.9"},
+		{"IPv6 trusted peer", "[2001:db8::1]:443", []string{"2001:db9::5, 2001:db8::2"}, "", "2001:db9::5"},
+		{"IPv6 untrusted peer", "[2001:db9::1]:443", []string{"203.0.113.5"}, "", "2001:db9::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.xff {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package web
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// defaultCORSMaxAge is how long, in seconds, browsers may cache a preflight response
+const defaultCORSMaxAge = 600
+
+// CORS sets the shared CORS headers and answers OPTIONS preflights itself with 204, like
+// PreflightFallback. Access-Control-Max-Age comes from CORS_MAX_AGE and is only sent on
+// preflight responses
+func CORS(next http.Handler) http.Handler {
+	maxAge := corsMaxAge()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w)
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Max-Age", maxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// PreflightFallback answers OPTIONS requests for paths mux has no handler for with the CORS
+// headers and 204, so preflights to routes the frontend probes succeed. Every other request,
+// including OPTIONS to registered paths, is served by mux as before
+func PreflightFallback(mux *http.ServeMux) http.Handler {
+	maxAge := corsMaxAge()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			if _, pattern := mux.Handler(r); pattern == "" {
+				setCORSHeaders(w)
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// setCORSHeaders sets the headers shared by every CORS response
+func setCORSHeaders(w http.ResponseWriter) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Encoding, X-Signature, X-Request-ID, Idempotency-Key, X-Pretty, Accept-Casing, X-Request-Timeout, X-Debug-Timing, If-None-Match")
+	w.Header().Set("Access-Control-Expose-Headers", "X-Config-Version, X-Request-Timeout, ETag")
+}
+
+func corsMaxAge() string {
+	raw := os.Getenv("CORS_MAX_AGE")
+	if raw == "" {
+		return strconv.Itoa(defaultCORSMaxAge)
+	}
+	if seconds, err := strconv.Atoi(raw); err != nil || seconds < 0 {
+		log.Printf("Ignoring invalid CORS_MAX_AGE %q, using %d", raw, defaultCORSMaxAge)
+		return strconv.Itoa(defaultCORSMaxAge)
+	}
+	return raw
+}
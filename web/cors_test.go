@@ -0,0 +1,32 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPreflightsAnswerNoContent(t *testing.T) {
+	handled := false
+	mux := http.NewServeMux()
+	mux.Handle("/api/go-verify", CORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handled = true
+	})))
+	server := PreflightFallback(mux)
+
+	for _, path := range []string{"/api/go-verify", "/api/unknown"} {
+		t.Run(path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, path, nil))
+			if rec.Code != http.StatusNoContent {
+				t.Errorf("OPTIONS %s = %d, want 204", path, rec.Code)
+			}
+			if rec.Header().Get("Access-Control-Allow-Origin") != "*" || rec.Header().Get("Access-Control-Max-Age") == "" {
+				t.Errorf("OPTIONS %s headers = %v, want the CORS preflight headers", path, rec.Header())
+			}
+		})
+	}
+	if handled {
+		t.Error("the handler behind CORS saw a preflight")
+	}
+}
@@ -0,0 +1,59 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"playground/settings"
+)
+
+// RequestTimeoutHeader lets a client ask for a tighter deadline, in milliseconds
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+type deadlineKey struct{}
+
+// Deadline bounds each request's context by REQUEST_TIMEOUT (default 30s), or by the
+// X-Request-Timeout the client sent, clamped to [100ms, REQUEST_TIMEOUT_MAX] (default 60s).
+// Malformed header values are ignored. The effective timeout is echoed in the same header
+func Deadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg, err := settings.Load()
+		if err != nil {
+			log.Printf("Failed to load settings: %v", err)
+			WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+			return
+		}
+		timeout := cfg.RequestTimeout
+		if raw := r.Header.Get(RequestTimeoutHeader); raw != "" {
+			if ms, err := strconv.ParseInt(raw, 10, 64); err == nil && ms > 0 {
+				timeout = min(max(time.Duration(ms)*time.Millisecond, settings.MinRequestTimeout), cfg.RequestTimeoutMax)
+			}
+		}
+		w.Header().Set(RequestTimeoutHeader, strconv.FormatInt(timeout.Milliseconds(), 10))
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		ctx = context.WithValue(ctx, deadlineKey{}, timeout)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// DeadlineExceeded reports whether r ran out of the time Deadline gave it
+func DeadlineExceeded(r *http.Request) bool {
+	_, ok := r.Context().Value(deadlineKey{}).(time.Duration)
+	return ok && errors.Is(r.Context().Err(), context.DeadlineExceeded)
+}
+
+// WriteDeadlineExceeded answers 504, noting the deadline the request was given
+func WriteDeadlineExceeded(w http.ResponseWriter, r *http.Request) {
+	timeout, _ := r.Context().Value(deadlineKey{}).(time.Duration)
+	WriteJSON(w, r, http.StatusGatewayTimeout, map[string]interface{}{
+		"message":   fmt.Sprintf("Request did not complete within its %dms deadline", timeout.Milliseconds()),
+		"timeoutMs": timeout.Milliseconds(),
+	})
+}
@@ -0,0 +1,126 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"playground/settings"
+)
+
+// DecodeJSON decodes a single JSON value from body into v
+// On failure the returned error carries a client-friendly description of what went wrong.
+// Unknown fields are ignored unless STRICT_JSON=true, which rejects them by name
+func DecodeJSON(body io.Reader, v interface{}) error {
+	dec := json.NewDecoder(body)
+	// Bad settings fail startup, and every handler that loads them, before a body gets here
+	if cfg, err := settings.Load(); err == nil && cfg.StrictJSON {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return errors.New(DescribeJSONError(err))
+	}
+	return nil
+}
+
+// DescribeJSONError turns encoding/json errors into messages that point at the offending field or offset
+func DescribeJSONError(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var sizeErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &sizeErr):
+		return fmt.Sprintf("request body exceeds %d bytes", sizeErr.Limit)
+	case errors.Is(err, io.EOF):
+		return "request body is empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "request body contains truncated JSON"
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("malformed JSON at offset %d: %s", syntaxErr.Offset, strings.TrimPrefix(syntaxErr.Error(), "json: "))
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return fmt.Sprintf("expected %s, got %s at offset %d", jsonTypeName(typeErr.Type.Kind()), typeErr.Value, typeErr.Offset)
+		}
+		return fmt.Sprintf("field `%s` expected %s, got %s at offset %d", typeErr.Field, jsonTypeName(typeErr.Type.Kind()), typeErr.Value, typeErr.Offset)
+	default:
+		return strings.TrimPrefix(err.Error(), "json: ")
+	}
+}
+
+// jsonTypeName maps Go kinds onto the JSON vocabulary clients think in
+func jsonTypeName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return kind.String()
+	}
+}
+
+// WriteJSON writes v as a JSON response with the given status code
+// Output is compact unless r asks for indentation with ?pretty=true or X-Pretty: true,
+// and keys are snake_case when the Casing middleware selected it for r.
+// Error statuses are rewritten as RFC 7807 problem+json when the client or PROBLEM_JSON asks.
+// Responses may carry PII, so they are marked no-store unless the handler already set
+// its own Cache-Control
+func WriteJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	if w.Header().Get("Cache-Control") == "" {
+		NoStore(w)
+	}
+	contentType := "application/json"
+	if status >= 400 {
+		w.Header().Add("Vary", "Accept")
+		if wantsProblem(r) {
+			if problem, err := toProblem(r, status, v); err != nil {
+				log.Printf("Failed to convert error response to problem+json: %v", err)
+			} else {
+				v, contentType = problem, ProblemContentType
+			}
+		}
+	}
+	if wantsSnakeCase(r) {
+		snake, err := toSnakeCase(v)
+		if err != nil {
+			log.Printf("Failed to convert JSON response to snake_case: %v", err)
+		} else {
+			v = snake
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	if wantsPretty(r) {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+// NoStore stops browsers and proxies from caching the response
+func NoStore(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+}
+
+// wantsPretty reports whether the client asked for indented JSON, for reading responses by hand
+func wantsPretty(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	return r.URL.Query().Get("pretty") == "true" || r.Header.Get("X-Pretty") == "true"
+}
@@ -0,0 +1,18 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Register adds handler to mux at pattern, returning an error where ServeMux would panic,
+// e.g. when two packages composed into one binary both claim a path
+func Register(mux *http.ServeMux, pattern string, handler http.HandlerFunc) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("cannot register %s: %v", pattern, p)
+		}
+	}()
+	mux.HandleFunc(pattern, handler)
+	return nil
+}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"playground/settings"
+)
+
+// ProblemContentType is the RFC 7807 media type for error responses
+const ProblemContentType = "application/problem+json"
+
+// problemSettings returns PROBLEM_JSON, which makes problem+json the default for every
+// client, and PROBLEM_TYPE_BASE, the URI prefix error codes are mapped under. Bad settings
+// fail startup, so the fallback only covers a process that never loaded them
+func problemSettings() (bool, string) {
+	cfg, err := settings.Load()
+	if err != nil {
+		return false, settings.DefaultProblemTypeBase
+	}
+	return cfg.ProblemJSON, cfg.ProblemTypeBase
+}
+
+// wantsProblem reports whether r's error responses should be problem+json, because the
+// client lists it in Accept or PROBLEM_JSON=true
+func wantsProblem(r *http.Request) bool {
+	if always, _ := problemSettings(); always {
+		return true
+	}
+	if r == nil {
+		return false
+	}
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(fields[0]), ProblemContentType) {
+			continue
+		}
+		for _, param := range fields[1:] {
+			if q, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// ProblemType maps an error code such as INVALID_PROOF to its problem type URI;
+// responses without a code use about:blank, as RFC 7807 prescribes
+func ProblemType(code string) string {
+	if code == "" {
+		return "about:blank"
+	}
+	_, base := problemSettings()
+	return base + strings.ReplaceAll(strings.ToLower(code), "_", "-")
+}
+
+// toProblem rewrites v, one of the handlers' simple JSON error bodies, as a problem
+// document. message becomes detail and errorCode picks the type; any other member except
+// the legacy status and result flags is kept as an extension
+func toProblem(r *http.Request, status int, v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		// Not an object, so there is nothing to carry over
+		body = nil
+	}
+
+	problem := make(map[string]interface{}, len(body)+5)
+	for k, val := range body {
+		switch k {
+		case "status", "result", "message", "errorCode":
+		default:
+			problem[k] = val
+		}
+	}
+	code, _ := body["errorCode"].(string)
+	problem["type"] = ProblemType(code)
+	problem["title"] = http.StatusText(status)
+	problem["status"] = status
+	if detail, ok := body["message"].(string); ok && detail != "" {
+		problem["detail"] = detail
+	}
+	if code != "" {
+		problem["code"] = code
+	}
+	if r != nil {
+		if id := RequestIDFromContext(r.Context()); id != "" {
+			problem["instance"] = id
+		} else if id := r.Header.Get(RequestIDHeader); id != "" {
+			problem["instance"] = id
+		}
+	}
+	return problem, nil
+}
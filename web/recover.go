@@ -0,0 +1,64 @@
+package web
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover turns a panic in next into a logged stack trace and a clean 500 JSON response
+// It belongs outermost in the middleware chain so it also catches panics in other middleware
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			requestID := w.Header().Get(RequestIDHeader)
+			if requestID == "" {
+				requestID = RequestID(r)
+			}
+			log.Printf("[%s] panic serving %s %s for %s: %v\n%s", requestID, r.Method, r.URL.Path, ClientIP(r), p, debug.Stack())
+
+			// Once the status line is out there is nothing left to do but drop the rest
+			if rw.wroteHeader {
+				return
+			}
+			WriteJSON(w, r, http.StatusInternalServerError, map[string]string{"status": "error", "code": "INTERNAL"})
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// statusRecorder records whether the wrapped handler has started the response
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if !s.wroteHeader {
+		s.WriteHeader(http.StatusOK)
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush streams
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
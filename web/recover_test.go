@@ -0,0 +1,88 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		wantCode int
+		wantBody map[string]string
+	}{
+		{
+			name:     "panic before writing",
+			handler:  func(w http.ResponseWriter, r *http.Request) { panic("boom") },
+			wantCode: http.StatusInternalServerError,
+			wantBody: map[string]string{"status": "error", "code": "INTERNAL"},
+		},
+		{
+			name:     "panic with an error",
+			handler:  func(w http.ResponseWriter, r *http.Request) { panic(io.ErrUnexpectedEOF) },
+			wantCode: http.StatusInternalServerError,
+			wantBody: map[string]string{"status": "error", "code": "INTERNAL"},
+		},
+		{
+			name: "panic after writing",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				WriteJSON(w, r, http.StatusAccepted, map[string]string{"status": "accepted"})
+				panic("boom")
+			},
+			wantCode: http.StatusAccepted,
+			wantBody: map[string]string{"status": "accepted"},
+		},
+		{
+			name: "no panic",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				WriteJSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+			},
+			wantCode: http.StatusOK,
+			wantBody: map[string]string{"status": "ok"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Recover(Trace(tt.handler)).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/go-verify", nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+			var body map[string]string
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %q isn't a single JSON object: %v", rec.Body, err)
+			}
+			for key, want := range tt.wantBody {
+				if body[key] != want {
+					t.Errorf("body %s = %q, want %q", key, body[key], want)
+				}
+			}
+			if rec.Header().Get(RequestIDHeader) == "" {
+				t.Errorf("response has no %s", RequestIDHeader)
+			}
+		})
+	}
+}
+
+func TestRecoverRepanicsOnAbort(t *testing.T) {
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", p)
+		}
+	}()
+	Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
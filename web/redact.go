@@ -0,0 +1,74 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the value of every field named in REDACT_FIELDS
+const Redacted = "***"
+
+// redactFields reads REDACT_FIELDS, a comma-separated list of field names to mask in logs
+// Names are matched by normalizedKey, so "date_of_birth" also covers "dateOfBirth"
+var redactFields = sync.OnceValue(func() map[string]bool {
+	fields := make(map[string]bool)
+	for _, name := range strings.Split(os.Getenv("REDACT_FIELDS"), ",") {
+		if name = normalizedKey(name); name != "" {
+			fields[name] = true
+		}
+	}
+	return fields
+})
+
+// normalizedKey folds case and underscores so snake_case and camelCase keys compare equal
+func normalizedKey(key string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(key), "_", ""))
+}
+
+// Redact renders v as JSON for a log line, with the values of the fields in REDACT_FIELDS
+// masked at any depth. Values that can't be encoded fall back to %+v when nothing is
+// redacted and are withheld entirely otherwise
+func Redact(v interface{}) string {
+	fields := redactFields()
+	data, err := json.Marshal(v)
+	if err != nil {
+		if len(fields) == 0 {
+			return fmt.Sprintf("%+v", v)
+		}
+		return Redacted
+	}
+	if len(fields) == 0 {
+		return string(data)
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return Redacted
+	}
+	out, err := json.Marshal(redactValue(decoded, fields))
+	if err != nil {
+		return Redacted
+	}
+	return string(out)
+}
+
+func redactValue(v interface{}, fields map[string]bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if fields[normalizedKey(key)] {
+				v[key] = Redacted
+			} else {
+				v[key] = redactValue(value, fields)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactValue(value, fields)
+		}
+	}
+	return v
+}
@@ -0,0 +1,48 @@
+package web
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// MaxRequestBodySize caps request bodies after decompression, so a small gzip bomb
+// can't expand into unbounded memory
+const MaxRequestBodySize = 1 << 20
+
+// DecompressRequest transparently gunzips bodies sent with Content-Encoding: gzip and
+// applies MaxRequestBodySize to every body. Other encodings are rejected with 415
+func DecompressRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+		case "", "identity":
+			r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBodySize)
+		case "gzip":
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				WriteJSON(w, r, http.StatusBadRequest, map[string]string{"message": "Invalid gzip request body"})
+				return
+			}
+			r.Body = http.MaxBytesReader(w, gzipBody{Reader: gz, body: r.Body}, MaxRequestBodySize)
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+		default:
+			WriteJSON(w, r, http.StatusUnsupportedMediaType, map[string]string{"message": "Unsupported Content-Encoding " + encoding + ", expected gzip or identity"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// gzipBody closes both the gzip reader and the underlying request body
+type gzipBody struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+func (g gzipBody) Close() error {
+	g.Reader.Close()
+	return g.body.Close()
+}
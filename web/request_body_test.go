@@ -0,0 +1,99 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompressRequest(t *testing.T) {
+	const payload = `{"attestationId":"1"}`
+	// A megabyte and a bit of zeros compresses to a few kilobytes
+	bomb := gzipped(t, make([]byte, MaxRequestBodySize+1))
+	if len(bomb) > MaxRequestBodySize/100 {
+		t.Fatalf("bomb is %d bytes compressed, want a small body", len(bomb))
+	}
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		// wantCode is the middleware's answer; 0 means the body reached the handler
+		wantCode    int
+		wantBody    string
+		wantTooLong bool
+	}{
+		{"plain", "", []byte(payload), 0, payload, false},
+		{"identity", "identity", []byte(payload), 0, payload, false},
+		{"gzip", "gzip", gzipped(t, []byte(payload)), 0, payload, false},
+		{"gzip in capitals", " GZIP ", gzipped(t, []byte(payload)), 0, payload, false},
+		{"brotli", "br", []byte(payload), http.StatusUnsupportedMediaType, "", false},
+		{"deflate", "deflate", []byte(payload), http.StatusUnsupportedMediaType, "", false},
+		{"stacked encodings", "gzip, gzip", gzipped(t, gzipped(t, []byte(payload))), http.StatusUnsupportedMediaType, "", false},
+		{"not gzip", "gzip", []byte(payload), http.StatusBadRequest, "", false},
+		{"plain over the limit", "", bytes.Repeat([]byte(" "), MaxRequestBodySize+1), 0, "", true},
+		{"gzip bomb", "gzip", bomb, 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reached bool
+			var got []byte
+			var readErr error
+			handler := DecompressRequest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reached = true
+				if strings.Contains(strings.ToLower(r.Header.Get("Content-Encoding")), "gzip") {
+					t.Errorf("handler saw Content-Encoding %q on a decompressed body", r.Header.Get("Content-Encoding"))
+				}
+				got, readErr = io.ReadAll(r.Body)
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, "/api/go-verify", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				r.Header.Set("Content-Encoding", tt.encoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			if tt.wantCode != 0 {
+				if reached || rec.Code != tt.wantCode {
+					t.Errorf("status = %d, handler reached %v, want %d before the handler", rec.Code, reached, tt.wantCode)
+				}
+				if !strings.Contains(rec.Header().Get("Content-Type"), "json") {
+					t.Errorf("Content-Type = %q, want a JSON error", rec.Header().Get("Content-Type"))
+				}
+				return
+			}
+			if !reached {
+				t.Fatalf("handler not reached, status %d %s", rec.Code, rec.Body)
+			}
+			var tooLong *http.MaxBytesError
+			if tt.wantTooLong {
+				if !errors.As(readErr, &tooLong) || len(got) > MaxRequestBodySize {
+					t.Errorf("read %d bytes with error %v, want MaxBytesError within %d bytes", len(got), readErr, MaxRequestBodySize)
+				}
+				return
+			}
+			if readErr != nil || string(got) != tt.wantBody {
+				t.Errorf("handler read %q, %v, want %q", got, readErr, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"userId":"a"}`)
+	valid := Sign(body, "secret")
+	tests := []struct {
+		name      string
+		signature string
+		secret    string
+		want      bool
+	}{
+		{"valid", valid, "secret", true},
+		{"valid with prefix", "sha256=" + valid, "secret", true},
+		{"uppercase hex", strings.ToUpper(valid), "secret", true},
+		{"wrong secret", valid, "other", false},
+		{"missing", "", "secret", false},
+		{"no secret", valid, "", false},
+		{"not hex", "zz" + valid[2:], "secret", false},
+		{"truncated", valid[:len(valid)-2], "secret", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature(body, tt.signature, tt.secret); got != tt.want {
+				t.Errorf("VerifySignature = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkVerifySignature(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		size int
+	}{
+		{"256B body", 256},
+		{"64KiB body", 64 << 10},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			body := []byte(strings.Repeat("x", bc.size))
+			signature := Sign(body, "secret")
+			b.SetBytes(int64(bc.size))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if !VerifySignature(body, signature, "secret") {
+					b.Fatal("signature rejected")
+				}
+			}
+		})
+	}
+}
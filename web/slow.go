@@ -0,0 +1,75 @@
+package web
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"playground/settings"
+)
+
+// phaseMark is the moment a request entered a named phase
+type phaseMark struct {
+	name string
+	at   time.Time
+}
+
+// phaseRecorder collects the phases a handler marks while Trace times the request
+type phaseRecorder struct {
+	mu    sync.Mutex
+	marks []phaseMark
+}
+
+// MarkPhase notes that the request handled under ctx has entered phase name. Trace uses
+// the marks to say which phase dominated a slow request; outside Trace it does nothing
+func MarkPhase(ctx context.Context, name string) {
+	p, ok := ctx.Value(phasesKey).(*phaseRecorder)
+	if !ok {
+		return
+	}
+	p.mu.Lock()
+	p.marks = append(p.marks, phaseMark{name: name, at: time.Now()})
+	p.mu.Unlock()
+}
+
+// slowest returns the longest span between consecutive marks, ending with end, named
+// "from->to"; ok is false when nothing was marked besides the start
+func (p *phaseRecorder) slowest(end time.Time) (name string, took time.Duration, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.marks) < 2 {
+		return "", 0, false
+	}
+	for i, mark := range p.marks {
+		next, nextName := end, "end"
+		if i+1 < len(p.marks) {
+			next, nextName = p.marks[i+1].at, p.marks[i+1].name
+		}
+		if d := next.Sub(mark.at); d > took {
+			name, took = mark.name+"->"+nextName, d
+		}
+	}
+	return name, took, true
+}
+
+// logIfSlow warns about a request that took longer than SLOW_REQUEST_MS, naming the
+// slowest phase when the handler marked any
+func logIfSlow(r *http.Request, requestID string, started time.Time, phases *phaseRecorder) {
+	cfg, err := settings.Load()
+	if err != nil {
+		return
+	}
+	end := time.Now()
+	took := end.Sub(started)
+	if cfg.SlowRequest == 0 || took <= cfg.SlowRequest {
+		return
+	}
+	if phase, phaseTook, ok := phases.slowest(end); ok {
+		log.Printf("WARN [%s] Slow request %s %s from %s took %dms, slowest phase %s took %dms",
+			requestID, r.Method, r.URL.Path, ClientIP(r), took.Milliseconds(), phase, phaseTook.Milliseconds())
+		return
+	}
+	log.Printf("WARN [%s] Slow request %s %s from %s took %dms", requestID, r.Method, r.URL.Path, ClientIP(r), took.Milliseconds())
+}
@@ -0,0 +1,23 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Trace assigns the request ID for the request and stores it on its context.
+// Requests slower than SLOW_REQUEST_MS (default 2000) are logged, see MarkPhase
+func Trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := RequestID(r)
+		w.Header().Set(RequestIDHeader, requestID)
+
+		started := time.Now()
+		phases := &phaseRecorder{marks: []phaseMark{{name: "start", at: started}}}
+		ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+		ctx = context.WithValue(ctx, phasesKey, phases)
+		defer logIfSlow(r, requestID, started, phases)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}